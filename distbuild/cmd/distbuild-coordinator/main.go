@@ -0,0 +1,219 @@
+// Команда distbuild-coordinator запускает координатор distbuild.
+//
+//	distbuild-coordinator -config coordinator.yaml
+//
+// По SIGINT или SIGTERM координатор перестаёт принимать новые сборки и ждёт завершения
+// текущих не дольше shutdown_timeout. С журналом координатор останавливается сразу,
+// а текущие сборки продолжатся после перезапуска.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/config"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to yaml config")
+	flag.Parse()
+
+	if *configPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "distbuild-coordinator: -config is required")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *configPath); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "distbuild-coordinator: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, configPath string) error {
+	cfg, err := config.LoadCoordinator(configPath)
+	if err != nil {
+		return err
+	}
+
+	log, err := cfg.Log.Build()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = log.Sync() }()
+
+	fileCache, err := filecache.New(filepath.Join(cfg.RootDir, "filecache"))
+	if err != nil {
+		return err
+	}
+
+	opts := []dist.Option{dist.WithWorkerTimeout(cfg.WorkerTimeout)}
+	if cfg.Speculative != nil {
+		opts = append(opts, dist.WithSpeculativeExecution(cfg.Speculative.Factor, cfg.Speculative.MinDelay))
+	}
+	var serverTLS *tls.Config
+	if cfg.TLS != nil {
+		if serverTLS, err = cfg.TLS.Server(); err != nil {
+			return err
+		}
+
+		var tokens []string
+		if cfg.TokensFile != "" {
+			if tokens, err = auth.ReadTokens(cfg.TokensFile); err != nil {
+				return err
+			}
+		}
+		opts = append(opts, dist.WithAuth(auth.NewAuthenticator(tokens...)))
+
+		// Воркеры отдают артефакты только по клиентскому сертификату, поэтому координатор
+		// проксирует к ним запросы артефактов со своим сертификатом.
+		clientTLS, err := cfg.TLS.Client()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, dist.WithHTTPClient(auth.NewHTTPClient(clientTLS, "")))
+	}
+
+	if cfg.RemoteCache {
+		actionCache, err := artifact.NewCache(filepath.Join(cfg.RootDir, "ac"))
+		if err != nil {
+			return err
+		}
+		cas, err := filecache.New(filepath.Join(cfg.RootDir, "cas"))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, dist.WithRemoteCache(actionCache, cas))
+
+		if cfg.RemoteCacheGC != nil {
+			opts = append(opts, dist.WithRemoteCacheGC(cfg.RemoteCacheGC.Build(), cfg.RemoteCacheGC.Interval))
+		}
+	}
+
+	var j *journal.Journal
+	if cfg.Journal {
+		var state *journal.State
+		if j, state, err = journal.Open(filepath.Join(cfg.RootDir, "journal")); err != nil {
+			return err
+		}
+		defer func() { _ = j.Close() }()
+
+		opts = append(opts, dist.WithJournal(j, state))
+	}
+
+	coordinator := dist.NewCoordinator(log.Named("coordinator"), fileCache, opts...)
+	defer coordinator.Stop()
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: coordinator, TLSConfig: serverTLS}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if serverTLS != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	// grpcErr остаётся nil, если gRPC выключен, и select ниже никогда его не выбирает.
+	var grpcSrv *grpc.Server
+	var grpcErr chan error
+	if cfg.GRPCListen != "" {
+		lsn, err := net.Listen("tcp", cfg.GRPCListen)
+		if err != nil {
+			_ = srv.Close()
+			return err
+		}
+
+		if grpcSrv, err = coordinator.NewGRPCServer(serverTLS); err != nil {
+			_ = lsn.Close()
+			_ = srv.Close()
+			return err
+		}
+
+		grpcErr = make(chan error, 1)
+		go func() {
+			grpcErr <- grpcSrv.Serve(lsn)
+		}()
+		defer grpcSrv.Stop()
+	}
+
+	log.Info("coordinator started",
+		zap.String("listen", cfg.Listen),
+		zap.String("grpc_listen", cfg.GRPCListen),
+		zap.String("root_dir", cfg.RootDir))
+
+	select {
+	case err := <-serveErr:
+		return err
+	case err := <-grpcErr:
+		_ = srv.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	if j != nil {
+		log.Info("shutting down, builds will resume after restart")
+
+		// Журнал закрывается первым, чтобы прерванные сборки не записались в него завершёнными.
+		if err := j.Close(); err != nil {
+			log.Warn("failed to close journal", zap.Error(err))
+		}
+		_ = srv.Close()
+		<-serveErr
+		return nil
+	}
+
+	log.Info("shutting down", zap.Duration("timeout", cfg.ShutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if grpcSrv != nil {
+		go stopGRPC(shutdownCtx, grpcSrv)
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Warn("builds did not finish in time", zap.Error(err))
+		_ = srv.Close()
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// stopGRPC ждёт завершения gRPC вызовов, пока не истечёт ctx, а потом закрывает оставшиеся.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
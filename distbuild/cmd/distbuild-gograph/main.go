@@ -0,0 +1,59 @@
+// Команда distbuild-gograph строит build.Graph для пакетов Go модуля и печатает его в формате json.
+//
+//	distbuild-gograph [-dir dir] [-o graph.json] [-skip-vet] [-skip-tests] [-test-shards n] [-test-retries n] [packages]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/gograph"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "module root directory")
+	output := flag.String("o", "", "output file, stdout by default")
+	skipVet := flag.Bool("skip-vet", false, "do not generate vet jobs")
+	skipTests := flag.Bool("skip-tests", false, "do not generate test jobs")
+	testShards := flag.Int("test-shards", 0, "split tests of each package into n shards")
+	testRetries := flag.Int("test-retries", 0, "retry a failed test shard up to n times")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, gograph.Config{
+		Dir:         *dir,
+		Patterns:    flag.Args(),
+		SkipVet:     *skipVet,
+		SkipTests:   *skipTests,
+		TestShards:  *testShards,
+		TestRetries: *testRetries,
+	}, *output); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "distbuild-gograph: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, config gograph.Config, output string) error {
+	graph, err := gograph.Generate(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	js, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return err
+	}
+	js = append(js, '\n')
+
+	if output == "" {
+		_, err = os.Stdout.Write(js)
+		return err
+	}
+	return os.WriteFile(output, js, 0666)
+}
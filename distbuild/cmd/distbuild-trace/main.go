@@ -0,0 +1,111 @@
+// Команда distbuild-trace разбирает трейс сборки, сохранённый distbuild -trace.
+//
+//	distbuild-trace trace.json
+//
+// Команда печатает длительность сборки, достигнутый параллелизм, время ожидания в очереди
+// и критический путь: цепочку зависимых джобов, которая ограничивает время сборки снизу.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: distbuild-trace trace.json")
+		os.Exit(2)
+	}
+
+	if err := run(os.Args[1], os.Stdout); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "distbuild-trace: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	t, err := trace.ReadChrome(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return report(t, out)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+func report(t *trace.Trace, out io.Writer) error {
+	var (
+		retries, cacheHits, failed int
+		wait, maxWait              time.Duration
+		maxWaitJob                 string
+	)
+
+	for i := range t.Jobs {
+		job := &t.Jobs[i]
+		if job.Attempt > 1 {
+			retries++
+		}
+		if job.Timings != nil && job.Timings.CacheHit {
+			cacheHits++
+		}
+		if job.Failed {
+			failed++
+		}
+
+		wait += job.Wait()
+		if job.Wait() > maxWait {
+			maxWait, maxWaitJob = job.Wait(), job.Name
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	_, _ = fmt.Fprintf(w, "build\t%s\n", t.BuildID)
+	_, _ = fmt.Fprintf(w, "duration\t%s\n", seconds(t.End.Sub(t.Start)))
+	_, _ = fmt.Fprintf(w, "jobs\t%d (%d retries, %d cache hits, %d failed)\n", len(t.Jobs), retries, cacheHits, failed)
+	_, _ = fmt.Fprintf(w, "parallelism\t%.2f\n", t.Parallelism())
+	_, _ = fmt.Fprintf(w, "queue wait\t%s total, %s max (%s)\n", seconds(wait), seconds(maxWait), maxWaitJob)
+
+	path := t.CriticalPath()
+
+	var length time.Duration
+	for i := range path {
+		length += path[i].Duration()
+	}
+	_, _ = fmt.Fprintf(w, "critical path\t%s, %d jobs\n", seconds(length), len(path))
+
+	_, _ = fmt.Fprintf(w, "\njob\tworker\twait\ttotal\tdownload\texec\tupload\n")
+	for i := range path {
+		job := &path[i]
+
+		stages := "-\t-\t-"
+		switch {
+		case job.Timings == nil:
+		case job.Timings.CacheHit:
+			stages = "cached\t-\t-"
+		default:
+			stages = fmt.Sprintf("%s\t%s\t%s",
+				seconds(job.Timings.Download.Duration()),
+				seconds(job.Timings.Exec.Duration()),
+				seconds(job.Timings.Upload.Duration()))
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.Name, job.Worker, seconds(job.Wait()), seconds(job.Duration()), stages)
+	}
+
+	return w.Flush()
+}
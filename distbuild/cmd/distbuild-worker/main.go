@@ -0,0 +1,200 @@
+// Команда distbuild-worker запускает воркер distbuild.
+//
+//	distbuild-worker -config worker.yaml
+//
+// По SIGINT или SIGTERM воркер перестаёт брать джобы, убивает выполняющиеся и
+// ждёт их остановки не дольше shutdown_timeout. Координатор перезапустит эти джобы на других воркерах.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/config"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to yaml config")
+	flag.Parse()
+
+	if *configPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "distbuild-worker: -config is required")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, *configPath); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "distbuild-worker: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func workerOptions(cfg *config.Worker) ([]worker.Option, error) {
+	var opts []worker.Option
+
+	if cfg.Capacity != nil {
+		opts = append(opts, worker.WithCapacity(cfg.Capacity.Build()))
+	}
+
+	if cfg.GC != nil {
+		opts = append(opts, worker.WithCacheGC(cfg.GC.Build(), cfg.GC.Interval))
+	}
+
+	if cfg.Sandbox != nil {
+		s, err := sandbox.New(cfg.Sandbox.Build())
+		if err != nil {
+			return nil, fmt.Errorf("sandbox: %w", err)
+		}
+		opts = append(opts, worker.WithSandbox(s))
+	}
+
+	if cfg.Hermetic {
+		opts = append(opts, worker.WithHermeticMode())
+	}
+
+	if cfg.CompressArtifacts {
+		opts = append(opts, worker.WithCompressedTransfer())
+	}
+
+	for _, tool := range cfg.Tools {
+		opts = append(opts, worker.WithTools(worker.Tool{Name: tool.Name, Path: tool.Path, Writable: tool.Writable}))
+	}
+
+	if cfg.TLS != nil {
+		clientTLS, err := cfg.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts,
+			worker.WithHTTPClient(auth.NewHTTPClient(clientTLS, "")),
+			worker.WithAuth(auth.NewAuthenticator()))
+	}
+
+	return opts, nil
+}
+
+func run(ctx context.Context, configPath string) error {
+	cfg, err := config.LoadWorker(configPath)
+	if err != nil {
+		return err
+	}
+
+	log, err := cfg.Log.Build()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = log.Sync() }()
+
+	fileCache, err := filecache.New(filepath.Join(cfg.RootDir, "filecache"))
+	if err != nil {
+		return err
+	}
+
+	artifacts, err := artifact.NewCache(filepath.Join(cfg.RootDir, "artifacts"))
+	if err != nil {
+		return err
+	}
+
+	opts, err := workerOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.CoordinatorGRPC != "" {
+		var clientTLS *tls.Config
+		if cfg.TLS != nil {
+			if clientTLS, err = cfg.TLS.Client(); err != nil {
+				return err
+			}
+		}
+
+		cc, err := grpc.Dial(cfg.CoordinatorGRPC, auth.DialOptions(clientTLS, "")...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cc.Close() }()
+
+		opts = append(opts, worker.WithGRPC(cc))
+	}
+
+	w := worker.New(
+		api.WorkerID(cfg.Endpoint),
+		cfg.Coordinator,
+		log.Named("worker"),
+		fileCache,
+		artifacts,
+		opts...,
+	)
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: w}
+	if cfg.TLS != nil {
+		if srv.TLSConfig, err = cfg.TLS.Server(); err != nil {
+			return err
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			serveErr <- srv.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- srv.ListenAndServe()
+		}
+	}()
+
+	runCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- w.Run(runCtx)
+	}()
+
+	log.Info("worker started",
+		zap.String("endpoint", cfg.Endpoint),
+		zap.String("listen", cfg.Listen),
+		zap.String("coordinator", cfg.Coordinator))
+
+	select {
+	case err := <-serveErr:
+		stopWorker()
+		<-runErr
+		return err
+	case err := <-runErr:
+		_ = srv.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("shutting down", zap.Duration("timeout", cfg.ShutdownTimeout))
+	stopWorker()
+
+	select {
+	case <-runErr:
+	case <-time.After(cfg.ShutdownTimeout):
+		log.Warn("jobs did not stop in time")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
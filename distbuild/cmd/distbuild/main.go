@@ -0,0 +1,192 @@
+// Команда distbuild запускает сборку графа на кластере distbuild и печатает прогресс.
+//
+//	distbuild [-config client.yaml] [-coordinator url] [-priority name] [-src dir] [-trace trace.json]
+//		[-out dir -output job[:glob,...]...] -graph graph.json
+//
+// Граф читается в формате json, например из вывода distbuild-gograph. Вывод джобов
+// печатается в stdout и stderr, а строки прогресса - в stderr.
+//
+// С флагом -trace клиент сохраняет трейс сборки в формате Chrome Trace Event. Его можно открыть
+// в https://ui.perfetto.dev или разобрать командой distbuild-trace.
+//
+// Флаги -output выбирают джобы по имени или ID и шаблоны файлов в их {{.OutputDir}}. После успешной
+// сборки подходящие файлы скачиваются в директорию -out, например -output 'link main:bin/*'.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+	"gitlab.com/slon/shad-go/distbuild/pkg/config"
+)
+
+func main() {
+	configPath := flag.String("config", "", "path to yaml config")
+	coordinator := flag.String("coordinator", "", "coordinator endpoint, overrides config")
+	priority := flag.String("priority", "", "build priority: normal, batch or interactive, overrides config")
+	graphPath := flag.String("graph", "", "path to json graph")
+	sourceDir := flag.String("src", ".", "directory with source files of the graph")
+	tracePath := flag.String("trace", "", "write build trace in Chrome Trace Event format to this file")
+	outDir := flag.String("out", "", "download job outputs selected by -output into this directory")
+	var outputs outputFlags
+	flag.Var(&outputs, "output", "job name or id and optional comma separated globs, job[:glob,...]; may be repeated")
+	flag.Parse()
+
+	if *graphPath == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "distbuild: -graph is required")
+		os.Exit(2)
+	}
+
+	if (*outDir == "") != (len(outputs) == 0) {
+		_, _ = fmt.Fprintln(os.Stderr, "distbuild: -out and -output must be set together")
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadClient(*configPath)
+	if err == nil {
+		if *coordinator != "" {
+			cfg.Coordinator = *coordinator
+		}
+		if *priority != "" {
+			cfg.Priority = *priority
+		}
+		if cfg.Coordinator == "" {
+			err = errors.New("coordinator endpoint is not set")
+		}
+	}
+
+	if err == nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err = run(ctx, cfg, *graphPath, *sourceDir, *tracePath, *outDir, outputs)
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "distbuild: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func readGraph(path string) (build.Graph, error) {
+	var graph build.Graph
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return graph, err
+	}
+
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return graph, fmt.Errorf("%s: %w", path, err)
+	}
+	return graph, graph.Validate()
+}
+
+// credentials читает TLS конфигурацию и токен из конфигурации клиента.
+func credentials(cfg *config.Client) (*tls.Config, string, error) {
+	var tlsConfig *tls.Config
+	if cfg.TLS != nil {
+		var err error
+		if tlsConfig, err = cfg.TLS.Client(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var token string
+	if cfg.TokenFile != "" {
+		tokens, err := auth.ReadTokens(cfg.TokenFile)
+		if err != nil {
+			return nil, "", err
+		}
+		token = tokens[0]
+	}
+
+	return tlsConfig, token, nil
+}
+
+func run(ctx context.Context, cfg *config.Client, graphPath, sourceDir, tracePath, outDir string, outputSpecs []string) error {
+	graph, err := readGraph(graphPath)
+	if err != nil {
+		return err
+	}
+
+	outputs, err := parseOutputs(&graph, outputSpecs)
+	if err != nil {
+		return err
+	}
+
+	log, err := cfg.Log.Build()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = log.Sync() }()
+
+	var opts []client.Option
+	if cfg.Priority != "" {
+		priority, err := api.ParsePriority(cfg.Priority)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, client.WithPriority(priority))
+	}
+
+	if cfg.RemoteCache != "" {
+		opts = append(opts, client.WithRemoteCache(cfg.RemoteCache))
+	}
+
+	if cfg.CacheOnly {
+		opts = append(opts, client.WithCacheOnly())
+	}
+
+	if len(outputs) != 0 {
+		opts = append(opts, client.WithOutputs(outDir, outputs...))
+	}
+
+	tlsConfig, token, err := credentials(cfg)
+	if err != nil {
+		return err
+	}
+
+	if tlsConfig != nil || token != "" {
+		opts = append(opts, client.WithHTTPClient(auth.NewHTTPClient(tlsConfig, token)))
+	}
+
+	if cfg.CoordinatorGRPC != "" {
+		cc, err := grpc.Dial(cfg.CoordinatorGRPC, auth.DialOptions(tlsConfig, token)...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cc.Close() }()
+
+		opts = append(opts, client.WithGRPC(cc))
+	}
+
+	c := client.NewClient(log, cfg.Coordinator, sourceDir, opts...)
+
+	p := newProgress(&graph, os.Stdout, os.Stderr)
+
+	var lsn client.BuildListener = p
+	if tracePath != "" {
+		lsn = &traceWriter{progress: p, path: tracePath}
+	}
+
+	if err := c.Build(ctx, graph, lsn); err != nil {
+		return err
+	}
+
+	p.summary()
+	return nil
+}
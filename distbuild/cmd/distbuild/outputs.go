@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+// outputFlags собирает значения повторяющегося флага -output.
+type outputFlags []string
+
+func (f *outputFlags) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *outputFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// parseOutputs разбирает значения -output вида job[:glob,...], где job - имя или ID джоба из graph.
+func parseOutputs(graph *build.Graph, specs []string) ([]client.Output, error) {
+	var outputs []client.Output
+	for _, spec := range specs {
+		name, globs, _ := strings.Cut(spec, ":")
+
+		id, err := findJob(graph, name)
+		if err != nil {
+			return nil, fmt.Errorf("-output %q: %w", spec, err)
+		}
+
+		out := client.Output{JobID: id}
+		if globs != "" {
+			out.Globs = strings.Split(globs, ",")
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+// findJob ищет джоб по ID или по имени. Имя должно быть уникальным в графе.
+func findJob(graph *build.Graph, name string) (build.ID, error) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(name)); err == nil {
+		return id, nil
+	}
+
+	found := false
+	for _, job := range graph.Jobs {
+		if job.Name != name {
+			continue
+		}
+
+		if found {
+			return id, fmt.Errorf("several jobs are named %q", name)
+		}
+		id, found = job.ID, true
+	}
+
+	if !found {
+		return id, fmt.Errorf("job %q not found", name)
+	}
+	return id, nil
+}
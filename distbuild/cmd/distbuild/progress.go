@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// progress печатает вывод джобов и строку на каждый завершившийся джоб.
+type progress struct {
+	stdout, stderr io.Writer
+
+	names map[build.ID]string
+	total int
+
+	mu       sync.Mutex
+	finished int
+	failed   int
+}
+
+func newProgress(graph *build.Graph, stdout, stderr io.Writer) *progress {
+	names := make(map[build.ID]string, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		names[job.ID] = job.Name
+	}
+
+	return &progress{
+		stdout: stdout,
+		stderr: stderr,
+		names:  names,
+		total:  len(graph.Jobs),
+	}
+}
+
+func (p *progress) name(jobID build.ID) string {
+	if name, ok := p.names[jobID]; ok {
+		return name
+	}
+	return jobID.String()
+}
+
+func (p *progress) OnJobStdout(jobID build.ID, stdout []byte) error {
+	_, err := p.stdout.Write(stdout)
+	return err
+}
+
+func (p *progress) OnJobStderr(jobID build.ID, stderr []byte) error {
+	_, err := p.stderr.Write(stderr)
+	return err
+}
+
+func (p *progress) OnJobFinished(jobID build.ID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished++
+	_, err := fmt.Fprintf(p.stderr, "[%d/%d] %s: ok\n", p.finished, p.total, p.name(jobID))
+	return err
+}
+
+func (p *progress) OnJobFailed(jobID build.ID, code int, error string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.finished++
+	p.failed++
+	if error != "" {
+		_, err := fmt.Fprintf(p.stderr, "[%d/%d] %s: FAILED: %s\n", p.finished, p.total, p.name(jobID), error)
+		return err
+	}
+
+	_, err := fmt.Fprintf(p.stderr, "[%d/%d] %s: FAILED with exit code %d\n", p.finished, p.total, p.name(jobID), code)
+	return err
+}
+
+// OnJobsAdded учитывает в счётчике джобы, которые координатор добавил из фрагмента графа.
+func (p *progress) OnJobsAdded(generator build.ID, jobs []build.Job) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, job := range jobs {
+		p.names[job.ID] = job.Name
+	}
+	p.total += len(jobs)
+
+	_, err := fmt.Fprintf(p.stderr, "%s: added %d jobs\n", p.name(generator), len(jobs))
+	return err
+}
+
+func (p *progress) OnJobViolations(jobID build.ID, violations []api.Violation) error {
+	for _, v := range violations {
+		if _, err := fmt.Fprintf(p.stderr, "%s: hermeticity violation: %s %s\n", p.name(jobID), v.Kind, v.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *progress) summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, _ = fmt.Fprintf(p.stderr, "%d jobs finished, %d failed\n", p.finished, p.failed)
+}
+
+// traceWriter дополнительно сохраняет трейс сборки в файл.
+type traceWriter struct {
+	*progress
+	path string
+}
+
+func (w *traceWriter) OnBuildTrace(t *trace.Trace) error {
+	f, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+
+	if err := trace.WriteChrome(f, t); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w.stderr, "trace written to %s\n", w.path)
+	return err
+}
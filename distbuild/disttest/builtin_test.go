@@ -0,0 +1,137 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestBuiltinCmds(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+
+	depDir := fmt.Sprintf("{{index .Deps %q}}", build.ID{'a'})
+
+	// config.tmpl ссылается на lib.txt из выходной директории джоба a и рендерится только в джобе b.
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "config.tmpl"),
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "prepare",
+				Inputs: []string{"config.tmpl"},
+				Cmds: []build.Cmd{
+					{MkdirPath: "{{.OutputDir}}/bin/empty"},
+					{Exec: []string{"sh", "-c", "echo tool > {{.OutputDir}}/bin/tool; chmod 755 {{.OutputDir}}/bin/tool"}},
+					{SymlinkTarget: "tool", SymlinkPath: "{{.OutputDir}}/bin/link"},
+					{CopyFrom: "{{.SourceDir}}/config.tmpl", CopyTo: "{{.OutputDir}}/config.tmpl"},
+					{CatTemplate: "lib\n", CatOutput: "{{.OutputDir}}/lib.txt"},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "use",
+				Deps: []build.ID{{'a'}},
+				Cmds: []build.Cmd{
+					{CopyFrom: depDir + "/bin", CopyTo: "{{.OutputDir}}/bin"},
+					{WriteFrom: depDir + "/config.tmpl", WriteOutput: "{{.OutputDir}}/config"},
+					{Exec: []string{"sh", "-c", `
+cd {{.OutputDir}}
+cat bin/link
+test -x bin/tool && test -d bin/empty && readlink bin/link
+sh config
+`}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "tool\ntool\nlib\n", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestSandboxBuiltinSymlinkEscape(t *testing.T) {
+	env := newEnv(t, sandboxConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "escape",
+				Cmds: []build.Cmd{
+					{SymlinkTarget: env.RootDir, SymlinkPath: "{{.OutputDir}}/escape"},
+					{CatTemplate: "pwned", CatOutput: "{{.OutputDir}}/escape/out.txt"},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	result := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, result)
+	assert.Contains(t, result.Error, "escape/out.txt is outside of writable directories")
+
+	_, err := os.Stat(filepath.Join(env.RootDir, "out.txt"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBuiltinOutsideOutputDir(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+
+	secret := filepath.Join(env.RootDir, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0666))
+
+	for i, test := range []struct {
+		name string
+		cmd  build.Cmd
+		err  string
+	}{
+		{
+			name: "cat",
+			cmd:  build.Cmd{CatTemplate: "pwned", CatOutput: filepath.Join(env.RootDir, "cat.txt")},
+			err:  "cat.txt is outside of writable directories",
+		},
+		{
+			name: "copy from",
+			cmd:  build.Cmd{CopyFrom: secret, CopyTo: "{{.OutputDir}}/secret.txt"},
+			err:  "secret.txt is outside of readable directories",
+		},
+		{
+			name: "copy to",
+			cmd:  build.Cmd{CopyFrom: "{{.SourceDir}}", CopyTo: filepath.Join(env.RootDir, "copy")},
+			err:  "copy is outside of writable directories",
+		},
+		{
+			name: "symlink",
+			cmd:  build.Cmd{SymlinkTarget: secret, SymlinkPath: filepath.Join(env.RootDir, "link")},
+			err:  "link is outside of writable directories",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			id := build.ID{'a', byte(i)}
+			graph := build.Graph{Jobs: []build.Job{{ID: id, Name: test.name, Cmds: []build.Cmd{test.cmd}}}}
+
+			recorder := NewRecorder()
+			require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+			result := recorder.Jobs[id]
+			require.NotNil(t, result)
+			assert.Contains(t, result.Error, test.err)
+		})
+	}
+
+	for _, name := range []string{"cat.txt", "copy", "link"} {
+		_, err := os.Lstat(filepath.Join(env.RootDir, name))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,72 @@
+package disttest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// sleepGraph содержит джоб, который не завершится до конца теста, если его не убить.
+var sleepGraph = build.Graph{
+	Jobs: []build.Job{
+		{
+			ID:   build.ID{'s'},
+			Name: "sleep",
+			Cmds: []build.Cmd{
+				{Exec: []string{"sh", "-c", "echo started; sleep 30"}},
+			},
+		},
+	},
+}
+
+// startSleepBuild запускает sleepGraph и дожидается, пока джоб начнёт выполняться.
+func startSleepBuild(t *testing.T, env *env) (*api.BuildClient, build.ID, api.StatusReader) {
+	builds := api.NewBuildClient(env.Logger.Named("test"), env.CoordinatorEndpoint)
+
+	started, r, err := builds.StartBuild(env.Ctx, &api.BuildRequest{Graph: sleepGraph})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+
+	_, err = builds.SignalBuild(env.Ctx, started.ID, &api.SignalRequest{UploadDone: &api.UploadDone{}})
+	require.NoError(t, err)
+
+	var stdout string
+	for !strings.Contains(stdout, "started") {
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.NotNil(t, u.JobOutput)
+		stdout += string(u.JobOutput.Stdout)
+	}
+
+	return builds, started.ID, r
+}
+
+func TestCancelBuild(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	builds, buildID, r := startSleepBuild(t, env)
+
+	_, err := builds.SignalBuild(env.Ctx, buildID, &api.SignalRequest{Cancel: &api.Cancel{}})
+	require.NoError(t, err)
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.NotNil(t, u.BuildFailed)
+	require.Equal(t, "build cancelled", u.BuildFailed.Error)
+
+	// У воркера один слот. Сборка завершится, только если воркер убил джоб отменённой сборки.
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+}
+
+func TestCancelOnDisconnect(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	_, _, r := startSleepBuild(t, env)
+	require.NoError(t, r.Close())
+
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+}
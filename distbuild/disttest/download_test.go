@@ -0,0 +1,107 @@
+package disttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+var packGraph = build.Graph{
+	Jobs: []build.Job{
+		{
+			ID:   build.ID{'p'},
+			Name: "pack",
+			Cmds: []build.Cmd{
+				{Exec: []string{"sh", "-c", `
+cd {{.OutputDir}}
+mkdir bin lib
+echo tool > bin/tool
+chmod 755 bin/tool
+ln -s tool bin/link
+echo a > lib/a.txt
+chmod 750 lib
+echo skip > skip.txt
+`}},
+			},
+		},
+	},
+}
+
+func testDownloadOutputs(t *testing.T, env *env, opts ...client.Option) {
+	outDir := filepath.Join(env.RootDir, "out")
+	require.NoError(t, os.MkdirAll(filepath.Join(outDir, "bin"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(outDir, "bin", "tool"), []byte("stale\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(outDir, "lib"), 0777))
+
+	opts = append(opts, client.WithOutputs(outDir, client.Output{
+		JobID: build.ID{'p'},
+		Globs: []string{"bin/*", "lib"},
+	}))
+	c := client.NewClient(env.Logger.Named("client"), env.CoordinatorEndpoint, env.SourceDir, opts...)
+
+	recorder := NewRecorder()
+	require.NoError(t, c.Build(env.Ctx, packGraph, recorder))
+	require.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[build.ID{'p'}])
+
+	tool, err := os.ReadFile(filepath.Join(outDir, "bin", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool\n", string(tool))
+
+	st, err := os.Stat(filepath.Join(outDir, "bin", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), st.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(outDir, "bin", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool", link)
+
+	a, err := os.ReadFile(filepath.Join(outDir, "lib", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(a))
+
+	st, err = os.Stat(filepath.Join(outDir, "lib"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), st.Mode().Perm())
+
+	entries, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.Equal(t, []string{"bin", "lib"}, names)
+}
+
+func TestDownloadOutputs(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+
+	testDownloadOutputs(t, env)
+}
+
+func TestDownloadOutputsTLS(t *testing.T) {
+	env := newEnv(t, tlsConfig)
+
+	testDownloadOutputs(t, env, client.WithHTTPClient(env.NewHTTPClient(t, "", testToken)))
+}
+
+func TestDownloadOutputsErrors(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+	outDir := filepath.Join(env.RootDir, "out")
+
+	c := client.NewClient(env.Logger.Named("client"), env.CoordinatorEndpoint, env.SourceDir,
+		client.WithOutputs(outDir, client.Output{JobID: build.ID{'x'}}))
+	err := c.Build(env.Ctx, packGraph, NewRecorder())
+	require.ErrorContains(t, err, "is not in the build graph")
+
+	c = client.NewClient(env.Logger.Named("client"), env.CoordinatorEndpoint, env.SourceDir,
+		client.WithOutputs(outDir, client.Output{JobID: build.ID{'p'}, Globs: []string{"missing/*"}}))
+	err = c.Build(env.Ctx, packGraph, NewRecorder())
+	require.ErrorContains(t, err, "no files match")
+}
@@ -0,0 +1,187 @@
+package disttest
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// fragmentCmd записывает фрагмент графа в {{.OutputDir}}/graph.json. В командах фрагмента не должно
+// быть шаблонов: CatTemplate рендерится в контексте генератора.
+func fragmentCmd(t *testing.T, fragment build.Graph) build.Cmd {
+	data, err := json.Marshal(fragment)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "{{")
+
+	return build.Cmd{CatTemplate: string(data), CatOutput: "{{.OutputDir}}/graph.json"}
+}
+
+func TestExpandGraph(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2})
+
+	// graph.json ссылается на a.txt и на выход генератора g, поэтому копируется без рендеринга.
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "graph.json", "a.txt"),
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'g'},
+				Name:   "generate",
+				Inputs: []string{"graph.json"},
+				Cmds: []build.Cmd{
+					{CopyFrom: "{{.SourceDir}}/graph.json", CopyTo: "{{.OutputDir}}/graph.json"},
+					{CatTemplate: "gen\n", CatOutput: "{{.OutputDir}}/gen.txt"},
+				},
+				Fragment: "graph.json",
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	added := recorder.Added[build.ID{'g'}]
+	require.Len(t, added, 2)
+	assert.Equal(t, build.ID{'a'}, added[0].ID)
+	assert.Equal(t, []build.ID{{'g'}}, added[0].Deps)
+	assert.Equal(t, "b", added[1].Name)
+	assert.Equal(t, []build.ID{{'a'}, {'g'}}, added[1].Deps)
+
+	assert.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[build.ID{'g'}])
+	assert.Equal(t, &JobResult{Stdout: "a\ngen\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "b\n", Code: new(int)}, recorder.Jobs[added[1].ID])
+}
+
+func TestExpandGraphNested(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+
+	// Джоб b из graph.json сам записывает фрагмент с джобом c.
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "graph.json"),
+		Jobs: []build.Job{
+			{
+				ID:       build.ID{'a'},
+				Name:     "a",
+				Inputs:   []string{"graph.json"},
+				Cmds:     []build.Cmd{{CopyFrom: "{{.SourceDir}}/graph.json", CopyTo: "{{.OutputDir}}/graph.json"}},
+				Fragment: "graph.json",
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	require.Len(t, recorder.Added[build.ID{'a'}], 1)
+	require.Len(t, recorder.Added[build.ID{'b'}], 1)
+	assert.Equal(t, &JobResult{Stdout: "c\n", Code: new(int)}, recorder.Jobs[build.ID{'c'}])
+}
+
+func TestExpandGraphInvalidFragment(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1})
+
+	for _, test := range []struct {
+		name string
+		job  build.Job
+		err  string
+	}{
+		{
+			name: "unknown dep",
+			job: build.Job{
+				ID:   build.ID{'a'},
+				Name: "unknown dep",
+				Cmds: []build.Cmd{fragmentCmd(t, build.Graph{Jobs: []build.Job{
+					{Name: "x", Deps: []build.ID{{'x'}}},
+				}})},
+				Fragment: "graph.json",
+			},
+			err: "unknown dependency",
+		},
+		{
+			name: "not json",
+			job: build.Job{
+				ID:       build.ID{'b'},
+				Name:     "not json",
+				Cmds:     []build.Cmd{{CatTemplate: "graph", CatOutput: "{{.OutputDir}}/graph.json"}},
+				Fragment: "graph.json",
+			},
+			err: "invalid graph fragment",
+		},
+		{
+			name: "missing",
+			job: build.Job{
+				ID:       build.ID{'c'},
+				Name:     "missing",
+				Cmds:     []build.Cmd{{Exec: []string{"true"}}},
+				Fragment: "graph.json",
+			},
+			err: "job did not write graph.json",
+		},
+		{
+			name: "symlink",
+			job: build.Job{
+				ID:       build.ID{'d'},
+				Name:     "symlink",
+				Cmds:     []build.Cmd{{SymlinkTarget: filepath.Join(env.RootDir, "graph.json"), SymlinkPath: "{{.OutputDir}}/graph.json"}},
+				Fragment: "graph.json",
+			},
+			err: "graph.json is not a regular file",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := NewRecorder()
+			err := env.Client.Build(env.Ctx, build.Graph{Jobs: []build.Job{test.job}}, recorder)
+			require.Error(t, err)
+
+			res := recorder.Jobs[test.job.ID]
+			require.NotNil(t, res)
+			assert.True(t, strings.HasPrefix(res.Error, "graph fragment graph.json: "), res.Error)
+			assert.Contains(t, res.Error, test.err)
+			assert.Empty(t, recorder.Added)
+		})
+	}
+}
+
+func TestExpandGraphCoordinatorRestart(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, Journal: true})
+
+	signal := filepath.Join(t.TempDir(), "signal")
+
+	fragment := build.Graph{Jobs: []build.Job{
+		{
+			ID:   build.ID{'b'},
+			Name: "across restart",
+			Cmds: []build.Cmd{
+				{Exec: []string{"sh", "-c", `
+echo waiting
+i=0
+while [ ! -e "$0" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+echo done
+`, signal}},
+			},
+		},
+		{
+			ID:   build.ID{'c'},
+			Name: "after restart",
+			Deps: []build.ID{{'b'}},
+			Cmds: []build.Cmd{{Exec: []string{"echo", "c"}}},
+		},
+	}}
+
+	graph := build.Graph{Jobs: []build.Job{
+		{ID: build.ID{'a'}, Name: "generate", Cmds: []build.Cmd{fragmentCmd(t, fragment)}, Fragment: "graph.json"},
+	}}
+
+	lsn := &restartingListener{Recorder: NewRecorder(), t: t, env: env, signal: signal}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+	require.True(t, lsn.restarted)
+
+	assert.Len(t, lsn.Added[build.ID{'a'}], 2)
+	assert.Equal(t, &JobResult{Stdout: "waiting\ndone\n", Code: new(int)}, lsn.Jobs[build.ID{'b'}])
+	assert.Equal(t, &JobResult{Stdout: "c\n", Code: new(int)}, lsn.Jobs[build.ID{'c'}])
+}
@@ -2,6 +2,7 @@ package disttest
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -10,17 +11,26 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth/authtest"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/client"
 	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
 	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
 	"gitlab.com/slon/shad-go/tools/testtool"
 
@@ -33,12 +43,32 @@ type env struct {
 
 	Ctx context.Context
 
+	CoordinatorEndpoint string
+
+	// SourceDir - директория с исходными файлами теста, testdata/<имя теста>.
+	SourceDir string
+
+	// CA выпускает сертификаты, если включён Config.TLS.
+	CA *authtest.CA
+
 	Client      *client.Client
 	Coordinator *dist.Coordinator
 	Workers     []*worker.Worker
 	WorkerCache []*artifact.Cache
 
+	stopWorkers []context.CancelFunc
+
+	// Поля ниже нужны, чтобы перезапустить координатора.
+	coordinatorCache *filecache.Cache
+	coordinatorOpts  []dist.Option
+	journalPath      string
+	journal          *journal.Journal
+	proxy            *coordinatorProxy
+
 	HTTP *http.Server
+
+	// GRPC - соединение с gRPC сервером координатора, если включён Config.GRPC.
+	GRPC *grpc.ClientConn
 }
 
 const (
@@ -47,8 +77,45 @@ const (
 
 type Config struct {
 	WorkerCount int
+
+	// Sandbox включает запуск джобов в песочнице на всех воркерах.
+	Sandbox *sandbox.Config
+
+	// Hermetic включает проверку герметичности джобов на всех воркерах.
+	Hermetic bool
+
+	// WorkerTimeout задаёт время, через которое координатор теряет воркера без heartbeat-ов.
+	WorkerTimeout time.Duration
+
+	// RemoteCache включает на координаторе удалённый кеш по протоколу Bazel.
+	RemoteCache bool
+
+	// Speculative включает на координаторе повторный запуск джобов, которые выполняются вдвое
+	// дольше обычного, но не меньше 200ms.
+	Speculative bool
+
+	// Capacity задаёт ресурсы каждого воркера.
+	Capacity *build.Resources
+
+	// CompressedTransfer включает сжатие артефактов при передаче между воркерами.
+	CompressedTransfer bool
+
+	// Tools задаёт инструменты всех воркеров.
+	Tools []worker.Tool
+
+	// Journal включает журнал координатора, который нужен для RestartCoordinator.
+	Journal bool
+
+	// TLS включает https и аутентификацию: воркеры предъявляют сертификаты от env.CA,
+	// а клиент - токен testToken.
+	TLS bool
+
+	// GRPC переводит сборки клиента и heartbeat-ы воркеров на gRPC.
+	GRPC bool
 }
 
+const testToken = "test-token"
+
 func newEnv(t *testing.T, config *Config) (e *env) {
 	t.Cleanup(func() {
 		goleak.VerifyNone(t)
@@ -76,6 +143,34 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		RootDir: rootDir,
 	}
 
+	var workerOpts []worker.Option
+	if config.Sandbox != nil {
+		s, err := sandbox.New(*config.Sandbox)
+		if err != nil {
+			t.Skipf("sandbox is not available: %v", err)
+		}
+		workerOpts = append(workerOpts, worker.WithSandbox(s))
+	}
+
+	if config.Capacity != nil {
+		workerOpts = append(workerOpts, worker.WithCapacity(*config.Capacity))
+	}
+
+	if config.CompressedTransfer {
+		workerOpts = append(workerOpts, worker.WithCompressedTransfer())
+	}
+
+	if len(config.Tools) != 0 {
+		workerOpts = append(workerOpts, worker.WithTools(config.Tools...))
+	}
+
+	if config.Hermetic {
+		if _, _, err := hermetic.Run(context.Background(), &build.Cmd{Exec: []string{"true"}}, nil, nil); err != nil {
+			t.Skipf("tracing is not available: %v", err)
+		}
+		workerOpts = append(workerOpts, worker.WithHermeticMode())
+	}
+
 	cfg := zap.NewDevelopmentConfig()
 
 	if runtime.GOOS == "windows" {
@@ -102,29 +197,107 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 
 	port, err := testtool.GetFreePort()
 	require.NoError(t, err)
+	scheme := "http"
+	if config.TLS {
+		scheme = "https"
+		env.CA = authtest.NewCA(t)
+	}
+
 	addr := "127.0.0.1:" + port
-	coordinatorEndpoint := "http://" + addr + "/coordinator"
+	coordinatorEndpoint := scheme + "://" + addr + "/coordinator"
+	env.CoordinatorEndpoint = coordinatorEndpoint
 
 	var cancelRootContext func()
 	env.Ctx, cancelRootContext = context.WithCancel(context.Background())
 	t.Cleanup(cancelRootContext)
 
+	env.SourceDir = filepath.Join(absCWD, "testdata", t.Name())
+	var clientOpts []client.Option
+	if config.TLS {
+		clientOpts = append(clientOpts, client.WithHTTPClient(env.NewHTTPClient(t, "", testToken)))
+	}
+
+	var grpcLsn net.Listener
+	if config.GRPC {
+		grpcLsn, err = net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		var clientTLS *tls.Config
+		var token string
+		if config.TLS {
+			clientTLS, token = env.NewTLSConfig(t, ""), testToken
+		}
+		env.GRPC = env.dialGRPC(t, grpcLsn.Addr().String(), clientTLS, token)
+		clientOpts = append(clientOpts, client.WithGRPC(env.GRPC))
+
+		// С TLS каждый воркер открывает своё соединение со своим сертификатом.
+		if !config.TLS {
+			workerOpts = append(workerOpts, worker.WithGRPC(env.GRPC))
+		}
+	}
+
 	env.Client = client.NewClient(
 		env.Logger.Named("client"),
 		coordinatorEndpoint,
-		filepath.Join(absCWD, "testdata", t.Name()))
+		env.SourceDir,
+		clientOpts...)
 
 	coordinatorCache, err := filecache.New(filepath.Join(env.RootDir, "coordinator", "filecache"))
 	require.NoError(t, err)
 
-	env.Coordinator = dist.NewCoordinator(
-		env.Logger.Named("coordinator"),
-		coordinatorCache,
-	)
-	t.Cleanup(env.Coordinator.Stop)
+	var coordinatorOpts []dist.Option
+	if config.WorkerTimeout != 0 {
+		coordinatorOpts = append(coordinatorOpts, dist.WithWorkerTimeout(config.WorkerTimeout))
+	}
+
+	if config.Speculative {
+		coordinatorOpts = append(coordinatorOpts, dist.WithSpeculativeExecution(2, 200*time.Millisecond))
+	}
+
+	if config.TLS {
+		coordinatorOpts = append(coordinatorOpts,
+			dist.WithAuth(auth.NewAuthenticator(testToken)),
+			dist.WithHTTPClient(env.NewHTTPClient(t, "coordinator", "")))
+	}
+
+	if config.RemoteCache {
+		actionCache, err := artifact.NewCache(filepath.Join(env.RootDir, "coordinator", "ac"))
+		require.NoError(t, err)
+		cas, err := filecache.New(filepath.Join(env.RootDir, "coordinator", "cas"))
+		require.NoError(t, err)
+		coordinatorOpts = append(coordinatorOpts, dist.WithRemoteCache(actionCache, cas))
+	}
+
+	env.coordinatorCache = coordinatorCache
+	env.coordinatorOpts = coordinatorOpts
+	if config.Journal {
+		env.journalPath = filepath.Join(env.RootDir, "coordinator", "journal")
+	}
+
+	env.startCoordinator(t)
+	if config.GRPC {
+		require.Empty(t, env.journalPath, "coordinator restart is not supported with grpc")
+
+		var serverTLS *tls.Config
+		if config.TLS {
+			certFile, keyFile := env.CA.Issue(t, "grpc")
+			serverTLS, err = auth.ServerTLS(certFile, keyFile, env.CA.CertFile)
+			require.NoError(t, err)
+		}
+
+		s, err := env.Coordinator.NewGRPCServer(serverTLS)
+		require.NoError(t, err)
+		go func() { _ = s.Serve(grpcLsn) }()
+		t.Cleanup(s.Stop)
+	}
+
+	env.proxy = &coordinatorProxy{
+		handler: http.StripPrefix("/coordinator", env.Coordinator),
+		streams: make(map[net.Conn]struct{}),
+	}
 
 	router := http.NewServeMux()
-	router.Handle("/coordinator/", http.StripPrefix("/coordinator", env.Coordinator))
+	router.Handle("/coordinator/", env.proxy)
 
 	for i := 0; i < config.WorkerCount; i++ {
 		workerName := fmt.Sprintf("worker%d", i)
@@ -139,7 +312,18 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 		require.NoError(t, err)
 
 		workerPrefix := fmt.Sprintf("/worker/%d", i)
-		workerID := api.WorkerID("http://" + addr + workerPrefix)
+		workerID := api.WorkerID(scheme + "://" + addr + workerPrefix)
+
+		opts := workerOpts[:len(workerOpts):len(workerOpts)]
+		if config.TLS {
+			opts = append(opts,
+				worker.WithHTTPClient(env.NewHTTPClient(t, workerName, "", workerID.String())),
+				worker.WithAuth(auth.NewAuthenticator()))
+
+			if config.GRPC {
+				opts = append(opts, worker.WithGRPC(env.dialGRPC(t, grpcLsn.Addr().String(), env.NewTLSConfig(t, workerName, workerID.String()), "")))
+			}
+		}
 
 		w := worker.New(
 			workerID,
@@ -147,6 +331,7 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 			env.Logger.Named(workerName),
 			fileCache,
 			artifacts,
+			opts...,
 		)
 
 		env.Workers = append(env.Workers, w)
@@ -158,13 +343,27 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	env.HTTP = &http.Server{
 		Addr:    addr,
 		Handler: router,
+		ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+			return context.WithValue(ctx, connKey{}, c)
+		},
+	}
+
+	if config.TLS {
+		certFile, keyFile := env.CA.Issue(t, "server")
+		env.HTTP.TLSConfig, err = auth.ServerTLS(certFile, keyFile, env.CA.CertFile)
+		require.NoError(t, err)
 	}
 
 	lsn, err := net.Listen("tcp", env.HTTP.Addr)
 	require.NoError(t, err)
 
 	go func() {
-		err := env.HTTP.Serve(lsn)
+		var err error
+		if env.HTTP.TLSConfig != nil {
+			err = env.HTTP.ServeTLS(lsn, "", "")
+		} else {
+			err = env.HTTP.Serve(lsn)
+		}
 		if err != http.ErrServerClosed {
 			env.Logger.Fatal("http server stopped", zap.Error(err))
 		}
@@ -176,8 +375,11 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	})
 
 	for _, w := range env.Workers {
+		ctx, stop := context.WithCancel(env.Ctx)
+		env.stopWorkers = append(env.stopWorkers, stop)
+
 		go func(w *worker.Worker) {
-			err := w.Run(env.Ctx)
+			err := w.Run(ctx)
 			if errors.Is(err, context.Canceled) {
 				return
 			}
@@ -198,6 +400,117 @@ func newEnv(t *testing.T, config *Config) (e *env) {
 	return env
 }
 
+// NewTLSConfig создаёт клиентскую TLS конфигурацию, которая доверяет env.CA. Если name не пустой,
+// клиент предъявляет сертификат name с URI uris.
+func (e *env) NewTLSConfig(t *testing.T, name string, uris ...string) *tls.Config {
+	var certFile, keyFile string
+	if name != "" {
+		certFile, keyFile = e.CA.Issue(t, name, uris...)
+	}
+
+	config, err := auth.ClientTLS(certFile, keyFile, e.CA.CertFile)
+	require.NoError(t, err)
+	return config
+}
+
+// NewHTTPClient создаёт http.Client, который доверяет env.CA. Если name не пустой, клиент предъявляет
+// сертификат name с URI uris, а если token не пустой - токен.
+func (e *env) NewHTTPClient(t *testing.T, name, token string, uris ...string) *http.Client {
+	c := auth.NewHTTPClient(e.NewTLSConfig(t, name, uris...), token)
+	t.Cleanup(c.CloseIdleConnections)
+	return c
+}
+
+// dialGRPC открывает соединение с gRPC сервером координатора, см. auth.DialOptions.
+func (e *env) dialGRPC(t *testing.T, addr string, config *tls.Config, token string) *grpc.ClientConn {
+	cc, err := grpc.Dial(addr, auth.DialOptions(config, token)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+	return cc
+}
+
+// StopWorker останавливает i-го воркера так, как будто его процесс упал: воркер перестаёт
+// слать heartbeat-ы, а его джобы убиваются.
+func (e *env) StopWorker(i int) {
+	e.stopWorkers[i]()
+}
+
+// startCoordinator создаёт координатора. С журналом координатор восстанавливает из него состояние.
+func (e *env) startCoordinator(t *testing.T) {
+	opts := e.coordinatorOpts
+	if e.journalPath != "" {
+		j, state, err := journal.Open(e.journalPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = j.Close() })
+
+		e.journal = j
+		opts = append(opts[:len(opts):len(opts)], dist.WithJournal(j, state))
+	}
+
+	e.Coordinator = dist.NewCoordinator(
+		e.Logger.Named("coordinator"),
+		e.coordinatorCache,
+		opts...,
+	)
+	t.Cleanup(e.Coordinator.Stop)
+}
+
+// RestartCoordinator заменяет координатора новым, который восстанавливает состояние из журнала.
+// Соединения клиентов со статусом сборок рвутся, как при перезапуске процесса координатора.
+func (e *env) RestartCoordinator(t *testing.T) {
+	require.NotEmpty(t, e.journalPath, "coordinator journal is disabled")
+
+	old := e.Coordinator
+	require.NoError(t, e.journal.Close())
+
+	e.startCoordinator(t)
+	e.proxy.restart(http.StripPrefix("/coordinator", e.Coordinator))
+	old.Stop()
+}
+
+type connKey struct{}
+
+// coordinatorProxy передаёт запросы текущему координатору и помнит соединения, через которые
+// клиенты читают статус сборок.
+type coordinatorProxy struct {
+	mu      sync.Mutex
+	handler http.Handler
+	streams map[net.Conn]struct{}
+}
+
+func (p *coordinatorProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	handler := p.handler
+	p.mu.Unlock()
+
+	if strings.HasSuffix(r.URL.Path, "/build") || strings.HasSuffix(r.URL.Path, "/attach") {
+		conn := r.Context().Value(connKey{}).(net.Conn)
+
+		p.mu.Lock()
+		p.streams[conn] = struct{}{}
+		p.mu.Unlock()
+
+		defer func() {
+			p.mu.Lock()
+			delete(p.streams, conn)
+			p.mu.Unlock()
+		}()
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// restart отправляет новые запросы в handler и рвёт соединения со статусом сборок.
+func (p *coordinatorProxy) restart(handler http.Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handler = handler
+	for conn := range p.streams {
+		_ = conn.Close()
+	}
+}
+
 func newWinFileSink(u *url.URL) (zap.Sink, error) {
 	if len(u.Opaque) > 0 {
 		// Remove leading slash left by url.Parse()
@@ -206,3 +519,19 @@ func newWinFileSink(u *url.URL) (zap.Sink, error) {
 	// if url.URL is empty, don't panic slice index error
 	return os.OpenFile(u.Opaque, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
 }
+
+// sourceFiles возвращает Graph.SourceFiles для файлов paths из env.SourceDir.
+func (e *env) sourceFiles(t *testing.T, paths ...string) map[build.ID]string {
+	files := make(map[build.ID]string, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(filepath.Join(e.SourceDir, filepath.FromSlash(path)))
+		require.NoError(t, err)
+
+		id, err := build.FileID(path, f)
+		_ = f.Close()
+		require.NoError(t, err)
+
+		files[id] = path
+	}
+	return files
+}
@@ -0,0 +1,56 @@
+package disttest
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/gograph"
+	"gitlab.com/slon/shad-go/distbuild/pkg/worker"
+)
+
+func TestGoModule(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", t.Name()))
+	require.NoError(t, err)
+
+	graph, err := gograph.Generate(t.Context(), gograph.Config{Dir: dir})
+	require.NoError(t, err)
+
+	paths, err := gograph.LocalTools(t.Context())
+	require.NoError(t, err)
+
+	// Прогреваем кеш стандартной библиотеки до старта таймера в newEnv.
+	warmup := exec.Command("go", "list", "-export", "-deps", "fmt", "os", "testing", "testing/internal/testdeps")
+	warmup.Dir = dir
+	for _, v := range gograph.DefaultEnv() {
+		v, err = build.JobContext{Tools: paths}.RenderTemplate(v)
+		require.NoError(t, err)
+		warmup.Env = append(warmup.Env, v)
+	}
+	require.NoError(t, warmup.Run())
+
+	config := &Config{WorkerCount: 1}
+	for _, name := range gograph.Tools {
+		config.Tools = append(config.Tools, worker.Tool{Name: name, Path: paths[name], Writable: name == "GOCACHE"})
+	}
+	env := newEnv(t, config)
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, *graph, recorder))
+
+	require.Len(t, recorder.Jobs, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		result := recorder.Jobs[job.ID]
+		require.NotNil(t, result, job.Name)
+		assert.Equal(t, new(int), result.Code, "%s: %s", job.Name, result.Stderr)
+
+		if strings.HasPrefix(job.Name, "test ") {
+			assert.Equal(t, "PASS\n", result.Stdout, job.Name)
+		}
+	}
+}
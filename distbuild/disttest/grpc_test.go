@@ -0,0 +1,97 @@
+package disttest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestGRPC(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, GRPC: true})
+
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "a.txt"),
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "copy",
+				Inputs: []string{"a.txt"},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cp", "{{.SourceDir}}/a.txt", "{{.OutputDir}}/out.txt"}},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "cat",
+				Deps: []build.ID{{'a'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				},
+			},
+			{
+				ID:   build.ID{'c'},
+				Name: "fail",
+				Deps: []build.ID{{'b'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", "echo oops >&2; exit 3"}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+
+	code := 3
+	assert.Equal(t, &JobResult{Stderr: "oops\n", Code: &code}, recorder.Jobs[build.ID{'c'}])
+}
+
+func TestGRPCCancelOnDisconnect(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, GRPC: true})
+
+	builds := api.NewBuildGRPCClient(env.Logger.Named("test"), env.GRPC)
+
+	started, r, err := builds.StartBuild(env.Ctx, &api.BuildRequest{Graph: sleepGraph})
+	require.NoError(t, err)
+
+	_, err = builds.SignalBuild(env.Ctx, started.ID, &api.SignalRequest{UploadDone: &api.UploadDone{}})
+	require.NoError(t, err)
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.NotNil(t, u.JobOutput)
+
+	// У воркера один слот. Сборка завершится, только если координатор заметил закрытый
+	// gRPC поток и воркер убил джоб.
+	require.NoError(t, r.Close())
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+}
+
+func TestGRPCWithTLS(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, GRPC: true, TLS: true})
+
+	// Клиент запускает сборку с токеном, а воркеры шлют heartbeat-ы со своими сертификатами.
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+
+	addr := env.GRPC.Target()
+
+	anonymous := api.NewBuildGRPCClient(env.Logger.Named("anonymous"), env.dialGRPC(t, addr, env.NewTLSConfig(t, ""), ""))
+	_, _, err := anonymous.StartBuild(env.Ctx, &api.BuildRequest{Graph: echoGraph})
+	require.ErrorContains(t, err, "code = Unauthenticated")
+
+	// Токен клиента не даёт права присылать heartbeat-ы.
+	workerID := api.WorkerID(strings.TrimSuffix(env.CoordinatorEndpoint, "/coordinator") + "/worker/0")
+	withToken := api.NewHeartbeatGRPCClient(env.Logger.Named("rogue"), env.dialGRPC(t, addr, env.NewTLSConfig(t, ""), testToken))
+	_, err = withToken.Heartbeat(env.Ctx, &api.HeartbeatRequest{WorkerID: workerID})
+	require.ErrorContains(t, err, "code = PermissionDenied desc = forbidden: worker certificate required")
+}
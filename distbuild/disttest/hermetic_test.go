@@ -0,0 +1,109 @@
+package disttest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+)
+
+var hermeticConfig = &Config{WorkerCount: 1, Hermetic: true}
+
+func TestHermeticArtifactTransfer(t *testing.T) {
+	env := newEnv(t, hermeticConfig)
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, artifactTransferGraph, recorder))
+
+	assert.Len(t, recorder.Jobs, 2)
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func undeclaredInputGraph(files map[build.ID]string, secret string) build.Graph {
+	return build.Graph{
+		SourceFiles: files,
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "cat",
+				Inputs: []string{"a.txt"},
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", "cat {{.SourceDir}}/a.txt; cat {{.SourceDir}}/b.txt " + secret + " 2>/dev/null; true"}},
+				},
+			},
+		},
+	}
+}
+
+func testUndeclaredInput(t *testing.T, config *Config) {
+	env := newEnv(t, config)
+
+	secret := filepath.Join(env.RootDir, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0666))
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, undeclaredInputGraph(env.sourceFiles(t, "a.txt", "b.txt"), secret), recorder))
+
+	result := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, result)
+	require.NotNil(t, result.Code)
+	assert.Contains(t, result.Error, "job is not hermetic")
+
+	kinds := map[string]api.ViolationKind{}
+	for _, v := range result.Violations {
+		kinds[filepath.Base(v.Path)] = v.Kind
+	}
+
+	assert.Equal(t, api.UndeclaredInput, kinds["b.txt"])
+	assert.Equal(t, api.ReadOutside, kinds["secret.txt"])
+	assert.NotContains(t, kinds, "a.txt")
+}
+
+func TestHermeticUndeclaredInput(t *testing.T) {
+	testUndeclaredInput(t, hermeticConfig)
+}
+
+func TestHermeticUndeclaredInputInSandbox(t *testing.T) {
+	testUndeclaredInput(t, &Config{WorkerCount: 1, Hermetic: true, Sandbox: &sandbox.Config{}})
+}
+
+func TestHermeticBuiltinCopy(t *testing.T) {
+	env := newEnv(t, hermeticConfig)
+
+	// copy должен сообщить о чтении b.txt, хотя сама команда получает только корень SourceDir.
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "a.txt"),
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "copy",
+				Inputs: []string{"a.txt"},
+				Cmds: []build.Cmd{
+					{Exec: []string{"bash", "-c", "echo b > {{.SourceDir}}/b.txt"}},
+					{CopyFrom: "{{.SourceDir}}", CopyTo: "{{.OutputDir}}/src"},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	result := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, result)
+	assert.Contains(t, result.Error, "job is not hermetic")
+
+	kinds := map[api.ViolationKind][]string{}
+	for _, v := range result.Violations {
+		kinds[v.Kind] = append(kinds[v.Kind], filepath.Base(v.Path))
+	}
+
+	assert.Equal(t, []string{"b.txt"}, kinds[api.UndeclaredInput])
+	assert.Equal(t, []string{"b.txt"}, kinds[api.WriteOutside])
+}
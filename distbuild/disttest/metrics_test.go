@@ -0,0 +1,98 @@
+package disttest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func scrape(t *testing.T, endpoint string) string {
+	rsp, err := http.Get(endpoint + "/metrics")
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// metricValue возвращает значение временного ряда series или 0, если его нет.
+func metricValue(t *testing.T, metrics, series string) float64 {
+	for _, line := range strings.Split(metrics, "\n") {
+		if value, ok := strings.CutPrefix(line, series+" "); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return v
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2})
+
+	// Джобы b0 и b1 зависят от a и выполняются дольше DepsTimeout, поэтому один из них
+	// уходит на второй воркер вместе с артефактом a.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{ID: build.ID{'a'}, Name: "write", Cmds: []build.Cmd{{CatTemplate: "OK", CatOutput: "{{.OutputDir}}/out.txt"}}},
+		},
+	}
+	for i := range 2 {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:   build.ID{'b', byte(i)},
+			Name: "cat",
+			Deps: []build.ID{{'a'}},
+			Cmds: []build.Cmd{
+				{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				{Exec: []string{"sleep", "0.5"}, Environ: os.Environ()},
+			},
+		})
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+
+	coordinator := scrape(t, env.CoordinatorEndpoint)
+	require.Equal(t, 3.0, metricValue(t, coordinator, `distbuild_coordinator_job_duration_seconds_count{result="success"}`))
+	require.Equal(t, 0.0, metricValue(t, coordinator, `distbuild_coordinator_queued_jobs{queue="all"}`))
+	require.Equal(t, 2.0, metricValue(t, coordinator, `distbuild_coordinator_workers`))
+	require.Equal(t, 0.0, metricValue(t, coordinator, `distbuild_coordinator_builds`))
+
+	workerEndpoint := strings.TrimSuffix(env.CoordinatorEndpoint, "/coordinator") + "/worker/"
+
+	var jobs, misses, sent, received, cacheBytes float64
+	for i := range 2 {
+		metrics := scrape(t, workerEndpoint+strconv.Itoa(i))
+
+		require.NotZero(t, metricValue(t, metrics, "distbuild_worker_heartbeat_duration_seconds_count"))
+		require.NotZero(t, metricValue(t, metrics, "distbuild_worker_heartbeat_wait_seconds_count"))
+
+		// Свободный воркер почти всё время heartbeat-а ждёт джоб на координаторе.
+		require.Less(t,
+			metricValue(t, metrics, "distbuild_worker_heartbeat_duration_seconds_sum"),
+			metricValue(t, metrics, "distbuild_worker_heartbeat_wait_seconds_sum"))
+		require.Zero(t, metricValue(t, metrics, "distbuild_worker_running_jobs"))
+
+		jobs += metricValue(t, metrics, `distbuild_worker_job_duration_seconds_count{result="success"}`)
+		misses += metricValue(t, metrics, `distbuild_worker_cache_requests_total{cache="artifact",result="miss"}`)
+		sent += metricValue(t, metrics, `distbuild_worker_artifact_transfer_bytes_total{direction="sent"}`)
+		received += metricValue(t, metrics, `distbuild_worker_artifact_transfer_bytes_total{direction="received"}`)
+		cacheBytes += metricValue(t, metrics, "distbuild_worker_artifact_cache_bytes")
+	}
+
+	require.Equal(t, 3.0, jobs)
+	require.Equal(t, 1.0, misses)
+	require.NotZero(t, received)
+	require.Equal(t, sent, received)
+	require.NotZero(t, cacheBytes)
+}
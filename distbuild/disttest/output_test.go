@@ -0,0 +1,66 @@
+package disttest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// streamingListener создаёт файл-сигнал, как только увидит первую строку вывода джоба.
+type streamingListener struct {
+	*Recorder
+
+	signal  string
+	created bool
+}
+
+func (l *streamingListener) OnJobStdout(jobID build.ID, stdout []byte) error {
+	if err := l.Recorder.OnJobStdout(jobID, stdout); err != nil {
+		return err
+	}
+
+	j := l.job(jobID)
+	if !l.created && j.Code == nil && strings.Contains(j.Stdout, "first") {
+		l.created = true
+		return os.WriteFile(l.signal, nil, 0666)
+	}
+	return nil
+}
+
+func TestStreamingOutput(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	signal := filepath.Join(t.TempDir(), "signal")
+
+	// Джоб ждёт файл-сигнал не дольше 5 секунд. Файл появится, только если клиент
+	// получит первую строку до завершения джоба.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "stream",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+echo first
+i=0
+while [ ! -e "$0" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+[ -e "$0" ] || echo "signal timeout" >&2
+echo second
+`, signal}},
+				},
+			},
+		},
+	}
+
+	lsn := &streamingListener{Recorder: NewRecorder(), signal: signal}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+
+	assert.True(t, lsn.created)
+	assert.Equal(t, &JobResult{Stdout: "first\nsecond\n", Code: new(int)}, lsn.Jobs[build.ID{'a'}])
+}
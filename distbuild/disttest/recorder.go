@@ -1,6 +1,7 @@
 package disttest
 
 import (
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
@@ -10,15 +11,21 @@ type JobResult struct {
 
 	Code  *int
 	Error string
+
+	Violations []api.Violation
 }
 
 type Recorder struct {
 	Jobs map[build.ID]*JobResult
+
+	// Added хранит джобы, которые добавил в сборку каждый джоб с фрагментом графа.
+	Added map[build.ID][]build.Job
 }
 
 func NewRecorder() *Recorder {
 	return &Recorder{
-		Jobs: map[build.ID]*JobResult{},
+		Jobs:  map[build.ID]*JobResult{},
+		Added: map[build.ID][]build.Job{},
 	}
 }
 
@@ -55,3 +62,14 @@ func (r *Recorder) OnJobFailed(jobID build.ID, code int, error string) error {
 	j.Error = error
 	return nil
 }
+
+func (r *Recorder) OnJobViolations(jobID build.ID, violations []api.Violation) error {
+	j := r.job(jobID)
+	j.Violations = append(j.Violations, violations...)
+	return nil
+}
+
+func (r *Recorder) OnJobsAdded(generator build.ID, jobs []build.Job) error {
+	r.Added[generator] = append(r.Added[generator], jobs...)
+	return nil
+}
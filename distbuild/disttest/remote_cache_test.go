@@ -0,0 +1,151 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+var remoteCacheConfig = &Config{WorkerCount: 1, RemoteCache: true}
+
+func newCacheClient(env *env, opts ...client.Option) *client.Client {
+	opts = append([]client.Option{client.WithRemoteCache(env.CoordinatorEndpoint)}, opts...)
+	return client.NewClient(env.Logger.Named("cache-client"), env.CoordinatorEndpoint, os.TempDir(), opts...)
+}
+
+// waitCached ждёт, пока координатор в фоне сохранит результаты джобов в удалённый кеш.
+func waitCached(t *testing.T, env *env, ids ...build.ID) {
+	cache := remotecache.NewClient(env.Logger, env.CoordinatorEndpoint)
+	for _, id := range ids {
+		require.Eventually(t, func() bool {
+			_, err := cache.GetActionResult(env.Ctx, id)
+			return err == nil
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestRemoteCacheOnly(t *testing.T) {
+	env := newEnv(t, remoteCacheConfig)
+
+	cacheOnly := newCacheClient(env, client.WithCacheOnly())
+
+	err := cacheOnly.Build(env.Ctx, echoGraph, NewRecorder())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `missing from remote cache, first is "echo"`)
+
+	require.NoError(t, env.Client.Build(env.Ctx, echoGraph, NewRecorder()))
+	waitCached(t, env, build.ID{'a'})
+
+	recorder := NewRecorder()
+	require.NoError(t, cacheOnly.Build(env.Ctx, echoGraph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
+
+func TestRemoteCacheSkipsJobs(t *testing.T) {
+	env := newEnv(t, remoteCacheConfig)
+
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	// Каждый запуск джоба дописывает строку в counter.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "count",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `echo run >> "$0"; echo OK`, counter}},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+	waitCached(t, env, build.ID{'a'})
+
+	// Зависимость берётся из кеша, а её артефакт уже лежит в кеше артефактов воркера.
+	dependent := graph
+	dependent.Jobs = append(dependent.Jobs, build.Job{
+		ID:   build.ID{'b'},
+		Name: "dependent",
+		Deps: []build.ID{{'a'}},
+		Cmds: []build.Cmd{
+			{Exec: []string{"echo", "B"}},
+		},
+	})
+
+	recorder := NewRecorder()
+	require.NoError(t, newCacheClient(env).Build(env.Ctx, dependent, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "B\n", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+
+	recorder = NewRecorder()
+	require.NoError(t, newCacheClient(env).Build(env.Ctx, dependent, recorder))
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "B\n", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+
+	runs, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(runs))
+}
+
+func TestRemoteCacheRestoresOutputs(t *testing.T) {
+	env := newEnv(t, remoteCacheConfig)
+
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "write",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `echo run >> "$0"`, counter}},
+					{MkdirPath: "{{.OutputDir}}/dir/empty"},
+					{CatTemplate: "OK\n", CatOutput: "{{.OutputDir}}/dir/out.txt"},
+					{SymlinkTarget: "dir/out.txt", SymlinkPath: "{{.OutputDir}}/link"},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, env.Client.Build(env.Ctx, graph, NewRecorder()))
+	waitCached(t, env, build.ID{'a'})
+
+	res, err := remotecache.NewClient(env.Logger, env.CoordinatorEndpoint).GetActionResult(env.Ctx, build.ID{'a'})
+	require.NoError(t, err)
+	assert.Equal(t, []remotecache.OutputDirectory{{Path: "dir"}, {Path: "dir/empty"}}, res.OutputDirectories)
+	assert.Equal(t, []remotecache.OutputSymlink{{Path: "link", Target: "dir/out.txt"}}, res.OutputSymlinks)
+	require.Len(t, res.OutputFiles, 1)
+	assert.Equal(t, "dir/out.txt", res.OutputFiles[0].Path)
+
+	// Воркер потерял артефакт, поэтому скачивает выходы зависимости из удалённого кеша.
+	require.NoError(t, env.WorkerCache[0].Remove(build.ID{'a'}))
+
+	dependent := graph
+	dependent.Jobs = append(dependent.Jobs, build.Job{
+		ID:   build.ID{'b'},
+		Name: "dependent",
+		Deps: []build.ID{{'a'}},
+		Cmds: []build.Cmd{
+			{Exec: []string{"sh", "-c", `cat "$0/link"; test -d "$0/dir/empty"`, fmt.Sprintf("{{index .Deps %q}}", build.ID{'a'})}},
+		},
+	})
+
+	recorder := NewRecorder()
+	require.NoError(t, newCacheClient(env).Build(env.Ctx, dependent, recorder))
+	assert.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+
+	runs, err := os.ReadFile(counter)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(runs))
+}
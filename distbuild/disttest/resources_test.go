@@ -0,0 +1,49 @@
+package disttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestResourcePacking(t *testing.T) {
+	env := newEnv(t, &Config{
+		WorkerCount: 1,
+		Capacity:    &build.Resources{MilliCPU: 2000},
+	})
+
+	dir := t.TempDir()
+
+	// Джобы ждут друг друга, поэтому сборка завершится, только если воркер выполняет их одновременно.
+	rendezvous := func(id byte, self, other string) build.Job {
+		return build.Job{
+			ID:        build.ID{id},
+			Name:      self,
+			Resources: build.Resources{MilliCPU: 1000},
+			Cmds: []build.Cmd{
+				{Exec: []string{"sh", "-c", `
+touch "$0/$1"
+i=0
+while [ ! -e "$0/$2" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+test -e "$0/$2"
+`, dir, self, other}},
+			},
+		}
+	}
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			rendezvous('a', "a", "b"),
+			rendezvous('b', "b", "a"),
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	for _, id := range []build.ID{{'a'}, {'b'}} {
+		require.Equal(t, &JobResult{Code: new(int)}, recorder.Jobs[id])
+	}
+}
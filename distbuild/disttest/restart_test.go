@@ -0,0 +1,86 @@
+package disttest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// restartingListener перезапускает координатора, как только джоб напечатает "waiting",
+// и после перезапуска создаёт файл-сигнал, которого ждёт джоб.
+type restartingListener struct {
+	*Recorder
+
+	t         *testing.T
+	env       *env
+	signal    string
+	restarted bool
+}
+
+func (l *restartingListener) OnJobStdout(jobID build.ID, stdout []byte) error {
+	if err := l.Recorder.OnJobStdout(jobID, stdout); err != nil {
+		return err
+	}
+
+	if !l.restarted && strings.Contains(l.job(jobID).Stdout, "waiting") {
+		l.restarted = true
+		l.env.RestartCoordinator(l.t)
+		return os.WriteFile(l.signal, nil, 0666)
+	}
+	return nil
+}
+
+func TestCoordinatorRestart(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, Journal: true})
+
+	signal := filepath.Join(t.TempDir(), "signal")
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "before restart",
+				Cmds: []build.Cmd{
+					{CatOutput: "{{.OutputDir}}/a.txt", CatTemplate: "a"},
+					{Exec: []string{"echo", "a"}},
+				},
+			},
+			{
+				ID:   build.ID{'b'},
+				Name: "across restart",
+				Deps: []build.ID{{'a'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+echo waiting
+i=0
+while [ ! -e "$0" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+[ -e "$0" ] || echo "signal timeout" >&2
+echo done
+`, signal}},
+				},
+			},
+			{
+				ID:   build.ID{'c'},
+				Name: "after restart",
+				Deps: []build.ID{{'b'}, {'a'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", "{{index .Deps \"" + build.ID{'a'}.String() + "\"}}/a.txt"}},
+				},
+			},
+		},
+	}
+
+	lsn := &restartingListener{Recorder: NewRecorder(), t: t, env: env, signal: signal}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+	require.True(t, lsn.restarted)
+
+	assert.Equal(t, &JobResult{Stdout: "a\n", Code: new(int)}, lsn.Jobs[build.ID{'a'}])
+	assert.Equal(t, &JobResult{Stdout: "waiting\ndone\n", Code: new(int)}, lsn.Jobs[build.ID{'b'}])
+	assert.Equal(t, &JobResult{Stdout: "a", Code: new(int)}, lsn.Jobs[build.ID{'c'}])
+}
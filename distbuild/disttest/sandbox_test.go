@@ -0,0 +1,77 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+)
+
+var sandboxConfig = &Config{WorkerCount: 1, Sandbox: &sandbox.Config{}}
+
+func TestSandboxArtifactTransfer(t *testing.T) {
+	env := newEnv(t, sandboxConfig)
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, artifactTransferGraph, recorder))
+
+	assert.Len(t, recorder.Jobs, 2)
+	assert.Equal(t, &JobResult{Stdout: "OK", Code: new(int)}, recorder.Jobs[build.ID{'b'}])
+}
+
+func TestSandboxIsolation(t *testing.T) {
+	env := newEnv(t, sandboxConfig)
+
+	secret := filepath.Join(env.RootDir, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0666))
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "cat",
+				Cmds: []build.Cmd{
+					{Exec: []string{"cat", secret}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	result := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, result)
+	require.NotNil(t, result.Code)
+	assert.NotEqual(t, 0, *result.Code)
+	assert.Empty(t, result.Stdout)
+}
+
+func TestSandboxWriteOutsideOutputDir(t *testing.T) {
+	env := newEnv(t, sandboxConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "write",
+				Cmds: []build.Cmd{
+					{CatTemplate: "OK", CatOutput: filepath.Join(env.RootDir, "out.txt")},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.Error(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	result := recorder.Jobs[build.ID{'a'}]
+	require.NotNil(t, result)
+	assert.Contains(t, result.Error, fmt.Sprintf("%s is outside of writable directories", filepath.Join(env.RootDir, "out.txt")))
+}
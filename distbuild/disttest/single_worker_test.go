@@ -48,7 +48,7 @@ func TestJobCaching(t *testing.T) {
 				ID:   build.ID{'a'},
 				Name: "echo",
 				Cmds: []build.Cmd{
-					{CatTemplate: "OK\n", CatOutput: tmpFile.Name()}, // No-hermetic, for testing purposes.
+					{Exec: []string{"bash", "-c", "echo OK > " + tmpFile.Name()}}, // No-hermetic, for testing purposes.
 					{Exec: []string{"echo", "OK"}},
 				},
 			},
@@ -72,10 +72,6 @@ func TestJobCaching(t *testing.T) {
 }
 
 var sourceFilesGraph = build.Graph{
-	SourceFiles: map[build.ID]string{
-		{'a'}: "a.txt",
-		{'c'}: "b/c.txt",
-	},
 	Jobs: []build.Job{
 		{
 			ID:   build.ID{'a'},
@@ -95,8 +91,11 @@ var sourceFilesGraph = build.Graph{
 func TestSourceFiles(t *testing.T) {
 	env := newEnv(t, singleWorkerConfig)
 
+	graph := sourceFilesGraph
+	graph.SourceFiles = env.sourceFiles(t, "a.txt", "b/c.txt")
+
 	recorder := NewRecorder()
-	require.NoError(t, env.Client.Build(env.Ctx, sourceFilesGraph, recorder))
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
 
 	assert.Len(t, recorder.Jobs, 1)
 	assert.Equal(t, &JobResult{Stdout: "foo", Stderr: "bar", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
@@ -0,0 +1,62 @@
+package disttest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestSpeculativeExecution(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, Speculative: true})
+
+	// Первая сборка задаёт типичное время джобов с именем straggler.
+	warmup := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'w'},
+				Name: "straggler",
+				Cmds: []build.Cmd{{Exec: []string{"echo", "warmup"}}},
+			},
+		},
+	}
+	require.NoError(t, env.Client.Build(env.Ctx, warmup, NewRecorder()))
+
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	// Первый запуск зависает. Копия на другом воркере видит маркер и сразу завершается,
+	// поэтому сборка завершится, только если координатор запустит копию.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'s'},
+				Name: "straggler",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+if [ -e "$0" ]; then echo done; exit 0; fi
+touch "$0"
+sleep 30
+`, marker}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	assert.Equal(t, &JobResult{Stdout: "done\n", Code: new(int)}, recorder.Jobs[build.ID{'s'}])
+
+	// Проигравший воркер убивает зависший джоб.
+	require.Eventually(t, func() bool {
+		for _, w := range fetchStatus(t, env.CoordinatorEndpoint).Workers {
+			if len(w.RunningJobs) != 0 {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,118 @@
+package disttest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/dist"
+)
+
+func fetchStatus(t *testing.T, endpoint string) *dist.Status {
+	rsp, err := http.Get(endpoint + "/status")
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	var status dist.Status
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&status))
+	return &status
+}
+
+// statusListener проверяет статус координатора, пока джоб выполняется, и отпускает джоб файлом-сигналом.
+type statusListener struct {
+	*Recorder
+
+	t       *testing.T
+	env     *env
+	signal  string
+	checked bool
+}
+
+func (l *statusListener) OnJobStderr(jobID build.ID, stderr []byte) error {
+	if err := l.Recorder.OnJobStderr(jobID, stderr); err != nil {
+		return err
+	}
+
+	if l.checked || !strings.Contains(l.job(jobID).Stderr, "progress") {
+		return nil
+	}
+	l.checked = true
+
+	var job dist.JobStatus
+	require.Eventually(l.t, func() bool {
+		status := fetchStatus(l.t, l.env.CoordinatorEndpoint)
+		if len(status.Builds) != 1 || len(status.Workers) != 1 {
+			return false
+		}
+
+		job = status.Builds[0].Jobs[0]
+		return job.State == dist.JobRunning
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.Equal(l.t, "progress\n", job.StderrTail)
+	assert.NotEmpty(l.t, job.Worker)
+
+	return os.WriteFile(l.signal, nil, 0666)
+}
+
+func TestStatus(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	signal := filepath.Join(t.TempDir(), "signal")
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "wait for signal",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+echo progress >&2
+i=0
+while [ ! -e "$0" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+echo done >&2
+`, signal}},
+				},
+			},
+		},
+	}
+
+	lsn := &statusListener{Recorder: NewRecorder(), t: t, env: env, signal: signal}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+	require.True(t, lsn.checked)
+
+	status := fetchStatus(t, env.CoordinatorEndpoint)
+	require.Len(t, status.Builds, 1)
+	require.NotNil(t, status.Builds[0].Finished)
+	assert.Empty(t, status.Builds[0].Error)
+	assert.Equal(t, "normal", status.Builds[0].Priority)
+
+	job := status.Builds[0].Jobs[0]
+	assert.Equal(t, dist.JobFinished, job.State)
+	assert.Equal(t, "progress\ndone\n", job.StderrTail)
+	require.Len(t, status.Workers, 1)
+	assert.True(t, status.Workers[0].Alive)
+	assert.Equal(t, status.Workers[0].ID, job.Worker)
+	assert.Empty(t, status.Queue)
+
+	rsp, err := http.Get(env.CoordinatorEndpoint + "/dashboard")
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "wait for signal")
+	assert.Contains(t, string(body), string(job.Worker))
+}
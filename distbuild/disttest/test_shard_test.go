@@ -0,0 +1,113 @@
+package disttest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// shardListener запоминает результаты шардов тестовых джобов.
+type shardListener struct {
+	*Recorder
+
+	Shards []*api.TestShard
+}
+
+func (l *shardListener) OnTestShard(jobID build.ID, shard *api.TestShard) error {
+	l.Shards = append(l.Shards, shard)
+	return nil
+}
+
+func TestTestShards(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2})
+
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	// Джоб имитирует тестовый бинарь: печатает отфильтрованные тесты, а TestB падает
+	// при первом запуске.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'t'},
+				Name: "test",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+case "$1" in
+*TestB*)
+	if [ ! -e "$0" ]; then
+		touch "$0"
+		echo "--- FAIL: TestB (0.00s)"
+		exit 1
+	fi
+	;;
+esac
+echo "$1"
+`, marker}},
+				},
+				Test: &build.Test{
+					Names:   []string{"TestA", "TestB", "TestC", "TestD"},
+					Shards:  2,
+					Retries: 1,
+				},
+			},
+		},
+	}
+
+	lsn := &shardListener{Recorder: NewRecorder()}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+
+	require.Len(t, lsn.Shards, 2)
+
+	shards := map[int]*api.TestShard{}
+	for _, shard := range lsn.Shards {
+		assert.Equal(t, build.ID{'t'}, shard.JobID)
+		assert.Equal(t, 2, shard.Shards)
+		shards[shard.Shard] = shard
+	}
+
+	assert.Equal(t, []string{"TestA", "TestC"}, shards[0].Tests)
+	assert.Equal(t, 1, shards[0].Attempts)
+	assert.Empty(t, shards[0].Flaky)
+
+	assert.Equal(t, []string{"TestB", "TestD"}, shards[1].Tests)
+	assert.Equal(t, 2, shards[1].Attempts)
+	assert.Equal(t, []string{"TestB"}, shards[1].Flaky)
+
+	assert.Equal(t, &JobResult{
+		Stdout: "-test.run=^(?:TestA|TestC)$\n-test.run=^(?:TestB|TestD)$\n",
+		Code:   new(int),
+	}, lsn.Jobs[build.ID{'t'}])
+}
+
+func TestTestRetriesExhausted(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'t'},
+				Name: "test",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `echo "--- FAIL: TestA (0.00s)"; exit 1`}},
+				},
+				Test: &build.Test{Names: []string{"TestA"}, Retries: 2},
+			},
+		},
+	}
+
+	lsn := &shardListener{Recorder: NewRecorder()}
+	require.Error(t, env.Client.Build(env.Ctx, graph, lsn))
+
+	require.Len(t, lsn.Shards, 1)
+	assert.Equal(t, 3, lsn.Shards[0].Attempts)
+	assert.Equal(t, []string{"TestA"}, lsn.Shards[0].Failed)
+	assert.Empty(t, lsn.Shards[0].Flaky)
+
+	require.NotNil(t, lsn.Jobs[build.ID{'t'}].Code)
+	assert.Equal(t, 1, *lsn.Jobs[build.ID{'t'}].Code)
+}
@@ -0,0 +1,152 @@
+package disttest
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/client"
+)
+
+var tlsConfig = &Config{WorkerCount: 2, TLS: true}
+
+func TestTLS(t *testing.T) {
+	env := newEnv(t, tlsConfig)
+
+	// Клиент заливает файл с токеном, а джобы b0 и b1 выполняются дольше DepsTimeout, поэтому
+	// один из них скачивает артефакт a с другого воркера по mTLS.
+	graph := build.Graph{
+		SourceFiles: env.sourceFiles(t, "a.txt"),
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "copy",
+				Inputs: []string{"a.txt"},
+				Cmds: []build.Cmd{
+					{Exec: []string{"cp", "{{.SourceDir}}/a.txt", "{{.OutputDir}}/out.txt"}},
+				},
+			},
+		},
+	}
+	for i := range 2 {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:   build.ID{'b', byte(i)},
+			Name: "cat",
+			Deps: []build.ID{{'a'}},
+			Cmds: []build.Cmd{
+				{Exec: []string{"cat", fmt.Sprintf("{{index .Deps %q}}/out.txt", build.ID{'a'})}},
+				{Exec: []string{"sleep", "0.5"}, Environ: os.Environ()},
+			},
+		})
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	for i := range 2 {
+		assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'b', byte(i)}])
+	}
+}
+
+func TestTLSRejectsClientWithoutToken(t *testing.T) {
+	env := newEnv(t, tlsConfig)
+
+	c := client.NewClient(env.Logger.Named("client"), env.CoordinatorEndpoint, env.SourceDir,
+		client.WithHTTPClient(env.NewHTTPClient(t, "", "")))
+
+	err := c.Build(env.Ctx, echoGraph, NewRecorder())
+	require.ErrorContains(t, err, "unauthorized")
+
+	c = client.NewClient(env.Logger.Named("client"), env.CoordinatorEndpoint, env.SourceDir,
+		client.WithHTTPClient(env.NewHTTPClient(t, "", "wrong-token")))
+
+	err = c.Build(env.Ctx, echoGraph, NewRecorder())
+	require.ErrorContains(t, err, "unauthorized")
+}
+
+func TestTLSRejectsUnknownWorker(t *testing.T) {
+	env := newEnv(t, tlsConfig)
+
+	workerID := api.WorkerID(strings.TrimSuffix(env.CoordinatorEndpoint, "/coordinator") + "/worker/0")
+	req := &api.HeartbeatRequest{WorkerID: workerID}
+
+	// Сертификат от того же CA, но выданный на другой URI.
+	rogue := api.NewHeartbeatClient(env.Logger.Named("rogue"), env.CoordinatorEndpoint,
+		api.WithHTTPClient(env.NewHTTPClient(t, "rogue", "", "https://rogue.example")))
+
+	_, err := rogue.Heartbeat(env.Ctx, req)
+	require.ErrorContains(t, err, "certificate is not issued to worker")
+
+	// Токен клиента не даёт права присылать heartbeat-ы.
+	withToken := api.NewHeartbeatClient(env.Logger.Named("rogue"), env.CoordinatorEndpoint,
+		api.WithHTTPClient(env.NewHTTPClient(t, "", testToken)))
+
+	_, err = withToken.Heartbeat(env.Ctx, req)
+	require.ErrorContains(t, err, "worker certificate required")
+
+	// Артефакты воркера отдаются только по сертификату.
+	rsp, err := env.NewHTTPClient(t, "", testToken).Get(string(workerID) + "/artifact?id=" + build.ID{'a'}.String())
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, rsp.StatusCode)
+}
+
+func TestTLSRejectsForeignJobOutput(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 1, TLS: true})
+
+	started := filepath.Join(t.TempDir(), "started")
+	signal := filepath.Join(t.TempDir(), "signal")
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "wait",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+touch "$0"
+i=0
+while [ ! -e "$1" ] && [ $i -lt 500 ]; do sleep 0.01; i=$((i+1)); done
+echo OK
+`, started, signal}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	done := make(chan error, 1)
+	go func() { done <- env.Client.Build(env.Ctx, graph, recorder) }()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(started)
+		return err == nil
+	}, 10*time.Second, 10*time.Millisecond)
+
+	output := &api.JobOutput{ID: build.ID{'a'}, Stdout: []byte("injected\n")}
+
+	// Сертификат от того же CA, но выданный не на воркер, который выполняет джоб.
+	workerID := strings.TrimSuffix(env.CoordinatorEndpoint, "/coordinator") + "/worker/0"
+	rogue := api.NewOutputClient(env.Logger.Named("rogue"), env.CoordinatorEndpoint,
+		api.WithHTTPClient(env.NewHTTPClient(t, "rogue", "", "https://rogue.example")))
+	require.ErrorContains(t, rogue.JobOutput(env.Ctx, output), "is not running on this worker")
+
+	// Сертификат воркера подходит только для джобов, которые он выполняет.
+	worker := api.NewOutputClient(env.Logger.Named("worker"), env.CoordinatorEndpoint,
+		api.WithHTTPClient(env.NewHTTPClient(t, "worker", "", workerID)))
+	require.ErrorContains(t, worker.JobOutput(env.Ctx, &api.JobOutput{ID: build.ID{'b'}}), "is not running on this worker")
+	require.NoError(t, worker.JobOutput(env.Ctx, &api.JobOutput{ID: build.ID{'a'}}))
+
+	require.NoError(t, os.WriteFile(signal, nil, 0666))
+	require.NoError(t, <-done)
+	assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
@@ -0,0 +1,62 @@
+package disttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// traceRecorder запоминает трейс сборки.
+type traceRecorder struct {
+	*Recorder
+	trace *trace.Trace
+}
+
+func (r *traceRecorder) OnBuildTrace(t *trace.Trace) error {
+	r.trace = t
+	return nil
+}
+
+func TestBuildTrace(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	a, b, c := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{ID: a, Name: "a", Cmds: []build.Cmd{{Exec: []string{"sleep", "0.1"}}}},
+			{ID: b, Name: "b", Deps: []build.ID{a}, Cmds: []build.Cmd{{Exec: []string{"true"}}}},
+			{ID: c, Name: "c", Deps: []build.ID{a}, Cmds: []build.Cmd{{Exec: []string{"sleep", "0.2"}}}},
+		},
+	}
+
+	lsn := &traceRecorder{Recorder: NewRecorder()}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+	require.NotNil(t, lsn.trace)
+	require.Len(t, lsn.trace.Jobs, 3)
+
+	for _, job := range lsn.trace.Jobs {
+		require.Contains(t, job.Worker, "/worker/0")
+		require.False(t, job.Picked.Before(job.Queued), job.Name)
+		require.False(t, job.Finished.Before(job.Picked), job.Name)
+		require.NotNil(t, job.Timings, job.Name)
+		require.False(t, job.Timings.CacheHit, job.Name)
+		require.False(t, job.Timings.Exec.Start.IsZero(), job.Name)
+	}
+
+	var path []string
+	for _, job := range lsn.trace.CriticalPath() {
+		path = append(path, job.Name)
+	}
+	require.Equal(t, []string{"a", "c"}, path)
+
+	// Повторная сборка берёт результаты из кеша воркера.
+	lsn = &traceRecorder{Recorder: NewRecorder()}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+	require.NotNil(t, lsn.trace)
+	for _, job := range lsn.trace.Jobs {
+		require.True(t, job.Timings.CacheHit, job.Name)
+	}
+}
@@ -0,0 +1,66 @@
+package disttest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestCompressedTransfer(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, CompressedTransfer: true})
+
+	// Джоб a пишет исполняемый скрипт и символическую ссылку на него. Джобы b0 и b1 выполняются
+	// дольше DepsTimeout, поэтому один из них запускает скрипт на втором воркере.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "write",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", strings.Join([]string{
+						`printf '#!/bin/sh\necho OK\n' > "$0/run.sh"`,
+						`chmod +x "$0/run.sh"`,
+						`ln -s run.sh "$0/link"`,
+					}, " && "), "{{.OutputDir}}"}},
+				},
+			},
+		},
+	}
+	for i := range 2 {
+		graph.Jobs = append(graph.Jobs, build.Job{
+			ID:   build.ID{'b', byte(i)},
+			Name: "run",
+			Deps: []build.ID{{'a'}},
+			Cmds: []build.Cmd{
+				{Exec: []string{fmt.Sprintf("{{index .Deps %q}}/link", build.ID{'a'})}},
+				{Exec: []string{"sleep", "0.5"}, Environ: os.Environ()},
+			},
+		})
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+
+	for i := range 2 {
+		assert.Equal(t, &JobResult{Stdout: "OK\n", Code: new(int)}, recorder.Jobs[build.ID{'b', byte(i)}])
+	}
+
+	workerEndpoint := strings.TrimSuffix(env.CoordinatorEndpoint, "/coordinator") + "/worker/"
+
+	var received float64
+	for i := range 2 {
+		metrics := scrape(t, workerEndpoint+strconv.Itoa(i))
+		received += metricValue(t, metrics, `distbuild_worker_artifact_transfer_bytes_total{direction="received"}`)
+	}
+
+	// Несжатый tar занимает хотя бы несколько блоков по 512 байт.
+	require.NotZero(t, received)
+	require.Less(t, received, 1024.0)
+}
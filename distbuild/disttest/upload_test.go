@@ -0,0 +1,57 @@
+package disttest
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestBuildAfterInterruptedUpload(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	files := env.sourceFiles(t, "a.txt")
+
+	var id build.ID
+	for id = range files {
+	}
+
+	content, err := os.ReadFile(filepath.Join(env.SourceDir, "a.txt"))
+	require.NoError(t, err)
+
+	// Прошлый клиент успел залить начало файла и пропал. Файл остаётся под локом на запись,
+	// пока загрузка не закончится или не устареет.
+	query := url.Values{"id": {id.String()}, "path": {"a.txt"}, "offset": {"0"}}
+	req, err := http.NewRequestWithContext(env.Ctx, http.MethodPatch,
+		env.CoordinatorEndpoint+"/file/upload?"+query.Encode(), strings.NewReader(string(content[:5])))
+	require.NoError(t, err)
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	require.Equal(t, "5", rsp.Header.Get("Upload-Offset"))
+
+	graph := build.Graph{
+		SourceFiles: files,
+		Jobs: []build.Job{
+			{
+				ID:     build.ID{'a'},
+				Name:   "cat",
+				Inputs: []string{"a.txt"},
+				Cmds:   []build.Cmd{{Exec: []string{"cat", "{{.SourceDir}}/a.txt"}}},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	require.NoError(t, env.Client.Build(env.Ctx, graph, recorder))
+	assert.Equal(t, &JobResult{Stdout: string(content), Code: new(int)}, recorder.Jobs[build.ID{'a'}])
+}
@@ -0,0 +1,33 @@
+package disttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func TestInvalidGraph(t *testing.T) {
+	env := newEnv(t, singleWorkerConfig)
+
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "echo",
+				Deps: []build.ID{{'b'}},
+				Cmds: []build.Cmd{
+					{Exec: []string{"echo", "OK"}},
+				},
+			},
+		},
+	}
+
+	recorder := NewRecorder()
+	err := env.Client.Build(env.Ctx, graph, recorder)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `job "echo": unknown dependency 6200000000000000000000000000000000000000`)
+	assert.Empty(t, recorder.Jobs)
+}
@@ -0,0 +1,71 @@
+package disttest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// stoppingListener останавливает воркера, на котором запустился джоб.
+type stoppingListener struct {
+	*Recorder
+
+	env     *env
+	stopped bool
+}
+
+func (l *stoppingListener) OnJobStdout(jobID build.ID, stdout []byte) error {
+	if err := l.Recorder.OnJobStdout(jobID, stdout); err != nil {
+		return err
+	}
+
+	for i := range l.env.Workers {
+		if !l.stopped && strings.Contains(string(stdout), fmt.Sprintf("/worker%d/", i)) {
+			l.stopped = true
+			l.env.StopWorker(i)
+		}
+	}
+	return nil
+}
+
+func TestWorkerLost(t *testing.T) {
+	env := newEnv(t, &Config{WorkerCount: 2, WorkerTimeout: 2 * time.Second})
+
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	// Первый запуск печатает свою выходную директорию, по которой тест находит воркера,
+	// и зависает. Повторный запуск видит маркер и сразу завершается.
+	graph := build.Graph{
+		Jobs: []build.Job{
+			{
+				ID:   build.ID{'a'},
+				Name: "hang once",
+				Cmds: []build.Cmd{
+					{Exec: []string{"sh", "-c", `
+if [ -e "$1" ]; then echo retried; exit 0; fi
+touch "$1"
+echo "$0"
+sleep 30
+`, "{{.OutputDir}}", marker}},
+				},
+			},
+		},
+	}
+
+	lsn := &stoppingListener{Recorder: NewRecorder(), env: env}
+	require.NoError(t, env.Client.Build(env.Ctx, graph, lsn))
+
+	require.True(t, lsn.stopped)
+
+	res := lsn.Jobs[build.ID{'a'}]
+	require.NotNil(t, res)
+	assert.Equal(t, new(int), res.Code)
+	assert.True(t, strings.HasSuffix(res.Stdout, "\nretried\n"), "stdout: %q", res.Stdout)
+}
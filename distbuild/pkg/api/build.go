@@ -2,12 +2,71 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
 )
 
+// Priority задаёт долю ресурсов кластера, которую получает сборка, когда конкурирует с другими.
+type Priority int
+
+const (
+	// PriorityNormal используется, если приоритет не указан.
+	PriorityNormal Priority = iota
+	// PriorityBatch подходит для CI и других сборок, результата которых никто не ждёт.
+	PriorityBatch
+	// PriorityInteractive подходит для сборок, которые запустил человек.
+	PriorityInteractive
+)
+
+// Weight возвращает вес сборки для справедливого распределения джобов между сборками.
+func (p Priority) Weight() int {
+	switch p {
+	case PriorityBatch:
+		return 1
+	case PriorityInteractive:
+		return 4
+	default:
+		return 2
+	}
+}
+
+var priorityNames = map[Priority]string{
+	PriorityNormal:      "normal",
+	PriorityBatch:       "batch",
+	PriorityInteractive: "interactive",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// ParsePriority разбирает имя приоритета: normal, batch или interactive.
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q", name)
+}
+
 type BuildRequest struct {
 	Graph build.Graph
+
+	// Priority влияет на то, как быстро выполняются джобы сборки, пока кластер занят другими сборками.
+	Priority Priority
+
+	// Trace просит координатора прислать трейс сборки перед её завершением.
+	Trace bool `json:",omitempty"`
+
+	// Cached перечисляет джобы графа, результаты которых клиент взял из удалённого кеша. Координатор
+	// их не запускает, а воркеры скачивают их выходы из удалённого кеша координатора.
+	Cached []build.ID `json:",omitempty"`
 }
 
 type BuildStarted struct {
@@ -16,11 +75,55 @@ type BuildStarted struct {
 }
 
 type StatusUpdate struct {
-	JobFinished   *JobResult
+	// JobOutput передаёт кусок вывода джоба, который ещё не завершился.
+	JobOutput *JobOutput
+
+	JobFinished *JobResult
+
+	// TestShard приходит для каждого шарда тестового джоба перед его JobFinished.
+	TestShard *TestShard `json:",omitempty"`
+
+	// JobsAdded приходит перед JobFinished джоба, который добавил в сборку джобы из фрагмента графа.
+	JobsAdded *JobsAdded `json:",omitempty"`
+
+	// Trace приходит перед BuildFinished или BuildFailed, если его запросили в BuildRequest.
+	Trace *trace.Trace
+
 	BuildFailed   *BuildFailed
 	BuildFinished *BuildFinished
 }
 
+// JobsAdded описывает джобы, которые джоб Generator добавил в сборку (см. build.Job.Fragment).
+// Jobs уже содержат ID и зависимость от Generator.
+type JobsAdded struct {
+	Generator build.ID
+	Jobs      []build.Job
+}
+
+// TestShard описывает результат одного шарда тестового джоба (см. build.Test).
+type TestShard struct {
+	// JobID - тестовый джоб из графа сборки. Shard - номер шарда от 0 до Shards-1.
+	JobID  build.ID
+	Shard  int
+	Shards int
+
+	// Tests перечисляет тесты шарда. Пустой Tests означает все тесты джоба.
+	Tests []string `json:",omitempty"`
+
+	// Attempts - сколько раз запускался шард.
+	Attempts int
+
+	// Failed перечисляет тесты, которые упали в последнем запуске.
+	Failed []string `json:",omitempty"`
+
+	// Flaky перечисляет тесты, которые упали, но прошли, когда шард перезапустили.
+	Flaky []string `json:",omitempty"`
+
+	// Result - результат последнего запуска. Result.ID - ID шарда, он совпадает с JobID, если джоб
+	// не делится на шарды.
+	Result JobResult
+}
+
 type BuildFailed struct {
 	Error string
 }
@@ -30,8 +133,24 @@ type BuildFinished struct {
 
 type UploadDone struct{}
 
+// Cancel просит координатора отменить сборку.
+//
+// Координатор убирает ещё не запущенные джобы сборки из очереди, останавливает уже запущенные
+// и завершает сборку с BuildFailed. Разрыв соединения со статусом сборки работает так же, как Cancel.
+type Cancel struct{}
+
+// AttachRequest просит координатора снова присылать статус сборки BuildID, например после
+// перезапуска координатора.
+type AttachRequest struct {
+	BuildID build.ID
+
+	// Received - сколько обновлений с JobFinished и JobsAdded клиент уже получил. Координатор не присылает их повторно.
+	Received int
+}
+
 type SignalRequest struct {
 	UploadDone *UploadDone
+	Cancel     *Cancel
 }
 
 type SignalResponse struct {
@@ -45,6 +164,10 @@ type StatusWriter interface {
 type Service interface {
 	StartBuild(ctx context.Context, request *BuildRequest, w StatusWriter) error
 	SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error)
+
+	// AttachBuild присылает в w статус уже запущенной сборки, к которой не подключён другой клиент.
+	// Первым в w приходит BuildStarted без MissingFiles. Разрыв соединения отменяет сборку, как и в StartBuild.
+	AttachBuild(ctx context.Context, request *AttachRequest, w StatusWriter) error
 }
 
 type StatusReader interface {
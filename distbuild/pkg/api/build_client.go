@@ -3,7 +3,12 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 
 	"go.uber.org/zap"
 
@@ -11,16 +16,117 @@ import (
 )
 
 type BuildClient struct {
+	l        *zap.Logger
+	endpoint string
+	http     *http.Client
 }
 
-func NewBuildClient(l *zap.Logger, endpoint string) *BuildClient {
-	panic("implement me")
+func NewBuildClient(l *zap.Logger, endpoint string, opts ...ClientOption) *BuildClient {
+	return &BuildClient{l: l, endpoint: endpoint, http: newClientConfig(opts).http}
+}
+
+type statusReader struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+func (r *statusReader) Close() error {
+	return r.body.Close()
+}
+
+func (r *statusReader) Next() (*StatusUpdate, error) {
+	var u StatusUpdate
+	if err := r.dec.Decode(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
 }
 
 func (c *BuildClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
-	panic("implement me")
+	c.l.Debug("starting build", zap.Int("num_jobs", len(request.Graph.Jobs)))
+
+	started, r, err := c.stream(ctx, "/build", request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.l.Debug("build started", zap.String("build_id", started.ID.String()))
+	return started, r, nil
+}
+
+// AttachBuild снова подключается к статусу сборки request.BuildID.
+func (c *BuildClient) AttachBuild(ctx context.Context, request *AttachRequest) (StatusReader, error) {
+	c.l.Debug("attaching to build",
+		zap.String("build_id", request.BuildID.String()),
+		zap.Int("received", request.Received))
+
+	_, r, err := c.stream(ctx, "/attach", request)
+	return r, err
+}
+
+// stream отправляет request на path и читает из ответа BuildStarted.
+func (c *BuildClient) stream(ctx context.Context, path string, request any) (*BuildStarted, StatusReader, error) {
+	reqJS, err := json.Marshal(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, bytes.NewBuffer(reqJS))
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRsp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if httpRsp.StatusCode != http.StatusOK {
+		defer func() { _ = httpRsp.Body.Close() }()
+
+		errorMsg, _ := io.ReadAll(httpRsp.Body)
+		return nil, nil, fmt.Errorf("build failed: %s", errorMsg)
+	}
+
+	dec := json.NewDecoder(httpRsp.Body)
+
+	var started BuildStarted
+	if err := dec.Decode(&started); err != nil {
+		_ = httpRsp.Body.Close()
+		return nil, nil, err
+	}
+
+	return &started, &statusReader{body: httpRsp.Body, dec: dec}, nil
 }
 
 func (c *BuildClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
-	panic("implement me")
+	reqJS, err := json.Marshal(signal)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/signal?build_id="+buildID.String(), bytes.NewBuffer(reqJS))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRsp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpRsp.Body.Close() }()
+
+	if httpRsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(httpRsp.Body)
+		return nil, fmt.Errorf("signal failed: %s", errorMsg)
+	}
+
+	var rsp SignalResponse
+	if err := json.NewDecoder(httpRsp.Body).Decode(&rsp); err != nil {
+		return nil, err
+	}
+
+	return &rsp, nil
 }
@@ -3,18 +3,156 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 
 	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
 func NewBuildService(l *zap.Logger, s Service) *BuildHandler {
-	panic("implement me")
+	return &BuildHandler{l: l, s: s}
 }
 
 type BuildHandler struct {
+	l *zap.Logger
+	s Service
 }
 
 func (h *BuildHandler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/build", h.build)
+	mux.HandleFunc("/signal", h.signal)
+	mux.HandleFunc("/attach", h.attach)
+}
+
+type statusWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	enc *json.Encoder
+
+	started bool
+}
+
+func (w *statusWriter) write(v any) error {
+	if err := w.enc.Encode(v); err != nil {
+		return err
+	}
+	return w.rc.Flush()
+}
+
+func (w *statusWriter) Started(rsp *BuildStarted) error {
+	if w.started {
+		return fmt.Errorf("build is already started")
+	}
+
+	w.started = true
+	w.w.Header().Set("Content-Type", "application/json")
+	w.w.WriteHeader(http.StatusOK)
+	return w.write(rsp)
+}
+
+func (w *statusWriter) Updated(update *StatusUpdate) error {
+	if !w.started {
+		return fmt.Errorf("build is not started")
+	}
+
+	return w.write(update)
+}
+
+func (h *BuildHandler) build(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.l.Warn("invalid build request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.stream(w, r, func(sw *statusWriter) error {
+		return h.s.StartBuild(r.Context(), &req, sw)
+	})
+}
+
+func (h *BuildHandler) attach(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AttachRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.l.Warn("invalid attach request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.stream(w, r, func(sw *statusWriter) error {
+		return h.s.AttachBuild(r.Context(), &req, sw)
+	})
+}
+
+// stream отдаёт клиенту статус сборки, который run пишет в statusWriter.
+func (h *BuildHandler) stream(w http.ResponseWriter, r *http.Request, run func(sw *statusWriter) error) {
+	// Сервер замечает разрыв соединения и отменяет r.Context(), только когда тело запроса
+	// прочитано до конца. Разрыв соединения означает отмену сборки.
+	_, _ = io.Copy(io.Discard, r.Body)
+
+	sw := &statusWriter{
+		w:   w,
+		rc:  http.NewResponseController(w),
+		enc: json.NewEncoder(w),
+	}
+
+	err := run(sw)
+	if err == nil {
+		return
+	}
+
+	h.l.Warn("build failed", zap.Error(err))
+	if !sw.started {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := sw.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: err.Error()}}); err != nil {
+		h.l.Warn("failed to send build error", zap.Error(err))
+	}
+}
+
+func (h *BuildHandler) signal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buildID build.ID
+	if err := buildID.UnmarshalText([]byte(r.URL.Query().Get("build_id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req SignalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rsp, err := h.s.SignalBuild(r.Context(), buildID, &req)
+	if err != nil {
+		h.l.Warn("signal failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		h.l.Warn("failed to write signal response", zap.Error(err))
+	}
 }
@@ -159,3 +159,37 @@ func TestBuildResultsStreaming(t *testing.T) {
 	defer func() { _ = r.Close() }()
 	require.Equal(t, started, rsp)
 }
+
+func TestBuildAttach(t *testing.T) {
+	env, stop := newEnv(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	req := &api.AttachRequest{BuildID: build.ID{02}, Received: 3}
+	finished := &api.StatusUpdate{BuildFinished: &api.BuildFinished{}}
+
+	env.mock.EXPECT().AttachBuild(gomock.Any(), req, gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *api.AttachRequest, w api.StatusWriter) error {
+			if err := w.Started(&api.BuildStarted{ID: req.BuildID}); err != nil {
+				return err
+			}
+			return w.Updated(finished)
+		})
+	env.mock.EXPECT().AttachBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("unknown build"))
+
+	r, err := env.client.AttachBuild(ctx, req)
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, finished, u)
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+
+	_, err = env.client.AttachBuild(ctx, &api.AttachRequest{BuildID: build.ID{03}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown build")
+}
@@ -0,0 +1,28 @@
+//go:build !solution
+
+package api
+
+import "net/http"
+
+// ClientOption задаёт необязательный параметр клиентов api.
+type ClientOption func(c *clientConfig)
+
+type clientConfig struct {
+	http *http.Client
+}
+
+// WithHTTPClient задаёт http.Client, через который клиент отправляет запросы, например
+// с TLS и токеном из auth.NewHTTPClient. По умолчанию используется http.DefaultClient.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(config *clientConfig) {
+		config.http = c
+	}
+}
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	config := clientConfig{http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
@@ -0,0 +1,28 @@
+//go:build !solution
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+)
+
+// errorStatus возвращает код ответа на ошибку сервиса.
+func errorStatus(err error) int {
+	if errors.Is(err, auth.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode возвращает gRPC код ответа на ошибку сервиса.
+func errorCode(err error) codes.Code {
+	if errors.Is(err, auth.ErrForbidden) {
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
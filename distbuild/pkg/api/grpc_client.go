@@ -0,0 +1,144 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// BuildGRPCClient - клиент Service поверх gRPC с теми же методами, что и у BuildClient.
+type BuildGRPCClient struct {
+	l *zap.Logger
+	c apipb.BuildClient
+}
+
+func NewBuildGRPCClient(l *zap.Logger, cc grpc.ClientConnInterface) *BuildGRPCClient {
+	return &BuildGRPCClient{l: l, c: apipb.NewBuildClient(cc)}
+}
+
+type buildEventReceiver interface {
+	Recv() (*apipb.BuildEvent, error)
+}
+
+type grpcStatusReader struct {
+	stream buildEventReceiver
+	cancel context.CancelFunc
+}
+
+// Close отменяет вызов, координатор видит это как разрыв соединения.
+func (r *grpcStatusReader) Close() error {
+	r.cancel()
+	return nil
+}
+
+func (r *grpcStatusReader) Next() (*StatusUpdate, error) {
+	event, err := r.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	if event.GetUpdate() == nil {
+		return nil, fmt.Errorf("unexpected build event %v", event)
+	}
+
+	var d pbDecoder
+	u := d.statusUpdate(event.GetUpdate())
+	return u, d.err
+}
+
+func (c *BuildGRPCClient) StartBuild(ctx context.Context, request *BuildRequest) (*BuildStarted, StatusReader, error) {
+	c.l.Debug("starting build", zap.Int("num_jobs", len(request.Graph.Jobs)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.c.StartBuild(ctx, buildRequestToPB(request))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	started, r, err := c.started(stream, cancel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.l.Debug("build started", zap.String("build_id", started.ID.String()))
+	return started, r, nil
+}
+
+// AttachBuild снова подключается к статусу сборки request.BuildID.
+func (c *BuildGRPCClient) AttachBuild(ctx context.Context, request *AttachRequest) (StatusReader, error) {
+	c.l.Debug("attaching to build",
+		zap.String("build_id", request.BuildID.String()),
+		zap.Int("received", request.Received))
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.c.AttachBuild(ctx, &apipb.AttachRequest{
+		BuildId:  request.BuildID[:],
+		Received: int32(request.Received),
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	_, r, err := c.started(stream, cancel)
+	return r, err
+}
+
+// started читает из потока BuildStarted. cancel отменяет вызов, если прочитать не удалось.
+func (c *BuildGRPCClient) started(stream buildEventReceiver, cancel context.CancelFunc) (*BuildStarted, StatusReader, error) {
+	event, err := stream.Recv()
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	if event.GetStarted() == nil {
+		cancel()
+		return nil, nil, fmt.Errorf("unexpected build event %v", event)
+	}
+
+	var d pbDecoder
+	started := d.buildStarted(event.GetStarted())
+	if d.err != nil {
+		cancel()
+		return nil, nil, d.err
+	}
+
+	return started, &grpcStatusReader{stream: stream, cancel: cancel}, nil
+}
+
+func (c *BuildGRPCClient) SignalBuild(ctx context.Context, buildID build.ID, signal *SignalRequest) (*SignalResponse, error) {
+	if _, err := c.c.SignalBuild(ctx, signalRequestToPB(buildID, signal)); err != nil {
+		return nil, fmt.Errorf("signal failed: %w", err)
+	}
+	return &SignalResponse{}, nil
+}
+
+// HeartbeatGRPCClient - клиент HeartbeatService поверх gRPC.
+type HeartbeatGRPCClient struct {
+	l *zap.Logger
+	c apipb.HeartbeatClient
+}
+
+func NewHeartbeatGRPCClient(l *zap.Logger, cc grpc.ClientConnInterface) *HeartbeatGRPCClient {
+	return &HeartbeatGRPCClient{l: l, c: apipb.NewHeartbeatClient(cc)}
+}
+
+func (c *HeartbeatGRPCClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
+	pb, err := c.c.Heartbeat(ctx, heartbeatRequestToPB(req))
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat failed: %w", err)
+	}
+
+	var d pbDecoder
+	rsp := d.heartbeatResponse(pb)
+	return rsp, d.err
+}
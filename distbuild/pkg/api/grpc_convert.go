@@ -0,0 +1,531 @@
+//go:build !solution
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// Функции *ToPB переводят типы api в сообщения apipb. Обратное преобразование делает pbDecoder.
+
+func idsToPB(ids []build.ID) [][]byte {
+	if ids == nil {
+		return nil
+	}
+
+	pb := make([][]byte, len(ids))
+	for i := range ids {
+		pb[i] = ids[i][:]
+	}
+	return pb
+}
+
+func timeToPB(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func sourceFilesToPB(files map[build.ID]string) []*apipb.SourceFile {
+	pb := make([]*apipb.SourceFile, 0, len(files))
+	for id, path := range files {
+		pb = append(pb, &apipb.SourceFile{Id: id[:], Path: path})
+	}
+	return pb
+}
+
+func resourcesToPB(r build.Resources) *apipb.Resources {
+	return &apipb.Resources{MilliCpu: r.MilliCPU, Memory: r.Memory}
+}
+
+func jobToPB(job *build.Job) *apipb.Job {
+	pb := &apipb.Job{
+		Id:        job.ID[:],
+		Name:      job.Name,
+		Inputs:    job.Inputs,
+		Deps:      idsToPB(job.Deps),
+		Resources: resourcesToPB(job.Resources),
+		Fragment:  job.Fragment,
+	}
+
+	for _, cmd := range job.Cmds {
+		pb.Cmds = append(pb.Cmds, &apipb.Cmd{
+			Exec:             cmd.Exec,
+			Environ:          cmd.Environ,
+			WorkingDirectory: cmd.WorkingDirectory,
+			CatTemplate:      cmd.CatTemplate,
+			CatOutput:        cmd.CatOutput,
+			CopyFrom:         cmd.CopyFrom,
+			CopyTo:           cmd.CopyTo,
+			SymlinkTarget:    cmd.SymlinkTarget,
+			SymlinkPath:      cmd.SymlinkPath,
+			MkdirPath:        cmd.MkdirPath,
+			WriteFrom:        cmd.WriteFrom,
+			WriteOutput:      cmd.WriteOutput,
+		})
+	}
+
+	if job.Test != nil {
+		pb.Test = &apipb.Test{
+			Names:   job.Test.Names,
+			Shards:  int32(job.Test.Shards),
+			Retries: int32(job.Test.Retries),
+		}
+	}
+	return pb
+}
+
+func buildRequestToPB(req *BuildRequest) *apipb.BuildRequest {
+	pb := &apipb.BuildRequest{
+		Graph:    &apipb.Graph{SourceFiles: sourceFilesToPB(req.Graph.SourceFiles)},
+		Priority: apipb.Priority(req.Priority),
+		Trace:    req.Trace,
+		Cached:   idsToPB(req.Cached),
+	}
+	for i := range req.Graph.Jobs {
+		pb.Graph.Jobs = append(pb.Graph.Jobs, jobToPB(&req.Graph.Jobs[i]))
+	}
+	return pb
+}
+
+func spanToPB(s trace.Span) *apipb.Span {
+	return &apipb.Span{Start: timeToPB(s.Start), End: timeToPB(s.End)}
+}
+
+func timingsToPB(t *trace.Timings) *apipb.Timings {
+	if t == nil {
+		return nil
+	}
+
+	return &apipb.Timings{
+		CacheHit: t.CacheHit,
+		Download: spanToPB(t.Download),
+		Exec:     spanToPB(t.Exec),
+		Upload:   spanToPB(t.Upload),
+	}
+}
+
+func jobResultToPB(res *JobResult) *apipb.JobResult {
+	if res == nil {
+		return nil
+	}
+
+	pb := &apipb.JobResult{
+		Id:         res.ID[:],
+		Stdout:     res.Stdout,
+		Stderr:     res.Stderr,
+		ExitCode:   int32(res.ExitCode),
+		Error:      res.Error,
+		WorkerLost: res.WorkerLost,
+		FlakyTests: res.FlakyTests,
+		Timings:    timingsToPB(res.Timings),
+	}
+	for _, v := range res.Violations {
+		pb.Violations = append(pb.Violations, &apipb.Violation{Kind: string(v.Kind), Path: v.Path})
+	}
+	return pb
+}
+
+func traceToPB(t *trace.Trace) *apipb.Trace {
+	if t == nil {
+		return nil
+	}
+
+	pb := &apipb.Trace{
+		BuildId: t.BuildID[:],
+		Start:   timeToPB(t.Start),
+		End:     timeToPB(t.End),
+	}
+	for _, job := range t.Jobs {
+		pb.Jobs = append(pb.Jobs, &apipb.TraceJob{
+			Id:       job.ID[:],
+			Name:     job.Name,
+			Deps:     idsToPB(job.Deps),
+			Attempt:  int32(job.Attempt),
+			Worker:   job.Worker,
+			Queued:   timeToPB(job.Queued),
+			Picked:   timeToPB(job.Picked),
+			Finished: timeToPB(job.Finished),
+			Timings:  timingsToPB(job.Timings),
+			Failed:   job.Failed,
+		})
+	}
+	return pb
+}
+
+func statusUpdateToPB(u *StatusUpdate) *apipb.StatusUpdate {
+	pb := &apipb.StatusUpdate{
+		JobFinished: jobResultToPB(u.JobFinished),
+		Trace:       traceToPB(u.Trace),
+	}
+
+	if out := u.JobOutput; out != nil {
+		pb.JobOutput = &apipb.JobOutput{
+			Id:           out.ID[:],
+			StdoutOffset: out.StdoutOffset,
+			StderrOffset: out.StderrOffset,
+			Stdout:       out.Stdout,
+			Stderr:       out.Stderr,
+		}
+	}
+
+	if s := u.TestShard; s != nil {
+		pb.TestShard = &apipb.TestShard{
+			JobId:    s.JobID[:],
+			Shard:    int32(s.Shard),
+			Shards:   int32(s.Shards),
+			Tests:    s.Tests,
+			Attempts: int32(s.Attempts),
+			Failed:   s.Failed,
+			Flaky:    s.Flaky,
+			Result:   jobResultToPB(&s.Result),
+		}
+	}
+
+	if a := u.JobsAdded; a != nil {
+		pb.JobsAdded = &apipb.JobsAdded{Generator: a.Generator[:]}
+		for i := range a.Jobs {
+			pb.JobsAdded.Jobs = append(pb.JobsAdded.Jobs, jobToPB(&a.Jobs[i]))
+		}
+	}
+
+	if u.BuildFailed != nil {
+		pb.BuildFailed = &apipb.BuildFailed{Error: u.BuildFailed.Error}
+	}
+	if u.BuildFinished != nil {
+		pb.BuildFinished = &apipb.BuildFinished{}
+	}
+	return pb
+}
+
+func buildStartedToPB(rsp *BuildStarted) *apipb.BuildStarted {
+	return &apipb.BuildStarted{Id: rsp.ID[:], MissingFiles: idsToPB(rsp.MissingFiles)}
+}
+
+func signalRequestToPB(buildID build.ID, signal *SignalRequest) *apipb.SignalRequest {
+	pb := &apipb.SignalRequest{BuildId: buildID[:]}
+	if signal.UploadDone != nil {
+		pb.UploadDone = &apipb.UploadDone{}
+	}
+	if signal.Cancel != nil {
+		pb.Cancel = &apipb.Cancel{}
+	}
+	return pb
+}
+
+func heartbeatRequestToPB(req *HeartbeatRequest) *apipb.HeartbeatRequest {
+	pb := &apipb.HeartbeatRequest{
+		WorkerId:    string(req.WorkerID),
+		RunningJobs: idsToPB(req.RunningJobs),
+		FreeSlots:   int32(req.FreeSlots),
+		Resources: &apipb.WorkerResources{
+			Capacity: resourcesToPB(req.Resources.Capacity),
+			Free:     resourcesToPB(req.Resources.Free),
+		},
+		AddedArtifacts:   idsToPB(req.AddedArtifacts),
+		EvictedArtifacts: idsToPB(req.EvictedArtifacts),
+	}
+	for i := range req.FinishedJob {
+		pb.FinishedJob = append(pb.FinishedJob, jobResultToPB(&req.FinishedJob[i]))
+	}
+	return pb
+}
+
+func heartbeatResponseToPB(rsp *HeartbeatResponse) *apipb.HeartbeatResponse {
+	pb := &apipb.HeartbeatResponse{JobsToCancel: idsToPB(rsp.JobsToCancel)}
+	if rsp.Waited != 0 {
+		pb.Waited = durationpb.New(rsp.Waited)
+	}
+	for _, spec := range rsp.JobsToRun {
+		pbSpec := &apipb.JobSpec{
+			SourceFiles:     sourceFilesToPB(spec.SourceFiles),
+			Job:             jobToPB(&spec.Job),
+			CachedArtifacts: idsToPB(spec.CachedArtifacts),
+		}
+		for id, workerID := range spec.Artifacts {
+			pbSpec.Artifacts = append(pbSpec.Artifacts, &apipb.Artifact{Id: id[:], WorkerId: string(workerID)})
+		}
+		pb.JobsToRun = append(pb.JobsToRun, pbSpec)
+	}
+	return pb
+}
+
+// pbDecoder переводит сообщения apipb в типы api и запоминает первую ошибку, например ID неверной длины.
+type pbDecoder struct {
+	err error
+}
+
+func (d *pbDecoder) id(b []byte) build.ID {
+	var id build.ID
+	if len(b) != len(id) {
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid id length %d", len(b))
+		}
+		return id
+	}
+
+	copy(id[:], b)
+	return id
+}
+
+func (d *pbDecoder) ids(pb [][]byte) []build.ID {
+	if pb == nil {
+		return nil
+	}
+
+	ids := make([]build.ID, len(pb))
+	for i, b := range pb {
+		ids[i] = d.id(b)
+	}
+	return ids
+}
+
+func (d *pbDecoder) time(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func (d *pbDecoder) sourceFiles(pb []*apipb.SourceFile) map[build.ID]string {
+	if len(pb) == 0 {
+		return nil
+	}
+
+	files := make(map[build.ID]string, len(pb))
+	for _, f := range pb {
+		files[d.id(f.Id)] = f.Path
+	}
+	return files
+}
+
+func (d *pbDecoder) resources(pb *apipb.Resources) build.Resources {
+	return build.Resources{MilliCPU: pb.GetMilliCpu(), Memory: pb.GetMemory()}
+}
+
+func (d *pbDecoder) job(pb *apipb.Job) build.Job {
+	job := build.Job{
+		ID:        d.id(pb.GetId()),
+		Name:      pb.GetName(),
+		Inputs:    pb.GetInputs(),
+		Deps:      d.ids(pb.GetDeps()),
+		Resources: d.resources(pb.GetResources()),
+		Fragment:  pb.GetFragment(),
+	}
+
+	for _, cmd := range pb.GetCmds() {
+		job.Cmds = append(job.Cmds, build.Cmd{
+			Exec:             cmd.Exec,
+			Environ:          cmd.Environ,
+			WorkingDirectory: cmd.WorkingDirectory,
+			CatTemplate:      cmd.CatTemplate,
+			CatOutput:        cmd.CatOutput,
+			CopyFrom:         cmd.CopyFrom,
+			CopyTo:           cmd.CopyTo,
+			SymlinkTarget:    cmd.SymlinkTarget,
+			SymlinkPath:      cmd.SymlinkPath,
+			MkdirPath:        cmd.MkdirPath,
+			WriteFrom:        cmd.WriteFrom,
+			WriteOutput:      cmd.WriteOutput,
+		})
+	}
+
+	if test := pb.GetTest(); test != nil {
+		job.Test = &build.Test{
+			Names:   test.Names,
+			Shards:  int(test.Shards),
+			Retries: int(test.Retries),
+		}
+	}
+	return job
+}
+
+func (d *pbDecoder) buildRequest(pb *apipb.BuildRequest) *BuildRequest {
+	req := &BuildRequest{
+		Graph:    build.Graph{SourceFiles: d.sourceFiles(pb.GetGraph().GetSourceFiles())},
+		Priority: Priority(pb.GetPriority()),
+		Trace:    pb.GetTrace(),
+		Cached:   d.ids(pb.GetCached()),
+	}
+	for _, job := range pb.GetGraph().GetJobs() {
+		req.Graph.Jobs = append(req.Graph.Jobs, d.job(job))
+	}
+	return req
+}
+
+func (d *pbDecoder) span(pb *apipb.Span) trace.Span {
+	return trace.Span{Start: d.time(pb.GetStart()), End: d.time(pb.GetEnd())}
+}
+
+func (d *pbDecoder) timings(pb *apipb.Timings) *trace.Timings {
+	if pb == nil {
+		return nil
+	}
+
+	return &trace.Timings{
+		CacheHit: pb.CacheHit,
+		Download: d.span(pb.Download),
+		Exec:     d.span(pb.Exec),
+		Upload:   d.span(pb.Upload),
+	}
+}
+
+func (d *pbDecoder) jobResult(pb *apipb.JobResult) *JobResult {
+	if pb == nil {
+		return nil
+	}
+
+	res := &JobResult{
+		ID:         d.id(pb.Id),
+		Stdout:     pb.Stdout,
+		Stderr:     pb.Stderr,
+		ExitCode:   int(pb.ExitCode),
+		Error:      pb.Error,
+		WorkerLost: pb.WorkerLost,
+		FlakyTests: pb.FlakyTests,
+		Timings:    d.timings(pb.Timings),
+	}
+	for _, v := range pb.Violations {
+		res.Violations = append(res.Violations, Violation{Kind: ViolationKind(v.Kind), Path: v.Path})
+	}
+	return res
+}
+
+func (d *pbDecoder) trace(pb *apipb.Trace) *trace.Trace {
+	if pb == nil {
+		return nil
+	}
+
+	t := &trace.Trace{
+		BuildID: d.id(pb.BuildId),
+		Start:   d.time(pb.Start),
+		End:     d.time(pb.End),
+	}
+	for _, job := range pb.Jobs {
+		t.Jobs = append(t.Jobs, trace.Job{
+			ID:       d.id(job.Id),
+			Name:     job.Name,
+			Deps:     d.ids(job.Deps),
+			Attempt:  int(job.Attempt),
+			Worker:   job.Worker,
+			Queued:   d.time(job.Queued),
+			Picked:   d.time(job.Picked),
+			Finished: d.time(job.Finished),
+			Timings:  d.timings(job.Timings),
+			Failed:   job.Failed,
+		})
+	}
+	return t
+}
+
+func (d *pbDecoder) statusUpdate(pb *apipb.StatusUpdate) *StatusUpdate {
+	u := &StatusUpdate{
+		JobFinished: d.jobResult(pb.JobFinished),
+		Trace:       d.trace(pb.Trace),
+	}
+
+	if out := pb.JobOutput; out != nil {
+		u.JobOutput = &JobOutput{
+			ID:           d.id(out.Id),
+			StdoutOffset: out.StdoutOffset,
+			StderrOffset: out.StderrOffset,
+			Stdout:       out.Stdout,
+			Stderr:       out.Stderr,
+		}
+	}
+
+	if s := pb.TestShard; s != nil {
+		u.TestShard = &TestShard{
+			JobID:    d.id(s.JobId),
+			Shard:    int(s.Shard),
+			Shards:   int(s.Shards),
+			Tests:    s.Tests,
+			Attempts: int(s.Attempts),
+			Failed:   s.Failed,
+			Flaky:    s.Flaky,
+		}
+		if res := d.jobResult(s.Result); res != nil {
+			u.TestShard.Result = *res
+		}
+	}
+
+	if a := pb.JobsAdded; a != nil {
+		u.JobsAdded = &JobsAdded{Generator: d.id(a.Generator)}
+		for _, job := range a.Jobs {
+			u.JobsAdded.Jobs = append(u.JobsAdded.Jobs, d.job(job))
+		}
+	}
+
+	if pb.BuildFailed != nil {
+		u.BuildFailed = &BuildFailed{Error: pb.BuildFailed.Error}
+	}
+	if pb.BuildFinished != nil {
+		u.BuildFinished = &BuildFinished{}
+	}
+	return u
+}
+
+func (d *pbDecoder) buildStarted(pb *apipb.BuildStarted) *BuildStarted {
+	return &BuildStarted{ID: d.id(pb.Id), MissingFiles: d.ids(pb.MissingFiles)}
+}
+
+func (d *pbDecoder) signalRequest(pb *apipb.SignalRequest) (build.ID, *SignalRequest) {
+	signal := &SignalRequest{}
+	if pb.UploadDone != nil {
+		signal.UploadDone = &UploadDone{}
+	}
+	if pb.Cancel != nil {
+		signal.Cancel = &Cancel{}
+	}
+	return d.id(pb.BuildId), signal
+}
+
+func (d *pbDecoder) heartbeatRequest(pb *apipb.HeartbeatRequest) *HeartbeatRequest {
+	req := &HeartbeatRequest{
+		WorkerID:    WorkerID(pb.WorkerId),
+		RunningJobs: d.ids(pb.RunningJobs),
+		FreeSlots:   int(pb.FreeSlots),
+		Resources: WorkerResources{
+			Capacity: d.resources(pb.GetResources().GetCapacity()),
+			Free:     d.resources(pb.GetResources().GetFree()),
+		},
+		AddedArtifacts:   d.ids(pb.AddedArtifacts),
+		EvictedArtifacts: d.ids(pb.EvictedArtifacts),
+	}
+	for _, res := range pb.FinishedJob {
+		req.FinishedJob = append(req.FinishedJob, *d.jobResult(res))
+	}
+	return req
+}
+
+func (d *pbDecoder) heartbeatResponse(pb *apipb.HeartbeatResponse) *HeartbeatResponse {
+	rsp := &HeartbeatResponse{JobsToCancel: d.ids(pb.JobsToCancel), Waited: pb.Waited.AsDuration()}
+	if len(pb.JobsToRun) != 0 {
+		rsp.JobsToRun = make(map[build.ID]JobSpec, len(pb.JobsToRun))
+	}
+
+	for _, pbSpec := range pb.JobsToRun {
+		spec := JobSpec{
+			SourceFiles:     d.sourceFiles(pbSpec.SourceFiles),
+			Job:             d.job(pbSpec.Job),
+			CachedArtifacts: d.ids(pbSpec.CachedArtifacts),
+		}
+		if len(pbSpec.Artifacts) != 0 {
+			spec.Artifacts = make(map[build.ID]WorkerID, len(pbSpec.Artifacts))
+		}
+		for _, a := range pbSpec.Artifacts {
+			spec.Artifacts[d.id(a.Id)] = WorkerID(a.WorkerId)
+		}
+		rsp.JobsToRun[spec.ID] = spec
+	}
+	return rsp
+}
@@ -0,0 +1,151 @@
+//go:build !solution
+
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/apipb"
+)
+
+// BuildGRPCServer отдаёт Service по gRPC. Статус сборки передаётся в server-streaming вызове:
+// первым идёт BuildStarted, за ним StatusUpdate-ы.
+type BuildGRPCServer struct {
+	apipb.UnimplementedBuildServer
+
+	l *zap.Logger
+	s Service
+}
+
+func NewBuildGRPCServer(l *zap.Logger, s Service) *BuildGRPCServer {
+	return &BuildGRPCServer{l: l, s: s}
+}
+
+func (h *BuildGRPCServer) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterBuildServer(s, h)
+}
+
+type buildEventSender interface {
+	Send(*apipb.BuildEvent) error
+}
+
+type grpcStatusWriter struct {
+	stream  buildEventSender
+	started bool
+}
+
+func (w *grpcStatusWriter) Started(rsp *BuildStarted) error {
+	if w.started {
+		return fmt.Errorf("build is already started")
+	}
+
+	w.started = true
+	return w.stream.Send(&apipb.BuildEvent{Event: &apipb.BuildEvent_Started{Started: buildStartedToPB(rsp)}})
+}
+
+func (w *grpcStatusWriter) Updated(update *StatusUpdate) error {
+	if !w.started {
+		return fmt.Errorf("build is not started")
+	}
+
+	return w.stream.Send(&apipb.BuildEvent{Event: &apipb.BuildEvent_Update{Update: statusUpdateToPB(update)}})
+}
+
+func (h *BuildGRPCServer) StartBuild(pb *apipb.BuildRequest, stream apipb.Build_StartBuildServer) error {
+	var d pbDecoder
+	req := d.buildRequest(pb)
+	if d.err != nil {
+		h.l.Warn("invalid build request", zap.Error(d.err))
+		return status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	return h.stream(stream, func(sw *grpcStatusWriter) error {
+		return h.s.StartBuild(stream.Context(), req, sw)
+	})
+}
+
+func (h *BuildGRPCServer) AttachBuild(pb *apipb.AttachRequest, stream apipb.Build_AttachBuildServer) error {
+	var d pbDecoder
+	req := &AttachRequest{BuildID: d.id(pb.BuildId), Received: int(pb.Received)}
+	if d.err != nil {
+		h.l.Warn("invalid attach request", zap.Error(d.err))
+		return status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	return h.stream(stream, func(sw *grpcStatusWriter) error {
+		return h.s.AttachBuild(stream.Context(), req, sw)
+	})
+}
+
+// stream отдаёт клиенту статус сборки, который run пишет в grpcStatusWriter. Ошибка до BuildStarted
+// возвращается статусом вызова, а после - в StatusUpdate.BuildFailed, как и в HTTP API.
+func (h *BuildGRPCServer) stream(stream buildEventSender, run func(sw *grpcStatusWriter) error) error {
+	sw := &grpcStatusWriter{stream: stream}
+
+	err := run(sw)
+	if err == nil {
+		return nil
+	}
+
+	h.l.Warn("build failed", zap.Error(err))
+	if !sw.started {
+		return status.Error(errorCode(err), err.Error())
+	}
+
+	if err := sw.Updated(&StatusUpdate{BuildFailed: &BuildFailed{Error: err.Error()}}); err != nil {
+		h.l.Warn("failed to send build error", zap.Error(err))
+	}
+	return nil
+}
+
+func (h *BuildGRPCServer) SignalBuild(ctx context.Context, pb *apipb.SignalRequest) (*apipb.SignalResponse, error) {
+	var d pbDecoder
+	buildID, req := d.signalRequest(pb)
+	if d.err != nil {
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	if _, err := h.s.SignalBuild(ctx, buildID, req); err != nil {
+		h.l.Warn("signal failed", zap.String("build_id", buildID.String()), zap.Error(err))
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+	return &apipb.SignalResponse{}, nil
+}
+
+// HeartbeatGRPCServer отдаёт HeartbeatService по gRPC.
+type HeartbeatGRPCServer struct {
+	apipb.UnimplementedHeartbeatServer
+
+	l *zap.Logger
+	s HeartbeatService
+}
+
+func NewHeartbeatGRPCServer(l *zap.Logger, s HeartbeatService) *HeartbeatGRPCServer {
+	return &HeartbeatGRPCServer{l: l, s: s}
+}
+
+func (h *HeartbeatGRPCServer) Register(s grpc.ServiceRegistrar) {
+	apipb.RegisterHeartbeatServer(s, h)
+}
+
+func (h *HeartbeatGRPCServer) Heartbeat(ctx context.Context, pb *apipb.HeartbeatRequest) (*apipb.HeartbeatResponse, error) {
+	var d pbDecoder
+	req := d.heartbeatRequest(pb)
+	if d.err != nil {
+		h.l.Warn("invalid heartbeat request", zap.Error(d.err))
+		return nil, status.Error(codes.InvalidArgument, d.err.Error())
+	}
+
+	rsp, err := h.s.Heartbeat(ctx, req)
+	if err != nil {
+		h.l.Warn("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+	return heartbeatResponseToPB(rsp), nil
+}
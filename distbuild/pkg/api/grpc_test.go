@@ -0,0 +1,253 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/mock"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// newGRPC запускает gRPC сервер с сервисами register в памяти и возвращает соединение с ним.
+func newGRPC(t *testing.T, register func(s grpc.ServiceRegistrar)) *grpc.ClientConn {
+	lsn := bufconn.Listen(1 << 20)
+
+	s := grpc.NewServer()
+	register(s)
+	go func() { _ = s.Serve(lsn) }()
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lsn.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	return cc
+}
+
+func TestGRPCBuild(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock.NewMockService(ctrl)
+
+	l := zaptest.NewLogger(t)
+	cc := newGRPC(t, api.NewBuildGRPCServer(l, m).Register)
+	client := api.NewBuildGRPCClient(l, cc)
+
+	start := time.Unix(1700000000, 0).UTC()
+	errorMsg := "exit status 1"
+
+	req := &api.BuildRequest{
+		Graph: build.Graph{
+			SourceFiles: map[build.ID]string{{'f'}: "a.go"},
+			Jobs: []build.Job{
+				{
+					ID:     build.ID{'a'},
+					Name:   "test a",
+					Inputs: []string{"a.go"},
+					Deps:   []build.ID{{'b'}},
+					Cmds: []build.Cmd{
+						{Exec: []string{"go", "test"}, Environ: []string{"GOCACHE=/tmp"}, WorkingDirectory: "/src"},
+						{CatTemplate: "ok", CatOutput: "/out/ok"},
+						{CopyFrom: "/dep/a", CopyTo: "/out/a"},
+						{SymlinkTarget: "a", SymlinkPath: "/out/b"},
+						{MkdirPath: "/out/c"},
+						{WriteFrom: "/dep/d.tmpl", WriteOutput: "/out/d"},
+					},
+					Resources: build.Resources{MilliCPU: 500, Memory: 1 << 20},
+					Test:      &build.Test{Names: []string{"TestA"}, Shards: 2, Retries: 1},
+				},
+				{
+					ID:       build.ID{'b'},
+					Name:     "gen",
+					Cmds:     []build.Cmd{{Exec: []string{"gen", "{{.OutputDir}}/graph.json"}}},
+					Fragment: "graph.json",
+				},
+			},
+		},
+		Priority: api.PriorityInteractive,
+		Trace:    true,
+		Cached:   []build.ID{{'c'}},
+	}
+
+	started := &api.BuildStarted{ID: build.ID{'x'}, MissingFiles: []build.ID{{'f'}}}
+	updates := []*api.StatusUpdate{
+		{JobOutput: &api.JobOutput{ID: build.ID{'a'}, StdoutOffset: 3, Stdout: []byte("foo")}},
+		{JobsAdded: &api.JobsAdded{
+			Generator: build.ID{'b'},
+			Jobs:      []build.Job{{ID: build.ID{'c'}, Name: "c", Deps: []build.ID{{'b'}}, Cmds: []build.Cmd{{Exec: []string{"true"}}}}},
+		}},
+		{TestShard: &api.TestShard{
+			JobID:    build.ID{'a'},
+			Shards:   2,
+			Tests:    []string{"TestA"},
+			Attempts: 2,
+			Flaky:    []string{"TestA"},
+			Result:   api.JobResult{ID: build.ID{'s'}, Stdout: []byte("PASS")},
+		}},
+		{JobFinished: &api.JobResult{
+			ID:         build.ID{'a'},
+			Stderr:     []byte("FAIL"),
+			ExitCode:   1,
+			Error:      &errorMsg,
+			Violations: []api.Violation{{Kind: api.ReadOutside, Path: "/etc/passwd"}},
+			Timings:    &trace.Timings{Exec: trace.Span{Start: start, End: start.Add(time.Second)}},
+		}},
+		{Trace: &trace.Trace{
+			BuildID: build.ID{'x'},
+			Start:   start,
+			End:     start.Add(time.Minute),
+			Jobs:    []trace.Job{{ID: build.ID{'a'}, Name: "test a", Attempt: 1, Worker: "w0", Queued: start, Failed: true}},
+		}},
+		{BuildFinished: &api.BuildFinished{}},
+	}
+
+	m.EXPECT().StartBuild(gomock.Any(), req, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *api.BuildRequest, w api.StatusWriter) error {
+			if err := w.Started(started); err != nil {
+				return err
+			}
+
+			for _, u := range updates {
+				if err := w.Updated(u); err != nil {
+					return err
+				}
+			}
+			return fmt.Errorf("foo bar error")
+		})
+
+	rsp, r, err := client.StartBuild(context.Background(), req)
+	require.NoError(t, err)
+	defer func() { _ = r.Close() }()
+	require.Equal(t, started, rsp)
+
+	for _, expected := range updates {
+		u, err := r.Next()
+		require.NoError(t, err)
+		require.Equal(t, expected, u)
+	}
+
+	u, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, &api.StatusUpdate{BuildFailed: &api.BuildFailed{Error: "foo bar error"}}, u)
+
+	_, err = r.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestGRPCBuildErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock.NewMockService(ctrl)
+
+	l := zaptest.NewLogger(t)
+	cc := newGRPC(t, api.NewBuildGRPCServer(l, m).Register)
+	client := api.NewBuildGRPCClient(l, cc)
+	ctx := context.Background()
+
+	m.EXPECT().StartBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("foo bar error"))
+	_, _, err := client.StartBuild(ctx, &api.BuildRequest{})
+	require.ErrorContains(t, err, "foo bar error")
+
+	m.EXPECT().AttachBuild(gomock.Any(), gomock.Any(), gomock.Any()).Return(fmt.Errorf("unknown build"))
+	_, err = client.AttachBuild(ctx, &api.AttachRequest{BuildID: build.ID{03}})
+	require.ErrorContains(t, err, "unknown build")
+
+	signal := &api.SignalRequest{Cancel: &api.Cancel{}}
+	m.EXPECT().SignalBuild(gomock.Any(), build.ID{01}, signal).Return(&api.SignalResponse{}, nil)
+	m.EXPECT().SignalBuild(gomock.Any(), build.ID{02}, signal).Return(nil, fmt.Errorf("foo bar error"))
+
+	_, err = client.SignalBuild(ctx, build.ID{01}, signal)
+	require.NoError(t, err)
+
+	_, err = client.SignalBuild(ctx, build.ID{02}, signal)
+	require.ErrorContains(t, err, "foo bar error")
+}
+
+func TestGRPCBuildClose(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock.NewMockService(ctrl)
+
+	l := zaptest.NewLogger(t)
+	cc := newGRPC(t, api.NewBuildGRPCServer(l, m).Register)
+	client := api.NewBuildGRPCClient(l, cc)
+
+	cancelled := make(chan struct{})
+	m.EXPECT().AttachBuild(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *api.AttachRequest, w api.StatusWriter) error {
+			if err := w.Started(&api.BuildStarted{ID: req.BuildID}); err != nil {
+				return err
+			}
+
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		})
+
+	r, err := client.AttachBuild(context.Background(), &api.AttachRequest{BuildID: build.ID{01}, Received: 2})
+	require.NoError(t, err)
+
+	// Как и разрыв HTTP соединения, Close отменяет контекст сервиса.
+	require.NoError(t, r.Close())
+	<-cancelled
+}
+
+func TestGRPCHeartbeat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock.NewMockHeartbeatService(ctrl)
+
+	l := zaptest.NewLogger(t)
+	cc := newGRPC(t, api.NewHeartbeatGRPCServer(l, m).Register)
+	client := api.NewHeartbeatGRPCClient(l, cc)
+
+	req := &api.HeartbeatRequest{
+		WorkerID:    "worker0",
+		RunningJobs: []build.ID{{'r'}},
+		FreeSlots:   1,
+		Resources: api.WorkerResources{
+			Capacity: build.Resources{MilliCPU: 4000, Memory: 1 << 30},
+			Free:     build.Resources{MilliCPU: 3000, Memory: 1 << 29},
+		},
+		FinishedJob:    []api.JobResult{{ID: build.ID{'f'}, Stdout: []byte("ok"), WorkerLost: true}},
+		AddedArtifacts: []build.ID{{'f'}},
+	}
+	rsp := &api.HeartbeatResponse{
+		JobsToRun: map[build.ID]api.JobSpec{
+			{0x01}: {
+				SourceFiles:     map[build.ID]string{{'s'}: "a.c"},
+				Artifacts:       map[build.ID]api.WorkerID{{'d'}: "worker1"},
+				CachedArtifacts: []build.ID{{'e'}},
+				Job:             build.Job{ID: build.ID{0x01}, Name: "cc a.c", Cmds: []build.Cmd{{Exec: []string{"cc", "a.c"}}}},
+			},
+		},
+		JobsToCancel: []build.ID{{'c'}},
+		Waited:       300 * time.Millisecond,
+	}
+
+	gomock.InOrder(
+		m.EXPECT().Heartbeat(gomock.Any(), gomock.Eq(req)).Return(rsp, nil),
+		m.EXPECT().Heartbeat(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("%w: unknown worker", auth.ErrForbidden)),
+	)
+
+	clientRsp, err := client.Heartbeat(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, rsp, clientRsp)
+
+	_, err = client.Heartbeat(context.Background(), req)
+	require.ErrorContains(t, err, "code = PermissionDenied desc = forbidden: unknown worker")
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
 )
 
 // JobResult описывает результат работы джоба.
@@ -18,6 +20,38 @@ type JobResult struct {
 	//
 	// Если Error == nil, значит джоб завершился успешно.
 	Error *string
+
+	// Violations перечисляет нарушения герметичности, которые воркер нашёл,
+	// запуская джоб в герметичном режиме.
+	Violations []Violation `json:",omitempty"`
+
+	// WorkerLost означает, что джоб не выполнен, потому что воркер, на котором он запускался,
+	// перестал отвечать. В отличие от ненулевого ExitCode, такая ошибка ничего не говорит о самом
+	// джобе, и координатор перезапускает джоб на другом воркере.
+	WorkerLost bool `json:",omitempty"`
+
+	// FlakyTests перечисляет тесты тестового джоба, которые прошли только после перезапуска шарда.
+	FlakyTests []string `json:",omitempty"`
+
+	// Timings описывает, на что ушло время выполнения джоба на воркере.
+	Timings *trace.Timings `json:",omitempty"`
+}
+
+type ViolationKind string
+
+const (
+	// UndeclaredInput - джоб обратился к файлу исходного кода, которого нет в Job.Inputs.
+	UndeclaredInput ViolationKind = "undeclared_input"
+	// ReadOutside - джоб читал файл вне своих входов, зависимостей и системных директорий.
+	ReadOutside ViolationKind = "read_outside"
+	// WriteOutside - джоб писал вне {{.OutputDir}}.
+	WriteOutside ViolationKind = "write_outside"
+)
+
+// Violation описывает одно обращение джоба к файловой системе, нарушающее герметичность.
+type Violation struct {
+	Kind ViolationKind
+	Path string
 }
 
 type WorkerID string
@@ -39,14 +73,52 @@ type HeartbeatRequest struct {
 	RunningJobs []build.ID
 
 	// FreeSlots сообщает, сколько еще процессов можно запустить на этом воркере.
+	//
+	// Воркер, который учитывает ресурсы, присылает 1, если в Resources.Free есть место ещё для одного джоба.
 	FreeSlots int
 
+	// Resources сообщает ресурсы воркера. Координатор отдаёт воркеру только джобы,
+	// которые помещаются в Resources.Free.
+	Resources WorkerResources
+
 	// JobResult сообщает координатору, какие джобы завершили исполнение на этом воркере
 	// на этой итерации цикла.
 	FinishedJob []JobResult
 
 	// AddedArtifacts говорит, какие артефакты появились в кеше на этой итерации цикла.
 	AddedArtifacts []build.ID
+
+	// EvictedArtifacts говорит, какие артефакты удалены из кеша на этой итерации цикла.
+	EvictedArtifacts []build.ID
+}
+
+// WorkerResources описывает ресурсы воркера.
+type WorkerResources struct {
+	// Capacity задаёт все ресурсы воркера. Нулевое Capacity.Memory означает, что воркер не следит за памятью.
+	Capacity build.Resources
+
+	// Free задаёт ресурсы, не занятые выполняющимися джобами.
+	Free build.Resources
+}
+
+// Idle возвращает true, если на воркере не выполняется ни одного джоба.
+func (r WorkerResources) Idle() bool {
+	return r.Free == r.Capacity
+}
+
+// Fits проверяет, можно ли запустить на воркере джоб, которому нужно requests.
+//
+// Простаивающий воркер берёт любой джоб, даже если тот не помещается в Capacity.
+// Иначе джоб, которому нужно больше ресурсов, чем есть у любого воркера, никогда бы не выполнился.
+func (r WorkerResources) Fits(requests build.Resources) bool {
+	if r.Idle() {
+		return true
+	}
+
+	if requests.MilliCPU > r.Free.MilliCPU {
+		return false
+	}
+	return r.Capacity.Memory == 0 || requests.Memory <= r.Free.Memory
 }
 
 // JobSpec описывает джоб, который нужно запустить.
@@ -57,11 +129,24 @@ type JobSpec struct {
 	// Artifacts задаёт воркеров, с которых можно скачать артефакты необходимые этому джобу.
 	Artifacts map[build.ID]WorkerID
 
+	// CachedArtifacts перечисляет зависимости, выходы которых воркер скачивает из удалённого кеша
+	// координатора (см. BuildRequest.Cached).
+	CachedArtifacts []build.ID `json:",omitempty"`
+
 	build.Job
 }
 
 type HeartbeatResponse struct {
 	JobsToRun map[build.ID]JobSpec
+
+	// JobsToCancel перечисляет джобы, которые больше никому не нужны.
+	//
+	// Воркер убивает процессы этих джобов и не присылает их результаты.
+	JobsToCancel []build.ID `json:",omitempty"`
+
+	// Waited - сколько координатор ждал нового джоба для воркера, прежде чем ответить.
+	// Воркер не считает это время задержкой heartbeat-а.
+	Waited time.Duration `json:",omitempty"`
 }
 
 type HeartbeatService interface {
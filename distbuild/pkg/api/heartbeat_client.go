@@ -3,18 +3,53 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 
 	"go.uber.org/zap"
 )
 
 type HeartbeatClient struct {
+	l        *zap.Logger
+	endpoint string
+	http     *http.Client
 }
 
-func NewHeartbeatClient(l *zap.Logger, endpoint string) *HeartbeatClient {
-	panic("implement me")
+func NewHeartbeatClient(l *zap.Logger, endpoint string, opts ...ClientOption) *HeartbeatClient {
+	return &HeartbeatClient{l: l, endpoint: endpoint, http: newClientConfig(opts).http}
 }
 
 func (c *HeartbeatClient) Heartbeat(ctx context.Context, req *HeartbeatRequest) (*HeartbeatResponse, error) {
-	panic("implement me")
+	reqJS, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/heartbeat", bytes.NewBuffer(reqJS))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRsp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpRsp.Body.Close() }()
+
+	if httpRsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(httpRsp.Body)
+		return nil, fmt.Errorf("heartbeat failed: %s", errorMsg)
+	}
+
+	var rsp HeartbeatResponse
+	if err := json.NewDecoder(httpRsp.Body).Decode(&rsp); err != nil {
+		return nil, err
+	}
+
+	return &rsp, nil
 }
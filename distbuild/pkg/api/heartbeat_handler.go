@@ -3,18 +3,51 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"go.uber.org/zap"
 )
 
 type HeartbeatHandler struct {
+	l *zap.Logger
+	s HeartbeatService
 }
 
 func NewHeartbeatHandler(l *zap.Logger, s HeartbeatService) *HeartbeatHandler {
-	panic("implement me")
+	return &HeartbeatHandler{l: l, s: s}
 }
 
 func (h *HeartbeatHandler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/heartbeat", h.heartbeat)
+}
+
+func (h *HeartbeatHandler) heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.l.Warn("invalid heartbeat request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.l.Debug("heartbeat started", zap.Any("req", req))
+
+	rsp, err := h.s.Heartbeat(r.Context(), &req)
+	if err != nil {
+		h.l.Warn("heartbeat failed", zap.String("worker_id", req.WorkerID.String()), zap.Error(err))
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	h.l.Debug("heartbeat finished", zap.Any("rsp", rsp))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		h.l.Warn("failed to write heartbeat response", zap.Error(err))
+	}
 }
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: gitlab.com/slon/shad-go/distbuild/pkg/api (interfaces: OutputService)
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	api "gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// MockOutputService is a mock of OutputService interface.
+type MockOutputService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutputServiceMockRecorder
+}
+
+// MockOutputServiceMockRecorder is the mock recorder for MockOutputService.
+type MockOutputServiceMockRecorder struct {
+	mock *MockOutputService
+}
+
+// NewMockOutputService creates a new mock instance.
+func NewMockOutputService(ctrl *gomock.Controller) *MockOutputService {
+	mock := &MockOutputService{ctrl: ctrl}
+	mock.recorder = &MockOutputServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutputService) EXPECT() *MockOutputServiceMockRecorder {
+	return m.recorder
+}
+
+// JobOutput mocks base method.
+func (m *MockOutputService) JobOutput(arg0 context.Context, arg1 *api.JobOutput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JobOutput", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// JobOutput indicates an expected call of JobOutput.
+func (mr *MockOutputServiceMockRecorder) JobOutput(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JobOutput", reflect.TypeOf((*MockOutputService)(nil).JobOutput), arg0, arg1)
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"context"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// JobOutput описывает кусок вывода джоба, который воркер прислал во время работы джоба.
+type JobOutput struct {
+	ID build.ID
+
+	// StdoutOffset и StderrOffset задают смещение начала куска в полном выводе джоба.
+	//
+	// Полный вывод всё равно приходит в JobResult. Смещения позволяют не показывать клиенту
+	// один и тот же вывод дважды.
+	StdoutOffset, StderrOffset int64
+
+	Stdout, Stderr []byte
+}
+
+type OutputService interface {
+	JobOutput(ctx context.Context, output *JobOutput) error
+}
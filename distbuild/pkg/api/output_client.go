@@ -0,0 +1,50 @@
+//go:build !solution
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type OutputClient struct {
+	l        *zap.Logger
+	endpoint string
+	http     *http.Client
+}
+
+func NewOutputClient(l *zap.Logger, endpoint string, opts ...ClientOption) *OutputClient {
+	return &OutputClient{l: l, endpoint: endpoint, http: newClientConfig(opts).http}
+}
+
+func (c *OutputClient) JobOutput(ctx context.Context, output *JobOutput) error {
+	reqJS, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/output", bytes.NewBuffer(reqJS))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRsp, err := c.http.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = httpRsp.Body.Close() }()
+
+	if httpRsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(httpRsp.Body)
+		return fmt.Errorf("job output failed: %s", errorMsg)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+//go:build !solution
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type OutputHandler struct {
+	l *zap.Logger
+	s OutputService
+}
+
+func NewOutputHandler(l *zap.Logger, s OutputService) *OutputHandler {
+	return &OutputHandler{l: l, s: s}
+}
+
+func (h *OutputHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/output", h.output)
+}
+
+func (h *OutputHandler) output(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var output JobOutput
+	if err := json.NewDecoder(r.Body).Decode(&output); err != nil {
+		h.l.Warn("invalid job output request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.JobOutput(r.Context(), &output); err != nil {
+		h.l.Warn("job output failed", zap.String("job_id", output.ID.String()), zap.Error(err))
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+}
@@ -0,0 +1,51 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/api/mock"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+//go:generate mockgen -package mock -destination mock/output.go . OutputService
+
+func TestJobOutput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	l := zaptest.NewLogger(t)
+	m := mock.NewMockOutputService(ctrl)
+	mux := http.NewServeMux()
+	api.NewOutputHandler(l, m).Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := api.NewOutputClient(l, server.URL)
+
+	output := &api.JobOutput{
+		ID:           build.ID{0x01},
+		StdoutOffset: 3,
+		Stdout:       []byte("foo"),
+		Stderr:       []byte("bar"),
+	}
+
+	gomock.InOrder(
+		m.EXPECT().JobOutput(gomock.Any(), gomock.Eq(output)).Times(1).Return(nil),
+		m.EXPECT().JobOutput(gomock.Any(), gomock.Eq(output)).Times(1).Return(fmt.Errorf("client is gone")),
+	)
+
+	require.NoError(t, client.JobOutput(context.Background(), output))
+
+	err := client.JobOutput(context.Background(), output)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "client is gone")
+}
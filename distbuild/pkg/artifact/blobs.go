@@ -0,0 +1,108 @@
+package artifact
+
+import (
+	"path/filepath"
+	"sync"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
+)
+
+// Manifest возвращает список файлов артефакта вместе с хешами их содержимого.
+//
+// Закоммиченный артефакт не меняется, поэтому Manifest считается один раз и хранится до Remove.
+func (c *Cache) Manifest(id build.ID) ([]tarstream.Entry, error) {
+	path, unlock, err := c.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	c.mu.Lock()
+	entries, ok := c.manifests[id]
+	c.mu.Unlock()
+	if ok {
+		return entries, nil
+	}
+
+	entries, err = tarstream.Manifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.manifests[id] = entries
+	c.mu.Unlock()
+	return entries, nil
+}
+
+type blob struct {
+	artifact build.ID
+	path     string
+}
+
+// BlobIndex помнит, в каких артефактах локального кеша лежат файлы с заданным хешом.
+//
+// Download использует индекс, чтобы не скачивать файлы, которые уже есть на диске.
+// Все методы BlobIndex concurrency safe.
+type BlobIndex struct {
+	mu        sync.Mutex
+	blobs     map[tarstream.Hash]blob
+	artifacts map[build.ID][]tarstream.Hash
+}
+
+func NewBlobIndex() *BlobIndex {
+	return &BlobIndex{
+		blobs:     make(map[tarstream.Hash]blob),
+		artifacts: make(map[build.ID][]tarstream.Hash),
+	}
+}
+
+// Add добавляет в индекс файлы артефакта id из кеша c.
+func (x *BlobIndex) Add(c *Cache, id build.ID) error {
+	entries, err := c.Manifest(id)
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if _, ok := x.artifacts[id]; ok {
+		return nil
+	}
+
+	hashes := []tarstream.Hash{}
+	for _, e := range entries {
+		if !e.IsRegular() {
+			continue
+		}
+
+		if _, ok := x.blobs[e.Hash]; !ok {
+			x.blobs[e.Hash] = blob{artifact: id, path: filepath.FromSlash(e.Path)}
+			hashes = append(hashes, e.Hash)
+		}
+	}
+
+	x.artifacts[id] = hashes
+	return nil
+}
+
+// Forget удаляет из индекса файлы артефакта id.
+func (x *BlobIndex) Forget(id build.ID) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for _, h := range x.artifacts[id] {
+		delete(x.blobs, h)
+	}
+	delete(x.artifacts, id)
+}
+
+func (x *BlobIndex) lookup(h tarstream.Hash) (blob, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	b, ok := x.blobs[h]
+	return b, ok
+}
@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 var (
@@ -25,6 +27,15 @@ type Cache struct {
 	mu          sync.Mutex
 	writeLocked map[build.ID]struct{}
 	readLocked  map[build.ID]int
+
+	// lastUsed хранит время последнего Get или commit артефакта, sizes - размер артефакта на диске,
+	// а total - сумму sizes, чтобы Size не обходил диск.
+	lastUsed map[build.ID]time.Time
+	sizes    map[build.ID]int64
+	total    int64
+
+	// manifests хранит уже посчитанные Manifest артефактов.
+	manifests map[build.ID][]tarstream.Entry
 }
 
 func NewCache(root string) (*Cache, error) {
@@ -49,12 +60,33 @@ func NewCache(root string) (*Cache, error) {
 		}
 	}
 
-	return &Cache{
+	c := &Cache{
 		tmpDir:      tmpDir,
 		cacheDir:    cacheDir,
 		writeLocked: make(map[build.ID]struct{}),
 		readLocked:  make(map[build.ID]int),
-	}, nil
+		lastUsed:    make(map[build.ID]time.Time),
+		sizes:       make(map[build.ID]int64),
+		manifests:   make(map[build.ID][]tarstream.Entry),
+	}
+
+	// Размеры артефактов, оставшихся с прошлого запуска, считаются один раз. Дальше total
+	// обновляют commit и Remove.
+	err := c.Range(func(id build.ID) error {
+		size, err := dirSize(filepath.Join(cacheDir, id.Path()))
+		if err != nil {
+			return err
+		}
+
+		c.sizes[id] = size
+		c.total += size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
 }
 
 func (c *Cache) readLock(id build.ID) error {
@@ -108,6 +140,23 @@ func (c *Cache) writeUnlock(id build.ID) {
 	delete(c.writeLocked, id)
 }
 
+func (c *Cache) touch(id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastUsed[id] = time.Now()
+}
+
+func (c *Cache) forget(id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.lastUsed, id)
+	c.total -= c.sizes[id]
+	delete(c.sizes, id)
+	delete(c.manifests, id)
+}
+
 func (c *Cache) Range(artifactFn func(artifact build.ID) error) error {
 	shards, err := os.ReadDir(c.cacheDir)
 	if err != nil {
@@ -141,6 +190,7 @@ func (c *Cache) Remove(artifact build.ID) error {
 	}
 	defer c.writeUnlock(artifact)
 
+	c.forget(artifact)
 	return os.RemoveAll(filepath.Join(c.cacheDir, artifact.Path()))
 }
 
@@ -162,7 +212,22 @@ func (c *Cache) Create(artifact build.ID) (path string, commit, abort func() err
 
 	commit = func() error {
 		defer c.writeUnlock(artifact)
-		return os.Rename(path, filepath.Join(c.cacheDir, artifact.Path()))
+
+		size, err := dirSize(path)
+		if err != nil {
+			return err
+		}
+
+		c.touch(artifact)
+		if err := os.Rename(path, filepath.Join(c.cacheDir, artifact.Path())); err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		c.sizes[artifact] = size
+		c.total += size
+		c.mu.Unlock()
+		return nil
 	}
 
 	return
@@ -183,6 +248,7 @@ func (c *Cache) Get(artifact build.ID) (path string, unlock func(), err error) {
 		return
 	}
 
+	c.touch(artifact)
 	unlock = func() {
 		c.readUnlock(artifact)
 	}
@@ -3,12 +3,269 @@
 package artifact
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 // Download artifact from remote cache into local cache.
 func Download(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) error {
-	panic("implement me")
+	_, err := DownloadN(ctx, endpoint, c, artifactID)
+	return err
+}
+
+// DownloadN работает как Download и возвращает число байт tarstream, полученных от endpoint.
+func DownloadN(ctx context.Context, endpoint string, c *Cache, artifactID build.ID) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/artifact?id="+artifactID.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return 0, fmt.Errorf("download failed: %s", errorMsg)
+	}
+
+	path, commit, abort, err := c.Create(artifactID)
+	if err != nil {
+		return 0, err
+	}
+
+	body := &countingReader{r: rsp.Body}
+	if err := tarstream.Receive(path, body); err != nil {
+		_ = abort()
+		return body.n, err
+	}
+
+	return body.n, commit()
+}
+
+// DownloadOptions задаёт необязательные параметры DownloadWith.
+type DownloadOptions struct {
+	// Blobs - индекс файлов локального кеша. Файлы из индекса копируются с диска, а не скачиваются.
+	// Если Blobs == nil, все файлы артефакта скачиваются.
+	Blobs *BlobIndex
+
+	// Compress просит endpoint сжать ответ gzip.
+	Compress bool
+
+	// Client - http.Client для запросов к endpoint. Если Client == nil, используется http.DefaultClient.
+	Client *http.Client
+}
+
+// Transfer описывает одно скачивание артефакта.
+type Transfer struct {
+	// Received - число байт, полученных от endpoint, вместе со списком файлов артефакта.
+	Received int64
+
+	// Reused - суммарный размер файлов, скопированных из локального кеша вместо скачивания.
+	Reused int64
+}
+
+// DownloadWith скачивает артефакт из удалённого кеша в локальный через /artifact/manifest и /artifact/fetch.
+//
+// Сначала DownloadWith получает список файлов артефакта и ищет их хеши в opts.Blobs.
+// Затем запрашивает артефакт, перечислив найденные хеши, и endpoint присылает только недостающие файлы.
+func DownloadWith(ctx context.Context, endpoint string, c *Cache, artifactID build.ID, opts DownloadOptions) (t Transfer, err error) {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var manifest []tarstream.Entry
+	t.Received, err = getJSON(ctx, client, endpoint+"/artifact/manifest?id="+artifactID.String(), &manifest)
+	if err != nil {
+		return t, err
+	}
+
+	// Артефакты, из которых копируются файлы, держим под локом на чтение, чтобы их не удалил GC.
+	local := make(map[tarstream.Hash]string)
+	locked := make(map[build.ID]string)
+	var unlocks []func()
+	defer func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}()
+
+	var req FetchRequest
+	for _, e := range manifest {
+		if opts.Blobs == nil || !e.IsRegular() {
+			continue
+		}
+
+		if _, ok := local[e.Hash]; ok {
+			t.Reused += e.Size
+			continue
+		}
+
+		b, ok := opts.Blobs.lookup(e.Hash)
+		if !ok || b.artifact == artifactID {
+			continue
+		}
+
+		dir, ok := locked[b.artifact]
+		if !ok {
+			var unlock func()
+			var getErr error
+			if dir, unlock, getErr = c.Get(b.artifact); getErr != nil {
+				continue
+			}
+			locked[b.artifact] = dir
+			unlocks = append(unlocks, unlock)
+		}
+
+		local[e.Hash] = filepath.Join(dir, b.path)
+		req.Have = append(req.Have, e.Hash)
+		t.Reused += e.Size
+	}
+
+	path, commit, abort, err := c.Create(artifactID)
+	if err != nil {
+		return t, err
+	}
+
+	n, err := fetch(ctx, client, endpoint, artifactID, &req, opts.Compress, func(stream io.Reader) error {
+		return tarstream.ReceiveDedup(path, stream, func(h tarstream.Hash) (string, bool) {
+			p, ok := local[h]
+			return p, ok
+		})
+	})
+	t.Received += n
+	if err != nil {
+		_ = abort()
+		return t, err
+	}
+
+	return t, commit()
+}
+
+// JobOutputDir - директория артефакта джоба, в которую джоб пишет свои файлы ({{.OutputDir}}).
+const JobOutputDir = "output"
+
+// DownloadFiles скачивает из артефакта записи, подходящие под шаблоны include (см. FetchRequest.Include),
+// в существующую директорию dir. Права файлов и символические ссылки сохраняются.
+//
+// Файлы в dir не перезаписываются: если запись уже существует, DownloadFiles возвращает ошибку.
+// opts.Blobs не используется.
+func DownloadFiles(ctx context.Context, endpoint, dir string, artifactID build.ID, include []string, opts DownloadOptions) (Transfer, error) {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req := FetchRequest{Include: include}
+	n, err := fetch(ctx, client, endpoint, artifactID, &req, opts.Compress, func(stream io.Reader) error {
+		return tarstream.Receive(dir, stream)
+	})
+	return Transfer{Received: n}, err
+}
+
+// fetch отправляет POST /artifact/fetch с телом req, передаёт полученный tarstream в receive
+// и возвращает число байт, полученных от endpoint.
+func fetch(
+	ctx context.Context,
+	client *http.Client,
+	endpoint string,
+	artifactID build.ID,
+	req *FetchRequest,
+	compress bool,
+	receive func(stream io.Reader) error,
+) (int64, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/artifact/fetch?id="+artifactID.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	// Явный Accept-Encoding отключает прозрачную распаковку в http.Transport:
+	// так Received считает байты, которые действительно прошли по сети.
+	if compress {
+		httpReq.Header.Set("Accept-Encoding", "gzip")
+	} else {
+		httpReq.Header.Set("Accept-Encoding", "identity")
+	}
+
+	rsp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return 0, fmt.Errorf("download failed: %s", errorMsg)
+	}
+
+	counter := &countingReader{r: rsp.Body}
+
+	var stream io.Reader = counter
+	if rsp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(counter)
+		if err != nil {
+			return counter.n, err
+		}
+		stream = gz
+	}
+
+	err = receive(stream)
+	return counter.n, err
+}
+
+// getJSON читает ответ на GET url в out и возвращает размер ответа.
+func getJSON(ctx context.Context, client *http.Client, url string, out any) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return 0, fmt.Errorf("download failed: %s", errorMsg)
+	}
+
+	body := &countingReader{r: rsp.Body}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return body.n, err
+	}
+
+	// Дочитываем перевод строки после JSON, чтобы посчитать ответ целиком.
+	_, err = io.Copy(io.Discard, body)
+	return body.n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
 }
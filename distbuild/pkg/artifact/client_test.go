@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -49,3 +50,98 @@ func TestArtifactTransfer(t *testing.T) {
 	err = artifact.Download(ctx, server.URL, localCache.Cache, build.ID{0x02})
 	require.Error(t, err)
 }
+
+func TestArtifactTransferDedup(t *testing.T) {
+	remoteCache := newTestCache(t)
+	localCache := newTestCache(t)
+
+	write := func(c *testCache, id build.ID, files map[string]string) {
+		dir, commit, _, err := c.Create(id)
+		require.NoError(t, err)
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0755))
+		}
+		require.NoError(t, commit())
+	}
+
+	big := strings.Repeat("shared", 1024)
+	write(remoteCache, build.ID{0x01}, map[string]string{"shared.bin": big, "own.txt": "own"})
+	write(localCache, build.ID{0x02}, map[string]string{"copy.bin": big})
+
+	blobs := artifact.NewBlobIndex()
+	require.NoError(t, blobs.Add(localCache.Cache, build.ID{0x02}))
+
+	h := artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	transfer, err := artifact.DownloadWith(context.Background(), server.URL, localCache.Cache, build.ID{0x01}, artifact.DownloadOptions{
+		Blobs: blobs,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(big)), transfer.Reused)
+	require.Less(t, transfer.Received, int64(len(big)))
+
+	dir, unlock, err := localCache.Get(build.ID{0x01})
+	require.NoError(t, err)
+	defer unlock()
+
+	for name, content := range map[string]string{"shared.bin": big, "own.txt": "own"} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, content, string(b))
+	}
+}
+
+func TestDownloadFiles(t *testing.T) {
+	remoteCache := newTestCache(t)
+
+	id := build.ID{0x01}
+	dir, commit, _, err := remoteCache.Create(id)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "output", "bin"), 0777))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "output", "lib", "sub"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output", "bin", "tool"), []byte("tool"), 0755))
+	require.NoError(t, os.Symlink("tool", filepath.Join(dir, "output", "bin", "link")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output", "lib", "sub", "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output", "skip.txt"), []byte("skip"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stdout"), []byte("stdout"), 0644))
+	require.NoError(t, commit())
+
+	h := artifact.NewHandler(zaptest.NewLogger(t), remoteCache.Cache)
+	mux := http.NewServeMux()
+	h.Register(mux)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	out := t.TempDir()
+	_, err = artifact.DownloadFiles(ctx, server.URL, out, id, []string{"output/bin/*", "output/lib"}, artifact.DownloadOptions{Compress: true})
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, filepath.Walk(out, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(out, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	}))
+	require.Equal(t, []string{"output/bin/link", "output/bin/tool", "output/lib/sub/a.txt"}, files)
+
+	st, err := os.Stat(filepath.Join(out, "output", "bin", "tool"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0755), st.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(out, "output", "bin", "link"))
+	require.NoError(t, err)
+	require.Equal(t, "tool", link)
+
+	_, err = artifact.DownloadFiles(ctx, server.URL, t.TempDir(), id, []string{"output/["}, artifact.DownloadOptions{})
+	require.ErrorContains(t, err, "invalid pattern")
+}
@@ -0,0 +1,132 @@
+package artifact
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// GCConfig задаёт ограничения на размер кеша.
+type GCConfig struct {
+	// MaxSize ограничивает суммарный размер артефактов в байтах. 0 означает отсутствие ограничения.
+	MaxSize int64
+
+	// MaxAge ограничивает время, прошедшее с последнего Get артефакта. 0 означает отсутствие ограничения.
+	MaxAge time.Duration
+}
+
+type gcEntry struct {
+	id       build.ID
+	lastUsed time.Time
+	size     int64
+}
+
+// GC удаляет из кеша давно не использованные артефакты, пока кеш не уложится в ограничения config.
+//
+// Артефакты удаляются в порядке последнего Get. Артефакты, на которые взят лок на чтение или
+// на запись, не удаляются. Для артефактов, к которым не было Get с момента создания Cache,
+// временем последнего использования считается время изменения директории артефакта.
+//
+// GC возвращает ID удалённых артефактов.
+func (c *Cache) GC(config GCConfig) ([]build.ID, error) {
+	var entries []gcEntry
+	var total int64
+
+	err := c.Range(func(id build.ID) error {
+		e, err := c.gcEntry(id)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		entries = append(entries, e)
+		total += e.size
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastUsed.Before(entries[j].lastUsed)
+	})
+
+	now := time.Now()
+
+	var evicted []build.ID
+	for _, e := range entries {
+		expired := config.MaxAge != 0 && now.Sub(e.lastUsed) > config.MaxAge
+		oversized := config.MaxSize != 0 && total > config.MaxSize
+		if !expired && !oversized {
+			break
+		}
+
+		if err := c.Remove(e.id); errors.Is(err, ErrReadLocked) || errors.Is(err, ErrWriteLocked) {
+			continue
+		} else if err != nil {
+			return evicted, err
+		}
+
+		total -= e.size
+		evicted = append(evicted, e.id)
+	}
+
+	return evicted, nil
+}
+
+// Size возвращает суммарный размер артефактов в кеше. Size не обращается к диску:
+// размер артефакта считается один раз, при commit или в NewCache.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.total
+}
+
+// gcEntry возвращает fs.ErrNotExist для артефакта, который удалён или ещё не закоммичен.
+func (c *Cache) gcEntry(id build.ID) (gcEntry, error) {
+	c.mu.Lock()
+	lastUsed, lastUsedOK := c.lastUsed[id]
+	size, sizeOK := c.sizes[id]
+	c.mu.Unlock()
+
+	if !sizeOK {
+		return gcEntry{}, fs.ErrNotExist
+	}
+
+	e := gcEntry{id: id, lastUsed: lastUsed, size: size}
+	if !lastUsedOK {
+		st, err := os.Stat(filepath.Join(c.cacheDir, id.Path()))
+		if err != nil {
+			return e, err
+		}
+		e.lastUsed = st.ModTime()
+	}
+
+	return e, nil
+}
+
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,124 @@
+package artifact_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func createArtifact(t *testing.T, c *testCache, id build.ID, size int) {
+	path, commit, _, err := c.Create(id)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(path, "data"), make([]byte, size), 0666))
+	require.NoError(t, commit())
+}
+
+func getArtifact(t *testing.T, c *testCache, id build.ID) {
+	_, unlock, err := c.Get(id)
+	require.NoError(t, err)
+	unlock()
+}
+
+func requireMissing(t *testing.T, c *testCache, id build.ID) {
+	_, _, err := c.Get(id)
+	require.Truef(t, errors.Is(err, artifact.ErrNotFound), "%v", err)
+}
+
+func TestGCEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTestCache(t)
+
+	idA, idB, idC := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}
+	for _, id := range []build.ID{idA, idB, idC} {
+		createArtifact(t, c, id, 100)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	getArtifact(t, c, idA)
+
+	require.Equal(t, int64(300), c.Size())
+
+	evicted, err := c.GC(artifact.GCConfig{MaxSize: 200})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idB}, evicted)
+
+	require.Equal(t, int64(200), c.Size())
+
+	requireMissing(t, c, idB)
+	getArtifact(t, c, idA)
+	getArtifact(t, c, idC)
+
+	evicted, err = c.GC(artifact.GCConfig{MaxSize: 200})
+	require.NoError(t, err)
+	require.Empty(t, evicted)
+}
+
+func TestGCMaxAge(t *testing.T) {
+	c := newTestCache(t)
+
+	idA, idB := build.ID{'a'}, build.ID{'b'}
+	createArtifact(t, c, idA, 1)
+	createArtifact(t, c, idB, 1)
+
+	time.Sleep(100 * time.Millisecond)
+	getArtifact(t, c, idB)
+
+	evicted, err := c.GC(artifact.GCConfig{MaxAge: 50 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idA}, evicted)
+
+	getArtifact(t, c, idB)
+}
+
+func TestGCSkipsLockedArtifacts(t *testing.T) {
+	c := newTestCache(t)
+
+	idA, idB := build.ID{'a'}, build.ID{'b'}
+	createArtifact(t, c, idA, 100)
+	createArtifact(t, c, idB, 100)
+
+	_, unlock, err := c.Get(idA)
+	require.NoError(t, err)
+
+	_, commit, _, err := c.Create(build.ID{'c'})
+	require.NoError(t, err)
+
+	evicted, err := c.GC(artifact.GCConfig{MaxSize: 1})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idB}, evicted)
+
+	unlock()
+	require.NoError(t, commit())
+
+	evicted, err = c.GC(artifact.GCConfig{MaxSize: 1})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{idA}, evicted)
+}
+
+func TestSizeTracksCommitAndRemove(t *testing.T) {
+	c := newTestCache(t)
+
+	idA, idB := build.ID{'a'}, build.ID{'b'}
+	createArtifact(t, c, idA, 100)
+	createArtifact(t, c, idB, 10)
+	require.Equal(t, int64(110), c.Size())
+
+	_, _, abort, err := c.Create(build.ID{'c'})
+	require.NoError(t, err)
+	require.NoError(t, abort())
+	require.Equal(t, int64(110), c.Size())
+
+	require.NoError(t, c.Remove(idA))
+	require.Equal(t, int64(10), c.Size())
+
+	reopened, err := artifact.NewCache(c.tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), reopened.Size())
+}
@@ -3,18 +3,232 @@
 package artifact
 
 import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strings"
 
 	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/tarstream"
 )
 
 type Handler struct {
+	l *zap.Logger
+	c *Cache
 }
 
 func NewHandler(l *zap.Logger, c *Cache) *Handler {
-	panic("implement me")
+	return &Handler{l: l, c: c}
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/artifact", h.artifact)
+	mux.HandleFunc("/artifact/manifest", h.manifest)
+	mux.HandleFunc("/artifact/fetch", h.fetch)
+}
+
+// FetchRequest - тело запроса POST /artifact/fetch.
+type FetchRequest struct {
+	// Have перечисляет хеши файлов, которые уже есть у получателя. Содержимое этих файлов не передаётся.
+	Have []tarstream.Hash
+
+	// Include - шаблоны path.Match для путей внутри артефакта. Если Include не пуст, передаются
+	// только подходящие записи, всё содержимое подходящих директорий и директории на пути к ним.
+	Include []string
+}
+
+// maxFetchRequestSize ограничивает размер FetchRequest.
+const maxFetchRequestSize = 64 << 20
+
+func parseID(w http.ResponseWriter, r *http.Request) (build.ID, bool) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, id build.ID, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.l.Warn("failed to open artifact", zap.String("artifact_id", id.String()), zap.Error(err))
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (h *Handler) manifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.c.Manifest(id)
+	if err != nil {
+		h.writeError(w, id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+func (h *Handler) fetch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	var req FetchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFetchRequestSize)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	have := make(map[tarstream.Hash]struct{}, len(req.Have))
+	for _, hash := range req.Have {
+		have[hash] = struct{}{}
+	}
+
+	path, unlock, err := h.c.Get(id)
+	if err != nil {
+		h.writeError(w, id, err)
+		return
+	}
+	defer unlock()
+
+	entries, err := h.c.Manifest(id)
+	if err != nil {
+		h.writeError(w, id, err)
+		return
+	}
+
+	if len(req.Include) != 0 {
+		if entries, err = filterEntries(entries, req.Include); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.l.Debug("sending artifact",
+		zap.String("artifact_id", id.String()),
+		zap.Int("have", len(have)),
+		zap.Strings("include", req.Include),
+		zap.Bool("gzip", acceptsGzip(r)))
+
+	w.Header().Set("Content-Type", "application/x-tar")
+
+	var out io.Writer = w
+	var gz *gzip.Writer
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		out = gz
+	}
+
+	err = tarstream.SendEntries(path, entries, func(hash tarstream.Hash) bool {
+		_, ok := have[hash]
+		return ok
+	}, out)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		h.l.Warn("failed to send artifact", zap.String("artifact_id", id.String()), zap.Error(err))
+	}
+}
+
+// filterEntries оставляет записи, которые подходят под один из шаблонов include или лежат
+// в подходящей директории, и директории на пути к ним. Порядок записей сохраняется.
+func filterEntries(entries []tarstream.Entry, include []string) ([]tarstream.Entry, error) {
+	for _, pattern := range include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	keep := make(map[string]struct{})
+	for _, e := range entries {
+		if !matchAny(e.Path, include) {
+			continue
+		}
+
+		for p := e.Path; p != "."; p = path.Dir(p) {
+			keep[p] = struct{}{}
+		}
+	}
+
+	var filtered []tarstream.Entry
+	for _, e := range entries {
+		if _, ok := keep[e.Path]; ok {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
+
+// matchAny проверяет, что name или одна из директорий на пути к нему подходит под один из patterns.
+func matchAny(name string, patterns []string) bool {
+	for p := name; p != "."; p = path.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptsGzip проверяет, что клиент готов принять ответ, сжатый gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) artifact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+
+	path, unlock, err := h.c.Get(id)
+	if err != nil {
+		h.writeError(w, id, err)
+		return
+	}
+	defer unlock()
+
+	h.l.Debug("sending artifact", zap.String("artifact_id", id.String()))
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	if err := tarstream.Send(path, w); err != nil {
+		h.l.Warn("failed to send artifact", zap.String("artifact_id", id.String()), zap.Error(err))
+	}
 }
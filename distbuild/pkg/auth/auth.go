@@ -0,0 +1,109 @@
+// Package auth реализует аутентификацию между компонентами distbuild: mTLS для координатора
+// и воркеров и bearer токены для клиентов.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrForbidden возвращают сервисы, когда аутентифицированный запрос не имеет права на действие,
+// например heartbeat от имени чужого воркера. Хендлеры отвечают на него 403.
+var ErrForbidden = errors.New("forbidden")
+
+// Peer описывает, кто отправил аутентифицированный запрос.
+type Peer struct {
+	// Certificate - проверенный клиентский сертификат. Если запрос аутентифицирован токеном, Certificate == nil.
+	Certificate *x509.Certificate
+}
+
+// HasURI проверяет, что клиентский сертификат выдан на uri. Воркер получает сертификат
+// с URI равным его WorkerID.
+func (p *Peer) HasURI(uri string) bool {
+	if p.Certificate == nil {
+		return false
+	}
+
+	for _, u := range p.Certificate.URIs {
+		if u.String() == uri {
+			return true
+		}
+	}
+	return false
+}
+
+type peerKey struct{}
+
+// PeerFromContext возвращает Peer запроса, который прошёл через Authenticator.
+func PeerFromContext(ctx context.Context) (*Peer, bool) {
+	p, ok := ctx.Value(peerKey{}).(*Peer)
+	return p, ok
+}
+
+// Authenticator пропускает запросы с клиентским сертификатом, который проверил TLS сервер,
+// или с заголовком "Authorization: Bearer <token>" с одним из известных токенов.
+type Authenticator struct {
+	tokens [][sha256.Size]byte
+}
+
+// NewAuthenticator создаёт Authenticator с токенами tokens. Без токенов пропускаются
+// только запросы с клиентским сертификатом.
+func NewAuthenticator(tokens ...string) *Authenticator {
+	a := &Authenticator{}
+	for _, token := range tokens {
+		a.tokens = append(a.tokens, sha256.Sum256([]byte(token)))
+	}
+	return a
+}
+
+// Authenticate проверяет запрос и возвращает его Peer.
+func (a *Authenticator) Authenticate(r *http.Request) (*Peer, bool) {
+	var chains [][]*x509.Certificate
+	if r.TLS != nil {
+		chains = r.TLS.VerifiedChains
+	}
+	return a.authenticate(chains, r.Header.Get("Authorization"))
+}
+
+// authenticate проверяет цепочки сертификатов, которые проверил TLS сервер, и значение заголовка Authorization.
+func (a *Authenticator) authenticate(chains [][]*x509.Certificate, authorization string) (*Peer, bool) {
+	if len(chains) != 0 {
+		return &Peer{Certificate: chains[0][0]}, true
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+
+	// Токены сравниваются по хешам за одинаковое время, чтобы не выдать их длину и префикс.
+	hash := sha256.Sum256([]byte(token))
+	found := 0
+	for i := range a.tokens {
+		found |= subtle.ConstantTimeCompare(hash[:], a.tokens[i][:])
+	}
+	if found == 0 {
+		return nil, false
+	}
+	return &Peer{}, true
+}
+
+// Handler отвечает 401 на неаутентифицированные запросы, а остальные передаёт в h
+// с Peer в контексте.
+func (a *Authenticator) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := a.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerKey{}, peer)))
+	})
+}
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth/authtest"
+)
+
+func newServer(t *testing.T, ca *authtest.CA, a *auth.Authenticator) *httptest.Server {
+	certFile, keyFile := ca.Issue(t, "server")
+	config, err := auth.ServerTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer, ok := auth.PeerFromContext(r.Context())
+		require.True(t, ok)
+
+		_, _ = fmt.Fprintf(w, "cert=%v worker=%v", peer.Certificate != nil, peer.HasURI("https://worker0"))
+	})))
+	srv.TLS = config
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, c *http.Client, url string) (int, string) {
+	rsp, err := c.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = rsp.Body.Close() }()
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	return rsp.StatusCode, string(body)
+}
+
+func TestAuthenticator(t *testing.T) {
+	ca := authtest.NewCA(t)
+	srv := newServer(t, ca, auth.NewAuthenticator("secret"))
+
+	certFile, keyFile := ca.Issue(t, "worker0", "https://worker0")
+	workerTLS, err := auth.ClientTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+
+	code, body := get(t, auth.NewHTTPClient(workerTLS, ""), srv.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "cert=true worker=true", body)
+
+	clientTLS, err := auth.ClientTLS("", "", ca.CertFile)
+	require.NoError(t, err)
+
+	code, body = get(t, auth.NewHTTPClient(clientTLS, "secret"), srv.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "cert=false worker=false", body)
+
+	code, _ = get(t, auth.NewHTTPClient(clientTLS, "wrong"), srv.URL)
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = get(t, auth.NewHTTPClient(clientTLS, ""), srv.URL)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestUnknownCA(t *testing.T) {
+	ca := authtest.NewCA(t)
+	srv := newServer(t, ca, auth.NewAuthenticator())
+
+	other := authtest.NewCA(t)
+	certFile, keyFile := other.Issue(t, "worker0", "https://worker0")
+
+	// Сертификат чужого CA не проходит TLS handshake.
+	config, err := auth.ClientTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+
+	_, err = auth.NewHTTPClient(config, "").Get(srv.URL)
+	require.Error(t, err)
+
+	// Клиент не доверяет серверу, подписанному чужим CA.
+	config, err = auth.ClientTLS("", "", other.CertFile)
+	require.NoError(t, err)
+
+	_, err = auth.NewHTTPClient(config, "").Get(srv.URL)
+	require.Error(t, err)
+}
+
+func TestReadTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# ci\nfirst\n\n  second  \n"), 0600))
+
+	tokens, err := auth.ReadTokens(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, tokens)
+
+	require.NoError(t, os.WriteFile(path, []byte("# empty\n"), 0600))
+	_, err = auth.ReadTokens(path)
+	require.Error(t, err)
+}
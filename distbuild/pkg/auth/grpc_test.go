@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth/authtest"
+)
+
+// newGRPCServer запускает gRPC сервер со службой health. Последний интерсептор записывает в peers
+// Peer из контекста так же, как newServer пишет его в ответ.
+func newGRPCServer(t *testing.T, ca *authtest.CA, a *auth.Authenticator, peers chan<- string) string {
+	certFile, keyFile := ca.Issue(t, "server")
+	config, err := auth.ServerTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+
+	record := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		peer, ok := auth.PeerFromContext(ctx)
+		require.True(t, ok)
+
+		peers <- fmt.Sprintf("cert=%v worker=%v", peer.Certificate != nil, peer.HasURI("https://worker0"))
+		return handler(ctx, req)
+	}
+
+	s := grpc.NewServer(append(a.ServerOptions(config), grpc.ChainUnaryInterceptor(record))...)
+	grpc_health_v1.RegisterHealthServer(s, health.NewServer())
+
+	lsn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = s.Serve(lsn) }()
+	t.Cleanup(s.Stop)
+
+	return lsn.Addr().String()
+}
+
+func check(t *testing.T, addr string, config *tls.Config, token string) error {
+	cc, err := grpc.Dial(addr, auth.DialOptions(config, token)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cc.Close() }()
+
+	_, err = grpc_health_v1.NewHealthClient(cc).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestAuthenticatorGRPC(t *testing.T) {
+	ca := authtest.NewCA(t)
+	peers := make(chan string, 1)
+	addr := newGRPCServer(t, ca, auth.NewAuthenticator("secret"), peers)
+
+	certFile, keyFile := ca.Issue(t, "worker0", "https://worker0")
+	workerTLS, err := auth.ClientTLS(certFile, keyFile, ca.CertFile)
+	require.NoError(t, err)
+
+	require.NoError(t, check(t, addr, workerTLS, ""))
+	assert.Equal(t, "cert=true worker=true", <-peers)
+
+	clientTLS, err := auth.ClientTLS("", "", ca.CertFile)
+	require.NoError(t, err)
+
+	require.NoError(t, check(t, addr, clientTLS, "secret"))
+	assert.Equal(t, "cert=false worker=false", <-peers)
+
+	err = check(t, addr, clientTLS, "wrong")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	err = check(t, addr, clientTLS, "")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// gRPC отказывается отправлять токен по соединению без TLS.
+	require.ErrorContains(t, check(t, addr, nil, "secret"), "transport level security")
+}
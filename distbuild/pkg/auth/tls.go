@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ServerTLS загружает сертификат сервера из certFile и keyFile и CA из caFile, которым
+// подписаны клиентские сертификаты.
+//
+// Клиентский сертификат необязателен на уровне TLS, потому что клиенты сборки аутентифицируются
+// токеном. Запросы без сертификата и без токена отклоняет Authenticator.
+func ServerTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := loadCA(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLS загружает CA из caFile, которым проверяются сертификаты серверов, и клиентский сертификат
+// из certFile и keyFile. Если certFile пустой, клиент не предъявляет сертификат.
+func ClientTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	pool, err := loadCA(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func loadCA(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", caFile)
+	}
+	return pool, nil
+}
+
+// NewHTTPClient создаёт http.Client с TLS конфигурацией config. Если token не пустой,
+// клиент добавляет его во все запросы в заголовке Authorization.
+func NewHTTPClient(config *tls.Config, token string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	if token == "" {
+		return &http.Client{Transport: transport}
+	}
+	return &http.Client{Transport: &tokenTransport{token: token, next: transport}}
+}
+
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
+
+// ReadTokens читает токены из файла, по одному в строке. Пустые строки и строки,
+// которые начинаются с #, пропускаются.
+func ReadTokens(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var tokens []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s: no tokens found", path)
+	}
+	return tokens, nil
+}
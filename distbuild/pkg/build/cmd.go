@@ -10,39 +10,86 @@ type JobContext struct {
 	SourceDir string
 	OutputDir string
 	Deps      map[ID]string
+
+	// Tools сопоставляет именам инструментов воркера их пути, которые подставляет {{.Tool "<name>"}}.
+	Tools map[string]string
+
+	// canonical заставляет {{.Tool "<name>"}} возвращать канонический путь любого инструмента (см. JobID).
+	canonical bool
 }
 
-// Render replaces variable references with their real value.
-func (c *Cmd) Render(ctx JobContext) (*Cmd, error) {
-	var errs []error
+// templateContext - значение, которое видят шаблоны: ключи Deps в нём строки, чтобы
+// к ним можно было обратиться через index.
+type templateContext struct {
+	SourceDir string
+	OutputDir string
+	Deps      map[string]string
+
+	tools     map[string]string
+	canonical bool
+}
 
-	var fixedCtx struct {
-		SourceDir string
-		OutputDir string
-		Deps      map[string]string
+// Tool возвращает путь до инструмента name на воркере.
+func (ctx *templateContext) Tool(name string) (string, error) {
+	if ctx.canonical {
+		return canonicalToolPrefix + name, nil
+	}
+
+	path, ok := ctx.tools[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownTool, name)
+	}
+	return path, nil
+}
+
+func (ctx JobContext) templateContext() *templateContext {
+	fixedCtx := &templateContext{
+		SourceDir: ctx.SourceDir,
+		OutputDir: ctx.OutputDir,
+		Deps:      map[string]string{},
+		tools:     ctx.Tools,
+		canonical: ctx.canonical,
 	}
-	fixedCtx.SourceDir = ctx.SourceDir
-	fixedCtx.OutputDir = ctx.OutputDir
-	fixedCtx.Deps = map[string]string{}
 
 	for k, v := range ctx.Deps {
 		fixedCtx.Deps[k.String()] = v
 	}
+	return fixedCtx
+}
+
+func renderTemplate(str string, fixedCtx *templateContext) (string, error) {
+	t, err := template.New("").Parse(str)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, fixedCtx); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// RenderTemplate рендерит str так же, как Render рендерит поля команды.
+func (ctx JobContext) RenderTemplate(str string) (string, error) {
+	return renderTemplate(str, ctx.templateContext())
+}
+
+// Render replaces variable references with their real value.
+func (c *Cmd) Render(ctx JobContext) (*Cmd, error) {
+	var errs []error
+
+	fixedCtx := ctx.templateContext()
 
 	render := func(str string) string {
-		t, err := template.New("").Parse(str)
+		result, err := renderTemplate(str, fixedCtx)
 		if err != nil {
 			errs = append(errs, err)
 			return ""
 		}
 
-		var b strings.Builder
-		if err := t.Execute(&b, fixedCtx); err != nil {
-			errs = append(errs, err)
-			return ""
-		}
-
-		return b.String()
+		return result
 	}
 
 	renderList := func(l []string) []string {
@@ -60,6 +107,13 @@ func (c *Cmd) Render(ctx JobContext) (*Cmd, error) {
 	rendered.WorkingDirectory = render(c.WorkingDirectory)
 	rendered.Exec = renderList(c.Exec)
 	rendered.Environ = renderList(c.Environ)
+	rendered.CopyFrom = render(c.CopyFrom)
+	rendered.CopyTo = render(c.CopyTo)
+	rendered.SymlinkTarget = render(c.SymlinkTarget)
+	rendered.SymlinkPath = render(c.SymlinkPath)
+	rendered.MkdirPath = render(c.MkdirPath)
+	rendered.WriteFrom = render(c.WriteFrom)
+	rendered.WriteOutput = render(c.WriteOutput)
 
 	if len(errs) != 0 {
 		return nil, fmt.Errorf("error rendering cmd: %w", errs[0])
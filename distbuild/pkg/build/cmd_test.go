@@ -29,3 +29,69 @@ func TestCmdRender(t *testing.T) {
 
 	require.Equal(t, expected, result)
 }
+
+func TestCmdRenderBuiltin(t *testing.T) {
+	tmpl := Cmd{
+		CopyFrom:      `{{index .Deps "6100000000000000000000000000000000000000"}}/bin`,
+		CopyTo:        "{{.OutputDir}}/bin",
+		SymlinkTarget: "bin/tool",
+		SymlinkPath:   "{{.OutputDir}}/tool",
+		MkdirPath:     "{{.OutputDir}}/lib",
+		WriteFrom:     "{{.SourceDir}}/config.tmpl",
+		WriteOutput:   "{{.OutputDir}}/config",
+	}
+
+	ctx := JobContext{
+		SourceDir: "/distbuild/src",
+		OutputDir: "/distbuild/jobs/b",
+		Deps: map[ID]string{
+			{'a'}: "/distbuild/jobs/a",
+		},
+	}
+
+	result, err := tmpl.Render(ctx)
+	require.NoError(t, err)
+
+	expected := &Cmd{
+		CopyFrom:      "/distbuild/jobs/a/bin",
+		CopyTo:        "/distbuild/jobs/b/bin",
+		SymlinkTarget: "bin/tool",
+		SymlinkPath:   "/distbuild/jobs/b/tool",
+		MkdirPath:     "/distbuild/jobs/b/lib",
+		WriteFrom:     "/distbuild/src/config.tmpl",
+		WriteOutput:   "/distbuild/jobs/b/config",
+	}
+
+	require.Equal(t, expected, result)
+
+	content, err := ctx.RenderTemplate(`lib={{index .Deps "6100000000000000000000000000000000000000"}}/lib.a`)
+	require.NoError(t, err)
+	require.Equal(t, "lib=/distbuild/jobs/a/lib.a", content)
+}
+
+func TestCmdRenderTool(t *testing.T) {
+	tmpl := Cmd{
+		Exec:    []string{`{{.Tool "GOROOT"}}/bin/go`, "build"},
+		Environ: []string{`GOCACHE={{.Tool "GOCACHE"}}`},
+	}
+
+	ctx := JobContext{
+		Tools: map[string]string{
+			"GOROOT":  "/usr/local/go",
+			"GOCACHE": "/var/cache/go-build",
+		},
+	}
+
+	result, err := tmpl.Render(ctx)
+	require.NoError(t, err)
+
+	expected := &Cmd{
+		Exec:    []string{"/usr/local/go/bin/go", "build"},
+		Environ: []string{"GOCACHE=/var/cache/go-build"},
+	}
+	require.Equal(t, expected, result)
+
+	unknown := Cmd{Exec: []string{`{{.Tool "GOPATH"}}/bin/tool`}}
+	_, err = unknown.Render(ctx)
+	require.ErrorIs(t, err, ErrUnknownTool)
+}
@@ -0,0 +1,49 @@
+package build
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Expand добавляет в граф джобы фрагмента, который записал джоб generator (см. Job.Fragment).
+//
+// Каждый джоб фрагмента зависит от generator. Если ID джоба не задан, он вычисляется через JobID;
+// явный ID нужен только джобам, от которых зависят другие джобы фрагмента. Фрагмент не может добавлять
+// исходные файлы: его джобы читают файлы из g.SourceFiles и выходы зависимостей.
+//
+// Expand возвращает добавленные джобы. Если с ними граф нельзя исполнить, граф не меняется,
+// а ошибка оборачивает ErrInvalidFragment и ошибки Validate.
+func (g *Graph) Expand(generator ID, fragment *Graph) ([]Job, error) {
+	if len(fragment.SourceFiles) != 0 {
+		return nil, fmt.Errorf("%w: fragment can't add source files", ErrInvalidFragment)
+	}
+
+	added := make([]Job, 0, len(fragment.Jobs))
+	for _, job := range fragment.Jobs {
+		if !slices.Contains(job.Deps, generator) {
+			job.Deps = append(slices.Clip(job.Deps), generator)
+		}
+
+		if job.ID == (ID{}) {
+			id, err := g.JobID(&job)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidFragment, err)
+			}
+			job.ID = id
+		}
+
+		added = append(added, job)
+	}
+
+	expanded := Graph{
+		SourceFiles: g.SourceFiles,
+		Jobs:        append(slices.Clip(g.Jobs), added...),
+	}
+
+	if err := expanded.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFragment, err)
+	}
+
+	g.Jobs = expanded.Jobs
+	return added, nil
+}
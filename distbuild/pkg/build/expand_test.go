@@ -0,0 +1,67 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	g := &Graph{
+		SourceFiles: map[ID]string{{'f'}: "a.go"},
+		Jobs: []Job{
+			{ID: ID{'g'}, Name: "gen", Fragment: "graph.json"},
+		},
+	}
+
+	added, err := g.Expand(ID{'g'}, &Graph{Jobs: []Job{
+		{ID: ID{'a'}, Name: "a", Inputs: []string{"a.go"}},
+		{Name: "b", Deps: []ID{{'a'}}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, added, 2)
+
+	assert.Equal(t, ID{'a'}, added[0].ID)
+	assert.Equal(t, []ID{{'g'}}, added[0].Deps)
+	assert.Equal(t, []ID{{'a'}, {'g'}}, added[1].Deps)
+
+	id, err := g.JobID(&added[1])
+	require.NoError(t, err)
+	assert.Equal(t, id, added[1].ID)
+
+	assert.Equal(t, append([]Job{{ID: ID{'g'}, Name: "gen", Fragment: "graph.json"}}, added...), g.Jobs)
+}
+
+func TestExpandInvalid(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		fragment Graph
+		msg      string
+	}{
+		{
+			name:     "source files",
+			fragment: Graph{SourceFiles: map[ID]string{{'f'}: "b.go"}},
+			msg:      "invalid graph fragment: fragment can't add source files",
+		},
+		{
+			name:     "missing input",
+			fragment: Graph{Jobs: []Job{{Name: "a", Inputs: []string{"b.go"}}}},
+			msg:      `invalid graph fragment: job "a": input is missing from source files: "b.go"`,
+		},
+		{
+			name:     "duplicate",
+			fragment: Graph{Jobs: []Job{{ID: ID{'g'}, Name: "a"}}},
+			msg:      `invalid graph fragment: duplicate job id 6700000000000000000000000000000000000000: jobs "gen" and "a"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			g := &Graph{Jobs: []Job{{ID: ID{'g'}, Name: "gen"}}}
+
+			_, err := g.Expand(ID{'g'}, &test.fragment)
+			require.ErrorIs(t, err, ErrInvalidFragment)
+			require.EqualError(t, err, test.msg)
+			assert.Len(t, g.Jobs, 1, "graph must not be modified")
+		})
+	}
+}
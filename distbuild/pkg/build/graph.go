@@ -8,6 +8,8 @@ type Job struct {
 	//
 	// Выход джоба целиком определяется его ID. Это важное свойство позволяет кешировать
 	// результаты сборки.
+	//
+	// Такой ID вычисляет функция JobID.
 	ID ID
 
 	// Name задаёт человекочитаемое имя джоба.
@@ -29,6 +31,36 @@ type Job struct {
 
 	// Cmds описывает список команд, которые нужно выполнить в рамках этого джоба.
 	Cmds []Cmd
+
+	// Resources задаёт ресурсы, которые нужны джобу на воркере.
+	//
+	// Resources не влияет на выход джоба и не попадает в ID.
+	Resources Resources
+
+	// Test делает джоб тестовым: координатор может разбить его на шарды и перезапустить упавшие шарды.
+	//
+	// Test не влияет на выход джоба и не попадает в ID.
+	Test *Test `json:",omitempty"`
+
+	// Fragment задаёт путь внутри {{.OutputDir}}, по которому джоб записывает фрагмент графа - Graph
+	// в JSON. После успешного завершения джоба координатор добавляет джобы фрагмента в сборку (см. Graph.Expand).
+	// Так шаг сборки может объявить джобы, которые становятся известны только во время сборки.
+	//
+	// Fragment не влияет на выход джоба и не попадает в ID.
+	Fragment string `json:",omitempty"`
+}
+
+// Test описывает тестовый джоб. Последняя команда тестового джоба должна запускать тестовый бинарь Go,
+// потому что координатор передаёт ей флаг -test.run.
+type Test struct {
+	// Names перечисляет тесты, примеры и fuzz тесты верхнего уровня. По ним джоб делится на шарды.
+	Names []string `json:",omitempty"`
+
+	// Shards задаёт, на сколько шардов разбить джоб. Шардов не бывает больше, чем тестов в Names.
+	Shards int `json:",omitempty"`
+
+	// Retries задаёт, сколько раз перезапускать упавший шард.
+	Retries int `json:",omitempty"`
 }
 
 // Cmd описывает одну команду сборки.
@@ -36,8 +68,15 @@ type Job struct {
 // Есть несколько видов команд. Все виды команд описываются одной структурой.
 // Реальный тип определяется тем, какие поля структуры заполнены.
 //
-//	exec - выполняет произвольную команду
-//	cat  - записывает строку в файл
+//	exec    - выполняет произвольную команду (Exec, Environ, WorkingDirectory)
+//	cat     - записывает строку CatTemplate в файл CatOutput
+//	copy    - рекурсивно копирует CopyFrom в CopyTo
+//	symlink - создаёт по пути SymlinkPath символическую ссылку на SymlinkTarget
+//	mkdir   - создаёт директорию MkdirPath вместе с родительскими
+//	write   - рендерит содержимое файла WriteFrom как шаблон и записывает его в WriteOutput
+//
+// Команды cat, copy, symlink, mkdir и write встроенные: воркер выполняет их сам, без запуска процесса.
+// В одной команде могут быть заполнены поля только одного вида.
 //
 // Все строки в описании команды могут содержать в себе ссылки на контекстные переменные. Перед выполнением
 // реальной команды, переменные заменяются на их реальные значения.
@@ -46,6 +85,9 @@ type Job struct {
 //	{{.SourceDir}} - абсолютный путь до директории с исходными файлами.
 //	{{index .Deps "f374b81d81f641c8c3d5d5468081ef83b2c7dae9"}} - абсолютный путь до директории,
 //	содержащей выход джоба с id f374b81d81f641c8c3d5d5468081ef83b2c7dae9.
+//	{{.Tool "GOROOT"}} - абсолютный путь до инструмента GOROOT, который задал воркер.
+//
+// Пути зависят от воркера, поэтому в ID джоба они попадают в каноническом виде.
 type Cmd struct {
 	// Exec описывает команду, которую нужно выполнить.
 	Exec []string
@@ -61,6 +103,24 @@ type Cmd struct {
 
 	// CatOutput задаёт выходной файл для команды типа cat.
 	CatOutput string
+
+	// CopyFrom и CopyTo задают встроенную команду copy: файл, символическая ссылка или директория
+	// CopyFrom рекурсивно копируется по пути CopyTo с сохранением прав. Ссылки копируются как ссылки.
+	CopyFrom string
+	CopyTo   string
+
+	// SymlinkTarget и SymlinkPath задают встроенную команду symlink: по пути SymlinkPath создаётся
+	// символическая ссылка на SymlinkTarget.
+	SymlinkTarget string
+	SymlinkPath   string
+
+	// MkdirPath задаёт встроенную команду mkdir: директория создаётся вместе с родительскими.
+	MkdirPath string
+
+	// WriteFrom и WriteOutput задают встроенную команду write: содержимое файла WriteFrom, обычно
+	// из выходной директории зависимости, рендерится как шаблон, так же как CatTemplate, и записывается в WriteOutput.
+	WriteFrom   string
+	WriteOutput string
 }
 
 type Graph struct {
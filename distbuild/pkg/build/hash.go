@@ -0,0 +1,145 @@
+package build
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+)
+
+// Значения, которые подставляются вместо путей при вычислении ID.
+//
+// Пути до директорий и инструментов зависят от машины, на которой запускается джоб, поэтому в ID они не попадают.
+const (
+	canonicalSourceDir  = "\x00source"
+	canonicalOutputDir  = "\x00output"
+	canonicalDepPrefix  = "\x00dep/"
+	canonicalToolPrefix = "\x00tool/"
+)
+
+// hashVersion меняется при любом изменении формата, чтобы старые артефакты не попадали в кеш.
+const hashVersion = "distbuild job v2"
+
+type hasher struct {
+	h hash.Hash
+}
+
+func (h *hasher) writeString(s string) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(s)))
+	_, _ = h.h.Write(buf[:n])
+	_, _ = h.h.Write([]byte(s))
+}
+
+func (h *hasher) writeList(l []string) {
+	h.writeString(fmt.Sprint(len(l)))
+	for _, s := range l {
+		h.writeString(s)
+	}
+}
+
+// JobID вычисляет ID джоба из его содержимого.
+//
+// В хеш попадают команды, отрендеренные с каноническими путями директорий и инструментов,
+// их окружение, пути и ID входных файлов и ID зависимостей. Name в хеш не попадает.
+// files сопоставляет путям из job.Inputs ID файлов из Graph.SourceFiles.
+func JobID(job *Job, files map[string]ID) (ID, error) {
+	ctx := JobContext{
+		SourceDir: canonicalSourceDir,
+		OutputDir: canonicalOutputDir,
+		Deps:      make(map[ID]string, len(job.Deps)),
+		canonical: true,
+	}
+
+	deps := make([]ID, 0, len(job.Deps))
+	for _, dep := range job.Deps {
+		ctx.Deps[dep] = canonicalDepPrefix + dep.String()
+		deps = append(deps, dep)
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].String() < deps[j].String()
+	})
+
+	inputs := append([]string{}, job.Inputs...)
+	sort.Strings(inputs)
+
+	h := &hasher{h: sha1.New()}
+	h.writeString(hashVersion)
+
+	h.writeString(fmt.Sprint(len(inputs)))
+	for _, input := range inputs {
+		id, ok := files[input]
+		if !ok {
+			return ID{}, fmt.Errorf("job %q: %w: %q", job.Name, ErrMissingInput, input)
+		}
+
+		h.writeString(input)
+		h.writeString(id.String())
+	}
+
+	h.writeString(fmt.Sprint(len(deps)))
+	for _, dep := range deps {
+		h.writeString(dep.String())
+	}
+
+	h.writeString(fmt.Sprint(len(job.Cmds)))
+	for i := range job.Cmds {
+		cmd, err := job.Cmds[i].Render(ctx)
+		if err != nil {
+			return ID{}, fmt.Errorf("job %q: %w", job.Name, err)
+		}
+
+		h.writeList(cmd.Exec)
+		h.writeList(cmd.Environ)
+		h.writeString(cmd.WorkingDirectory)
+		h.writeString(cmd.CatTemplate)
+		h.writeString(cmd.CatOutput)
+		h.writeString(cmd.CopyFrom)
+		h.writeString(cmd.CopyTo)
+		h.writeString(cmd.SymlinkTarget)
+		h.writeString(cmd.SymlinkPath)
+		h.writeString(cmd.MkdirPath)
+		h.writeString(cmd.WriteFrom)
+		h.writeString(cmd.WriteOutput)
+	}
+
+	var id ID
+	copy(id[:], h.h.Sum(nil))
+	return id, nil
+}
+
+// JobID вычисляет ID джоба графа. Входы джоба ищутся в g.SourceFiles.
+func (g *Graph) JobID(job *Job) (ID, error) {
+	files := make(map[string]ID, len(g.SourceFiles))
+	for id, path := range g.SourceFiles {
+		files[path] = id
+	}
+
+	return JobID(job, files)
+}
+
+// NewFileHash возвращает хеш, который после записи в него содержимого файла path
+// возвращает из Sum ID этого файла.
+//
+// path - путь файла относительно корня сборки в том виде, в котором он записан в Graph.SourceFiles.
+// Путь входит в хеш, потому что файлы с одинаковым содержимым по разным путям - разные входы.
+func NewFileHash(path string) hash.Hash {
+	h := sha1.New()
+	_, _ = fmt.Fprintf(h, "%s\x00", path)
+	return h
+}
+
+// FileID вычисляет ID исходного файла path с содержимым r.
+func FileID(path string, r io.Reader) (ID, error) {
+	h := NewFileHash(path)
+	if _, err := io.Copy(h, r); err != nil {
+		return ID{}, err
+	}
+
+	var id ID
+	copy(id[:], h.Sum(nil))
+	return id, nil
+}
@@ -0,0 +1,128 @@
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobID(t *testing.T) {
+	files := map[string]ID{
+		"a.go": {'f', 'a'},
+		"b.go": {'f', 'b'},
+	}
+
+	base := Job{
+		Name:   "compile",
+		Inputs: []string{"a.go", "b.go"},
+		Deps:   []ID{{'a'}, {'b'}},
+		Cmds: []Cmd{
+			{
+				Exec:    []string{"go", "tool", "compile", "-o", "{{.OutputDir}}/pkg.a", "{{.SourceDir}}/a.go"},
+				Environ: []string{"GOOS=linux"},
+			},
+			{
+				CatTemplate: `{{index .Deps "6100000000000000000000000000000000000000"}}/pkg.a`,
+				CatOutput:   "{{.OutputDir}}/importcfg",
+			},
+		},
+	}
+
+	id, err := JobID(&base, files)
+	require.NoError(t, err)
+
+	same := base
+	same.Name = "other name"
+	same.Inputs = []string{"b.go", "a.go"}
+	same.Deps = []ID{{'b'}, {'a'}}
+	same.Cmds = append([]Cmd{}, base.Cmds...)
+	same.Cmds[0].Exec = []string{"go", "tool", "compile", "-o", "{{ .OutputDir }}/pkg.a", "{{ .SourceDir }}/a.go"}
+
+	sameID, err := JobID(&same, files)
+	require.NoError(t, err)
+	require.Equal(t, id, sameID)
+
+	for name, change := range map[string]func(j *Job){
+		"environ": func(j *Job) { j.Cmds[0].Environ = []string{"GOOS=darwin"} },
+		"exec":    func(j *Job) { j.Cmds[0].Exec = []string{"go", "tool", "compile"} },
+		"cat":     func(j *Job) { j.Cmds[1].CatTemplate = "" },
+		"deps":    func(j *Job) { j.Deps = []ID{{'a'}} },
+		"inputs":  func(j *Job) { j.Inputs = []string{"a.go"} },
+		"cmds":    func(j *Job) { j.Cmds = j.Cmds[:1] },
+		"tool":    func(j *Job) { j.Cmds[0].Environ = []string{`GOOS={{.Tool "GOOS"}}`} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			changed := base
+			changed.Cmds = append([]Cmd{}, base.Cmds...)
+			change(&changed)
+
+			changedID, err := JobID(&changed, files)
+			require.NoError(t, err)
+			require.NotEqual(t, id, changedID)
+		})
+	}
+
+	t.Run("builtin", func(t *testing.T) {
+		ids := map[ID]struct{}{id: {}}
+		for _, cmd := range []Cmd{
+			{CopyFrom: "{{.OutputDir}}/pkg.a", CopyTo: "{{.OutputDir}}/lib.a"},
+			{CopyFrom: "{{.OutputDir}}/pkg.a", CopyTo: "{{.OutputDir}}/lib.o"},
+			{SymlinkTarget: "pkg.a", SymlinkPath: "{{.OutputDir}}/lib.a"},
+			{MkdirPath: "{{.OutputDir}}/lib.a"},
+			{WriteFrom: "{{.OutputDir}}/pkg.a", WriteOutput: "{{.OutputDir}}/lib.a"},
+		} {
+			changed := base
+			changed.Cmds = append(append([]Cmd{}, base.Cmds...), cmd)
+
+			changedID, err := JobID(&changed, files)
+			require.NoError(t, err)
+			require.NotContains(t, ids, changedID)
+			ids[changedID] = struct{}{}
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		changedID, err := JobID(&base, map[string]ID{"a.go": {'f', 'a'}, "b.go": {'f', 'c'}})
+		require.NoError(t, err)
+		require.NotEqual(t, id, changedID)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := JobID(&base, map[string]ID{"a.go": {'f', 'a'}})
+		require.ErrorIs(t, err, ErrMissingInput)
+	})
+}
+
+func TestGraphJobID(t *testing.T) {
+	g := Graph{
+		SourceFiles: map[ID]string{{'f'}: "a.go"},
+		Jobs: []Job{
+			{Inputs: []string{"a.go"}, Cmds: []Cmd{{Exec: []string{"true"}}}},
+		},
+	}
+
+	id, err := g.JobID(&g.Jobs[0])
+	require.NoError(t, err)
+
+	expected, err := JobID(&g.Jobs[0], map[string]ID{"a.go": {'f'}})
+	require.NoError(t, err)
+	require.Equal(t, expected, id)
+}
+
+func TestFileID(t *testing.T) {
+	id, err := FileID("a.txt", strings.NewReader("foo"))
+	require.NoError(t, err)
+
+	same, err := FileID("a.txt", strings.NewReader("foo"))
+	require.NoError(t, err)
+	require.Equal(t, id, same)
+
+	otherPath, err := FileID("b.txt", strings.NewReader("foo"))
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherPath)
+
+	otherContent, err := FileID("a.txt", strings.NewReader("bar"))
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherContent)
+}
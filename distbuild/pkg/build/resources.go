@@ -0,0 +1,30 @@
+package build
+
+// DefaultMilliCPU - процессорное время, которое получает джоб без явного Resources.MilliCPU.
+const DefaultMilliCPU = 1000
+
+// Resources описывает процессорное время и память.
+type Resources struct {
+	// MilliCPU задаёт процессорное время в тысячных долях ядра.
+	MilliCPU int64 `json:",omitempty"`
+
+	// Memory задаёт память в байтах.
+	Memory int64 `json:",omitempty"`
+}
+
+func (r Resources) Add(o Resources) Resources {
+	return Resources{MilliCPU: r.MilliCPU + o.MilliCPU, Memory: r.Memory + o.Memory}
+}
+
+func (r Resources) Sub(o Resources) Resources {
+	return Resources{MilliCPU: r.MilliCPU - o.MilliCPU, Memory: r.Memory - o.Memory}
+}
+
+// Requests возвращает ресурсы, которые нужно зарезервировать под джоб.
+func (j *Job) Requests() Resources {
+	r := j.Resources
+	if r.MilliCPU == 0 {
+		r.MilliCPU = DefaultMilliCPU
+	}
+	return r
+}
@@ -0,0 +1,77 @@
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TestShards делит тестовый джоб на шарды. i-й шард из n запускает тесты Test.Names с номерами
+// i, i+n, i+2n и так далее, а его ID вычисляется через JobID.
+//
+// Если job не тестовый или делить его не на что, TestShards возвращает единственный шард - сам job.
+func (g *Graph) TestShards(job *Job) ([]Job, error) {
+	if !job.sharded() {
+		return []Job{*job}, nil
+	}
+
+	n := min(job.Test.Shards, len(job.Test.Names))
+
+	names := slices.Clone(job.Test.Names)
+	slices.Sort(names)
+
+	shards := make([]Job, n)
+	for i := range shards {
+		var run []string
+		for j := i; j < len(names); j += n {
+			run = append(run, names[j])
+		}
+
+		shard := *job
+		shard.Name = fmt.Sprintf("%s [shard %d/%d]", job.Name, i+1, n)
+		shard.Test = &Test{Names: run}
+
+		shard.Cmds = slices.Clone(job.Cmds)
+		last := &shard.Cmds[len(shard.Cmds)-1]
+		last.Exec = append(slices.Clip(last.Exec), "-test.run=^(?:"+strings.Join(run, "|")+")$")
+
+		id, err := g.JobID(&shard)
+		if err != nil {
+			return nil, err
+		}
+		shard.ID = id
+
+		shards[i] = shard
+	}
+
+	return shards, nil
+}
+
+func (job *Job) sharded() bool {
+	return job.Test != nil && len(job.Cmds) != 0 && min(job.Test.Shards, len(job.Test.Names)) > 1
+}
+
+// FailedTests возвращает тесты, которые упали по выводу тестового бинаря Go: строки вида
+// "--- FAIL: TestName (0.00s)", в том числе для подтестов.
+func FailedTests(stdout []byte) []string {
+	var failed []string
+
+	s := bufio.NewScanner(bytes.NewReader(stdout))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		name, ok := strings.CutPrefix(line, "--- FAIL: ")
+		if !ok {
+			continue
+		}
+
+		name, _, _ = strings.Cut(name, " ")
+		if !slices.Contains(failed, name) {
+			failed = append(failed, name)
+		}
+	}
+
+	return failed
+}
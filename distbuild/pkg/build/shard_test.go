@@ -0,0 +1,65 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestShards(t *testing.T) {
+	g := &Graph{SourceFiles: map[ID]string{{'f'}: "a_test.go"}}
+
+	job := Job{
+		ID:     ID{'t'},
+		Name:   "test a",
+		Inputs: []string{"a_test.go"},
+		Cmds: []Cmd{
+			{CatTemplate: "x", CatOutput: "{{.OutputDir}}/x"},
+			{Exec: []string{"a.test", "-test.v"}},
+		},
+		Test: &Test{Names: []string{"TestC", "TestA", "ExampleB", "TestD"}, Shards: 3, Retries: 1},
+	}
+
+	shards, err := g.TestShards(&job)
+	require.NoError(t, err)
+	require.Len(t, shards, 3)
+
+	assert.Equal(t, "test a [shard 1/3]", shards[0].Name)
+	assert.Equal(t, []string{"a.test", "-test.v", "-test.run=^(?:ExampleB|TestD)$"}, shards[0].Cmds[1].Exec)
+	assert.Equal(t, []string{"a.test", "-test.v", "-test.run=^(?:TestA)$"}, shards[1].Cmds[1].Exec)
+	assert.Equal(t, []string{"a.test", "-test.v", "-test.run=^(?:TestC)$"}, shards[2].Cmds[1].Exec)
+	assert.Equal(t, []string{"a.test", "-test.v"}, job.Cmds[1].Exec, "job must not be modified")
+
+	ids := map[ID]bool{job.ID: true}
+	for _, shard := range shards {
+		assert.False(t, ids[shard.ID], "shard ids must be unique")
+		ids[shard.ID] = true
+
+		id, err := g.JobID(&shard)
+		require.NoError(t, err)
+		assert.Equal(t, id, shard.ID)
+	}
+
+	job.Test.Shards = 1
+	shards, err = g.TestShards(&job)
+	require.NoError(t, err)
+	assert.Equal(t, []Job{job}, shards)
+}
+
+func TestFailedTests(t *testing.T) {
+	stdout := []byte(`=== RUN   TestA
+--- PASS: TestA (0.00s)
+=== RUN   TestB
+    b_test.go:10: boom
+--- FAIL: TestB (0.01s)
+=== RUN   TestC
+=== RUN   TestC/sub
+    --- FAIL: TestC/sub (0.00s)
+--- FAIL: TestC (0.00s)
+FAIL
+`)
+
+	assert.Equal(t, []string{"TestB", "TestC/sub", "TestC"}, FailedTests(stdout))
+	assert.Empty(t, FailedTests([]byte("PASS\n")))
+}
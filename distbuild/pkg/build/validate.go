@@ -0,0 +1,178 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+var (
+	ErrDuplicateID  = errors.New("duplicate job id")
+	ErrUnknownDep   = errors.New("unknown dependency")
+	ErrMissingInput = errors.New("input is missing from source files")
+	ErrCycle        = errors.New("dependency cycle")
+	ErrShardedDep   = errors.New("dependency on sharded test job")
+	ErrInvalidCmd   = errors.New("invalid command")
+	ErrUnknownTool  = errors.New("unknown tool")
+
+	ErrInvalidFragment = errors.New("invalid graph fragment")
+)
+
+// Validate проверяет, что граф можно исполнить.
+//
+// Validate находит все проблемы сразу и возвращает их через errors.Join. Каждую ошибку можно
+// проверить через errors.Is на ErrDuplicateID, ErrUnknownDep, ErrMissingInput, ErrCycle, ErrShardedDep,
+// ErrInvalidCmd и ErrInvalidFragment.
+//
+// У тестового джоба, который делится на шарды, нет своего артефакта, поэтому от него нельзя зависеть
+// и он не может записать фрагмент графа.
+func (g *Graph) Validate() error {
+	var errs []error
+
+	files := make(map[string]struct{}, len(g.SourceFiles))
+	for _, path := range g.SourceFiles {
+		files[path] = struct{}{}
+	}
+
+	jobs := make(map[ID]*Job, len(g.Jobs))
+	for i := range g.Jobs {
+		job := &g.Jobs[i]
+		if prev, ok := jobs[job.ID]; ok {
+			errs = append(errs, fmt.Errorf("%w %s: jobs %q and %q", ErrDuplicateID, job.ID, prev.Name, job.Name))
+			continue
+		}
+		jobs[job.ID] = job
+	}
+
+	for i := range g.Jobs {
+		job := &g.Jobs[i]
+
+		for _, dep := range job.Deps {
+			if d, ok := jobs[dep]; !ok {
+				errs = append(errs, fmt.Errorf("job %q: %w %s", job.Name, ErrUnknownDep, dep))
+			} else if d.sharded() {
+				errs = append(errs, fmt.Errorf("job %q: %w %q", job.Name, ErrShardedDep, d.Name))
+			}
+		}
+
+		for _, input := range job.Inputs {
+			if _, ok := files[input]; !ok {
+				errs = append(errs, fmt.Errorf("job %q: %w: %q", job.Name, ErrMissingInput, input))
+			}
+		}
+
+		if job.Fragment != "" && (!fs.ValidPath(job.Fragment) || job.Fragment == ".") {
+			errs = append(errs, fmt.Errorf("job %q: %w: path %q is not inside output directory", job.Name, ErrInvalidFragment, job.Fragment))
+		} else if job.Fragment != "" && job.sharded() {
+			errs = append(errs, fmt.Errorf("job %q: %w: sharded test job has no output directory", job.Name, ErrInvalidFragment))
+		}
+
+		for j := range job.Cmds {
+			if err := job.Cmds[j].validate(); err != nil {
+				errs = append(errs, fmt.Errorf("job %q: command %d: %w", job.Name, j, err))
+			}
+		}
+	}
+
+	if cycle := findCycle(g.Jobs, jobs); cycle != nil {
+		names := make([]string, 0, len(cycle))
+		for _, job := range cycle {
+			names = append(names, fmt.Sprintf("%q", job.Name))
+		}
+		errs = append(errs, fmt.Errorf("%w: %s", ErrCycle, strings.Join(names, " -> ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validate проверяет, что команда относится не больше чем к одному виду и у встроенной
+// команды заданы все пути.
+func (c *Cmd) validate() error {
+	var kinds []string
+	if len(c.Exec) != 0 {
+		kinds = append(kinds, "exec")
+	}
+
+	builtins := []struct {
+		name  string
+		paths []string
+	}{
+		{"cat", []string{c.CatOutput}},
+		{"copy", []string{c.CopyFrom, c.CopyTo}},
+		{"symlink", []string{c.SymlinkTarget, c.SymlinkPath}},
+		{"mkdir", []string{c.MkdirPath}},
+		{"write", []string{c.WriteFrom, c.WriteOutput}},
+	}
+
+	for _, b := range builtins {
+		set := 0
+		for _, path := range b.paths {
+			if path != "" {
+				set++
+			}
+		}
+
+		switch set {
+		case 0:
+			continue
+		case len(b.paths):
+			kinds = append(kinds, b.name)
+		default:
+			return fmt.Errorf("%w: %s has empty paths", ErrInvalidCmd, b.name)
+		}
+	}
+
+	if len(kinds) > 1 {
+		return fmt.Errorf("%w: %s in one command", ErrInvalidCmd, strings.Join(kinds, " and "))
+	}
+	return nil
+}
+
+// findCycle возвращает один из циклов в графе зависимостей: первый и последний элементы совпадают.
+func findCycle(order []Job, jobs map[ID]*Job) []*Job {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	state := make(map[ID]int, len(jobs))
+	var stack []*Job
+
+	var visit func(job *Job) []*Job
+	visit = func(job *Job) []*Job {
+		switch state[job.ID] {
+		case done:
+			return nil
+		case inProgress:
+			for i, j := range stack {
+				if j.ID == job.ID {
+					return append(append([]*Job{}, stack[i:]...), job)
+				}
+			}
+		}
+
+		state[job.ID] = inProgress
+		stack = append(stack, job)
+
+		for _, dep := range job.Deps {
+			if next, ok := jobs[dep]; ok {
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[job.ID] = done
+		return nil
+	}
+
+	for i := range order {
+		if cycle := visit(jobs[order[i].ID]); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
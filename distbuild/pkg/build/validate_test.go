@@ -0,0 +1,129 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Graph{
+		SourceFiles: map[ID]string{{'f'}: "a.go"},
+		Jobs: []Job{
+			{ID: ID{'a'}, Name: "a", Inputs: []string{"a.go"}},
+			{ID: ID{'b'}, Name: "b", Deps: []ID{{'a'}}},
+		},
+	}
+	require.NoError(t, valid.Validate())
+
+	for _, test := range []struct {
+		name  string
+		graph Graph
+		err   error
+		msg   string
+	}{
+		{
+			name: "duplicate",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a"},
+				{ID: ID{'a'}, Name: "b"},
+			}},
+			err: ErrDuplicateID,
+			msg: `duplicate job id 6100000000000000000000000000000000000000: jobs "a" and "b"`,
+		},
+		{
+			name: "unknown dep",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Deps: []ID{{'b'}}},
+			}},
+			err: ErrUnknownDep,
+			msg: `job "a": unknown dependency 6200000000000000000000000000000000000000`,
+		},
+		{
+			name: "missing input",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Inputs: []string{"a.go"}},
+			}},
+			err: ErrMissingInput,
+			msg: `job "a": input is missing from source files: "a.go"`,
+		},
+		{
+			name: "cycle",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Deps: []ID{{'b'}}},
+				{ID: ID{'b'}, Name: "b", Deps: []ID{{'c'}}},
+				{ID: ID{'c'}, Name: "c", Deps: []ID{{'a'}}},
+				{ID: ID{'d'}, Name: "d", Deps: []ID{{'a'}}},
+			}},
+			err: ErrCycle,
+			msg: `dependency cycle: "a" -> "b" -> "c" -> "a"`,
+		},
+		{
+			name: "self loop",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Deps: []ID{{'a'}}},
+			}},
+			err: ErrCycle,
+			msg: `dependency cycle: "a" -> "a"`,
+		},
+		{
+			name: "sharded dep",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "test", Cmds: []Cmd{{Exec: []string{"a.test"}}}, Test: &Test{Names: []string{"TestA", "TestB"}, Shards: 2}},
+				{ID: ID{'b'}, Name: "b", Deps: []ID{{'a'}}},
+			}},
+			err: ErrShardedDep,
+			msg: `job "b": dependency on sharded test job "test"`,
+		},
+		{
+			name: "mixed cmd",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Cmds: []Cmd{{Exec: []string{"true"}, MkdirPath: "{{.OutputDir}}/bin"}}},
+			}},
+			err: ErrInvalidCmd,
+			msg: `job "a": command 0: invalid command: exec and mkdir in one command`,
+		},
+		{
+			name: "incomplete cmd",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Cmds: []Cmd{{MkdirPath: "{{.OutputDir}}/bin"}, {CopyTo: "{{.OutputDir}}/bin/a"}}},
+			}},
+			err: ErrInvalidCmd,
+			msg: `job "a": command 1: invalid command: copy has empty paths`,
+		},
+		{
+			name: "fragment outside output",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "a", Fragment: "../graph.json"},
+			}},
+			err: ErrInvalidFragment,
+			msg: `job "a": invalid graph fragment: path "../graph.json" is not inside output directory`,
+		},
+		{
+			name: "sharded fragment",
+			graph: Graph{Jobs: []Job{
+				{ID: ID{'a'}, Name: "test", Cmds: []Cmd{{Exec: []string{"a.test"}}}, Test: &Test{Names: []string{"TestA", "TestB"}, Shards: 2}, Fragment: "graph.json"},
+			}},
+			err: ErrInvalidFragment,
+			msg: `job "test": invalid graph fragment: sharded test job has no output directory`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.graph.Validate()
+			require.ErrorIs(t, err, test.err)
+			require.EqualError(t, err, test.msg)
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	g := Graph{Jobs: []Job{
+		{ID: ID{'a'}, Name: "a", Deps: []ID{{'b'}}, Inputs: []string{"a.go"}},
+		{ID: ID{'a'}, Name: "a"},
+	}}
+
+	err := g.Validate()
+	require.ErrorIs(t, err, ErrDuplicateID)
+	require.ErrorIs(t, err, ErrUnknownDep)
+	require.ErrorIs(t, err, ErrMissingInput)
+}
@@ -4,21 +4,131 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
 )
 
+const (
+	// cancelTimeout ограничивает время на отправку сигнала Cancel после отмены контекста сборки.
+	cancelTimeout = time.Second
+
+	// uploadConcurrency ограничивает число исходных файлов, которые клиент заливает одновременно.
+	uploadConcurrency = 16
+
+	// lookupConcurrency ограничивает число джобов, которые клиент одновременно ищет в удалённом кеше.
+	lookupConcurrency = 16
+)
+
+// buildService - клиент api.Service: api.BuildClient или api.BuildGRPCClient.
+type buildService interface {
+	StartBuild(ctx context.Context, request *api.BuildRequest) (*api.BuildStarted, api.StatusReader, error)
+	SignalBuild(ctx context.Context, buildID build.ID, signal *api.SignalRequest) (*api.SignalResponse, error)
+	AttachBuild(ctx context.Context, request *api.AttachRequest) (api.StatusReader, error)
+}
+
 type Client struct {
+	l           *zap.Logger
+	apiEndpoint string
+	sourceDir   string
+
+	http   *http.Client
+	grpc   grpc.ClientConnInterface
+	builds buildService
+	files  *filecache.Client
+
+	remoteCacheEndpoint string
+	remoteCache         *remotecache.Client
+	cacheOnly           bool
+
+	priority api.Priority
+
+	outputDir string
+	outputs   []Output
+}
+
+// Option задаёт необязательный параметр клиента.
+type Option func(c *Client)
+
+// WithRemoteCache включает поиск результатов джобов в удалённом кеше по HTTP протоколу Bazel.
+//
+// Джоб, результат которого есть в кеше, не отправляется на координатор, если от него
+// не зависят джобы, которые нужно выполнять.
+func WithRemoteCache(endpoint string) Option {
+	return func(c *Client) {
+		c.remoteCacheEndpoint = endpoint
+	}
+}
+
+// WithCacheOnly запрещает выполнять джобы. Сборка завершается с ошибкой, если результата
+// какого-то джоба нет в удалённом кеше. Опция имеет смысл только вместе с WithRemoteCache.
+func WithCacheOnly() Option {
+	return func(c *Client) {
+		c.cacheOnly = true
+	}
+}
+
+// WithHTTPClient задаёт http.Client, через который клиент ходит на координатор и в удалённый кеш,
+// например с токеном из auth.NewHTTPClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+// WithGRPC переводит запуск сборок и статус сборок на gRPC соединение cc с координатором.
+// Исходные файлы и удалённый кеш по-прежнему работают по HTTP.
+func WithGRPC(cc grpc.ClientConnInterface) Option {
+	return func(c *Client) {
+		c.grpc = cc
+	}
+}
+
+// WithPriority задаёт приоритет сборок клиента.
+func WithPriority(priority api.Priority) Option {
+	return func(c *Client) {
+		c.priority = priority
+	}
 }
 
 func NewClient(
 	l *zap.Logger,
 	apiEndpoint string,
 	sourceDir string,
+	opts ...Option,
 ) *Client {
-	panic("implement me")
+	c := &Client{
+		l:           l,
+		apiEndpoint: apiEndpoint,
+		sourceDir:   sourceDir,
+		http:        http.DefaultClient,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.grpc != nil {
+		c.builds = api.NewBuildGRPCClient(l, c.grpc)
+	} else {
+		c.builds = api.NewBuildClient(l, apiEndpoint, api.WithHTTPClient(c.http))
+	}
+	c.files = filecache.NewClient(l, apiEndpoint, filecache.WithHTTPClient(c.http))
+	if c.remoteCacheEndpoint != "" {
+		c.remoteCache = remotecache.NewClient(l, c.remoteCacheEndpoint, remotecache.WithHTTPClient(c.http))
+	}
+	return c
 }
 
 type BuildListener interface {
@@ -29,6 +139,221 @@ type BuildListener interface {
 	OnJobFailed(jobID build.ID, code int, error string) error
 }
 
+// HermeticityListener - необязательное расширение BuildListener.
+//
+// Если listener реализует этот интерфейс, клиент сообщает ему о нарушениях
+// герметичности, найденных воркером, перед вызовом OnJobFailed.
+type HermeticityListener interface {
+	OnJobViolations(jobID build.ID, violations []api.Violation) error
+}
+
+// TraceListener - необязательное расширение BuildListener.
+//
+// Если listener реализует этот интерфейс, клиент запрашивает у координатора трейс сборки
+// и передаёт его listener-у перед завершением сборки, в том числе неудачным.
+type TraceListener interface {
+	OnBuildTrace(t *trace.Trace) error
+}
+
+// TestListener - необязательное расширение BuildListener.
+//
+// Если listener реализует этот интерфейс, клиент передаёт ему результат каждого шарда тестового
+// джоба до того, как сообщить о завершении самого джоба. Вывод тестовых джобов приходит целиком
+// после завершения джоба, а не по мере выполнения.
+type TestListener interface {
+	OnTestShard(jobID build.ID, shard *api.TestShard) error
+}
+
+// ExpandListener - необязательное расширение BuildListener.
+//
+// Если listener реализует этот интерфейс, клиент сообщает ему о джобах, которые координатор добавил
+// в сборку из фрагмента графа джоба generator (см. build.Job.Fragment). Добавленные джобы приходят
+// до результата generator и до своих результатов.
+type ExpandListener interface {
+	OnJobsAdded(generator build.ID, jobs []build.Job) error
+}
+
+func (c *Client) uploadFiles(ctx context.Context, graph *build.Graph, missing []build.ID) error {
+	for _, id := range missing {
+		if _, ok := graph.SourceFiles[id]; !ok {
+			return fmt.Errorf("coordinator requested unknown file %s", id)
+		}
+	}
+
+	// Часть файлов могла залить параллельная сборка, пока координатор отвечал на StartBuild.
+	if len(missing) > uploadConcurrency {
+		var err error
+		if missing, err = c.files.Missing(ctx, missing); err != nil {
+			return err
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(uploadConcurrency)
+
+	for _, id := range missing {
+		path := graph.SourceFiles[id]
+		g.Go(func() error {
+			if err := c.files.UploadFile(ctx, id, path, filepath.Join(c.sourceDir, filepath.FromSlash(path))); err != nil {
+				return fmt.Errorf("upload %q: %w", path, err)
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
 func (c *Client) Build(ctx context.Context, graph build.Graph, lsn BuildListener) error {
-	panic("implement me")
+	if err := c.checkOutputs(&graph); err != nil {
+		return err
+	}
+
+	if err := c.build(ctx, graph, lsn); err != nil {
+		return err
+	}
+
+	return c.downloadOutputs(ctx)
+}
+
+func (c *Client) build(ctx context.Context, graph build.Graph, lsn BuildListener) error {
+	var cached []build.ID
+	if c.remoteCache != nil {
+		var err error
+		if graph, cached, err = c.skipCached(ctx, graph, lsn); err != nil {
+			return err
+		}
+
+		if len(graph.Jobs) == 0 {
+			c.l.Info("all jobs found in remote cache")
+			return nil
+		}
+	}
+
+	_, wantTrace := lsn.(TraceListener)
+	started, r, err := c.builds.StartBuild(ctx, &api.BuildRequest{Graph: graph, Priority: c.priority, Trace: wantTrace, Cached: cached})
+	if err != nil {
+		return err
+	}
+
+	status := c.newBuildStatus(started.ID, r)
+	defer func() { _ = status.Close() }()
+
+	defer func() {
+		if ctx.Err() != nil {
+			c.cancelBuild(ctx, started.ID)
+		}
+	}()
+
+	c.l.Info("build started",
+		zap.String("build_id", started.ID.String()),
+		zap.Int("missing_files", len(started.MissingFiles)))
+
+	if err := c.uploadFiles(ctx, &graph, started.MissingFiles); err != nil {
+		return err
+	}
+
+	if _, err := c.builds.SignalBuild(ctx, started.ID, &api.SignalRequest{UploadDone: &api.UploadDone{}}); err != nil {
+		return err
+	}
+
+	for {
+		u, err := status.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case u.JobOutput != nil:
+			if err := c.onJobOutput(u.JobOutput, lsn); err != nil {
+				return err
+			}
+
+		case u.JobFinished != nil:
+			if err := c.onJobFinished(u.JobFinished, lsn); err != nil {
+				return err
+			}
+
+		case u.JobsAdded != nil:
+			if el, ok := lsn.(ExpandListener); ok {
+				if err := el.OnJobsAdded(u.JobsAdded.Generator, u.JobsAdded.Jobs); err != nil {
+					return err
+				}
+			}
+
+		case u.TestShard != nil:
+			if tl, ok := lsn.(TestListener); ok {
+				if err := tl.OnTestShard(u.TestShard.JobID, u.TestShard); err != nil {
+					return err
+				}
+			}
+
+		case u.Trace != nil:
+			if tl, ok := lsn.(TraceListener); ok {
+				if err := tl.OnBuildTrace(u.Trace); err != nil {
+					return err
+				}
+			}
+
+		case u.BuildFailed != nil:
+			c.l.Info("build failed", zap.String("error", u.BuildFailed.Error))
+			return fmt.Errorf("build failed: %s", u.BuildFailed.Error)
+
+		case u.BuildFinished != nil:
+			c.l.Info("build finished")
+			return nil
+		}
+	}
+}
+
+// cancelBuild просит координатора остановить сборку.
+//
+// Координатор сам отменит сборку, когда заметит разрыв соединения, а сигнал лишь ускоряет отмену.
+func (c *Client) cancelBuild(ctx context.Context, buildID build.ID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelTimeout)
+	defer cancel()
+
+	if _, err := c.builds.SignalBuild(ctx, buildID, &api.SignalRequest{Cancel: &api.Cancel{}}); err != nil {
+		c.l.Warn("failed to cancel build", zap.String("build_id", buildID.String()), zap.Error(err))
+	}
+}
+
+func (c *Client) onJobOutput(out *api.JobOutput, lsn BuildListener) error {
+	if len(out.Stdout) != 0 {
+		if err := lsn.OnJobStdout(out.ID, out.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if len(out.Stderr) != 0 {
+		if err := lsn.OnJobStderr(out.ID, out.Stderr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) onJobFinished(res *api.JobResult, lsn BuildListener) error {
+	if err := c.onJobOutput(&api.JobOutput{ID: res.ID, Stdout: res.Stdout, Stderr: res.Stderr}, lsn); err != nil {
+		return err
+	}
+
+	if len(res.Violations) != 0 {
+		if hl, ok := lsn.(HermeticityListener); ok {
+			if err := hl.OnJobViolations(res.ID, res.Violations); err != nil {
+				return err
+			}
+		}
+	}
+
+	if res.Error != nil {
+		return lsn.OnJobFailed(res.ID, res.ExitCode, *res.Error)
+	}
+
+	if res.ExitCode != 0 {
+		return lsn.OnJobFailed(res.ID, res.ExitCode, "")
+	}
+
+	return lsn.OnJobFinished(res.ID)
 }
@@ -0,0 +1,141 @@
+//go:build !solution
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+// skipCached сообщает lsn о джобах, которые можно взять из удалённого кеша, и возвращает
+// граф из оставшихся джобов и список взятых из кеша джобов, от которых они зависят.
+//
+// Такие зависимости остаются в графе без команд и входов, а координатор получает их в
+// api.BuildRequest.Cached: воркеры скачают их выходы из удалённого кеша. Джобы, выходы которых клиент
+// скачивает после сборки, и джобы с фрагментом графа не пропускаются. В режиме cacheOnly пропускаются
+// все найденные джобы, а если какого-то джоба нет в кеше, возвращается ошибка.
+func (c *Client) skipCached(ctx context.Context, graph build.Graph, lsn BuildListener) (build.Graph, []build.ID, error) {
+	jobs := build.TopSort(graph.Jobs)
+
+	if c.cacheOnly {
+		for _, job := range jobs {
+			if job.Fragment != "" {
+				return graph, nil, fmt.Errorf("job %q with graph fragment can't be taken from cache", job.Name)
+			}
+		}
+	}
+
+	results, err := c.lookupCached(ctx, jobs)
+	if err != nil {
+		return graph, nil, err
+	}
+
+	cached := make(map[build.ID]*remotecache.ActionResult, len(jobs))
+	var missing []string
+	for i, job := range jobs {
+		if results[i] != nil {
+			cached[job.ID] = results[i]
+		} else {
+			missing = append(missing, job.Name)
+		}
+	}
+
+	skip := make(map[build.ID]bool, len(jobs))
+	for _, job := range jobs {
+		// Джобы фрагмента графа появятся только после того, как координатор выполнит его генератор.
+		skip[job.ID] = cached[job.ID] != nil && (c.cacheOnly || !c.wantsOutputs(job.ID) && job.Fragment == "")
+	}
+
+	remaining := graph
+	remaining.Jobs = nil
+	remaining.SourceFiles = make(map[build.ID]string)
+
+	var deps []build.ID
+	needed := make(map[build.ID]bool)
+	inputs := make(map[string]struct{})
+	for _, job := range jobs {
+		if !skip[job.ID] {
+			remaining.Jobs = append(remaining.Jobs, job)
+			for _, path := range job.Inputs {
+				inputs[path] = struct{}{}
+			}
+
+			for _, dep := range job.Deps {
+				if skip[dep] && !needed[dep] {
+					needed[dep] = true
+					deps = append(deps, dep)
+				}
+			}
+			continue
+		}
+
+		res := cached[job.ID]
+		err := c.onJobFinished(&api.JobResult{
+			ID:       job.ID,
+			Stdout:   res.StdoutRaw,
+			Stderr:   res.StderrRaw,
+			ExitCode: res.ExitCode,
+		}, lsn)
+		if err != nil {
+			return graph, nil, err
+		}
+	}
+
+	// Координатор не запускает джобы из кеша, поэтому им не нужны ни команды, ни входы, ни зависимости.
+	for _, job := range jobs {
+		if needed[job.ID] {
+			remaining.Jobs = append(remaining.Jobs, build.Job{ID: job.ID, Name: job.Name})
+		}
+	}
+
+	for id, path := range graph.SourceFiles {
+		if _, ok := inputs[path]; ok {
+			remaining.SourceFiles[id] = path
+		}
+	}
+
+	c.l.Info("remote cache lookup finished",
+		zap.Int("cached", len(cached)),
+		zap.Int("skipped", len(jobs)-len(remaining.Jobs)+len(deps)))
+
+	if c.cacheOnly && len(missing) != 0 {
+		return graph, nil, fmt.Errorf("cache-only build: %d jobs are missing from remote cache, first is %q", len(missing), missing[0])
+	}
+
+	return remaining, deps, nil
+}
+
+// lookupCached параллельно ищет результаты джобов в удалённом кеше. Для джобов, которых нет в кеше,
+// результат nil. Ошибка кеша возвращается только в режиме cacheOnly, иначе джоб считается отсутствующим.
+func (c *Client) lookupCached(ctx context.Context, jobs []build.Job) ([]*remotecache.ActionResult, error) {
+	results := make([]*remotecache.ActionResult, len(jobs))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(lookupConcurrency)
+
+	for i, job := range jobs {
+		g.Go(func() error {
+			res, err := c.remoteCache.GetActionResult(ctx, job.ID)
+			switch {
+			case err == nil:
+				results[i] = res
+			case errors.Is(err, remotecache.ErrNotFound):
+			case c.cacheOnly:
+				return err
+			default:
+				c.l.Warn("remote cache lookup failed", zap.String("job_id", job.ID.String()), zap.Error(err))
+			}
+			return nil
+		})
+	}
+
+	return results, g.Wait()
+}
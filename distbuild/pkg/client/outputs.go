@@ -0,0 +1,184 @@
+//go:build !solution
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Output выбирает файлы из выходной директории джоба, которые клиент скачивает после сборки.
+type Output struct {
+	JobID build.ID
+
+	// Globs - шаблоны path.Match для путей внутри {{.OutputDir}} джоба. Подходящая директория
+	// скачивается целиком. Если Globs пуст, скачивается вся выходная директория.
+	Globs []string
+}
+
+// WithOutputs включает скачивание выходов джобов outputs в директорию dir после успешной сборки.
+//
+// Файлы всех джобов складываются в dir по путям относительно {{.OutputDir}} с сохранением прав
+// и символических ссылок. Файлы, которые уже есть в dir, заменяются. Артефакты скачиваются
+// через координатора, поэтому клиенту не нужен доступ к воркерам.
+func WithOutputs(dir string, outputs ...Output) Option {
+	return func(c *Client) {
+		c.outputDir = dir
+		c.outputs = outputs
+	}
+}
+
+// checkOutputs проверяет, что выходы можно скачать после сборки graph.
+func (c *Client) checkOutputs(graph *build.Graph) error {
+	if len(c.outputs) == 0 {
+		return nil
+	}
+
+	if c.cacheOnly {
+		return errors.New("job outputs can't be downloaded in cache-only build")
+	}
+
+	jobs := make(map[build.ID]struct{}, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		jobs[job.ID] = struct{}{}
+	}
+
+	for _, out := range c.outputs {
+		if _, ok := jobs[out.JobID]; !ok {
+			return fmt.Errorf("output job %s is not in the build graph", out.JobID)
+		}
+
+		for _, glob := range out.Globs {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("invalid output pattern %q: %w", glob, err)
+			}
+		}
+	}
+	return nil
+}
+
+// wantsOutputs проверяет, что клиент скачивает выходы джоба id.
+func (c *Client) wantsOutputs(id build.ID) bool {
+	for _, out := range c.outputs {
+		if out.JobID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) downloadOutputs(ctx context.Context) error {
+	if len(c.outputs) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.outputDir, 0777); err != nil {
+		return err
+	}
+
+	for _, out := range c.outputs {
+		if err := c.downloadOutput(ctx, out); err != nil {
+			return fmt.Errorf("download outputs of job %s: %w", out.JobID, err)
+		}
+	}
+	return nil
+}
+
+// downloadOutput скачивает выходы джоба во временную директорию внутри outputDir и переносит их на место.
+func (c *Client) downloadOutput(ctx context.Context, out Output) error {
+	tmpDir, err := os.MkdirTemp(c.outputDir, ".download-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	include := []string{artifact.JobOutputDir}
+	if len(out.Globs) != 0 {
+		include = include[:0]
+		for _, glob := range out.Globs {
+			include = append(include, artifact.JobOutputDir+"/"+glob)
+		}
+	}
+
+	t, err := artifact.DownloadFiles(ctx, c.apiEndpoint, tmpDir, out.JobID, include, artifact.DownloadOptions{Client: c.http})
+	if err != nil {
+		return err
+	}
+
+	c.l.Info("job outputs downloaded",
+		zap.String("job_id", out.JobID.String()),
+		zap.Int64("received", t.Received))
+
+	src := filepath.Join(tmpDir, artifact.JobOutputDir)
+	if _, err := os.Lstat(src); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no files match %q", out.Globs)
+	}
+	return moveTree(src, c.outputDir)
+}
+
+// moveTree переносит содержимое src в dst. Записи, которые уже есть в dst, заменяются,
+// а директории сливаются и получают права директорий из src.
+func moveTree(src, dst string) error {
+	// Права выставляются после обхода, чтобы директория без права на запись не помешала перенести в неё файлы.
+	dirModes := map[string]fs.FileMode{}
+
+	err := filepath.WalkDir(src, func(srcPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, srcPath)
+		if err != nil || rel == "." {
+			return err
+		}
+		dstPath := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			dirModes[dstPath] = info.Mode().Perm()
+		}
+
+		existing, err := os.Lstat(dstPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return err
+		case d.IsDir() && existing.IsDir():
+			return nil
+		case d.IsDir() || existing.IsDir():
+			// Файл заменяет директорию, а директория - файл или символическую ссылку,
+			// через которую иначе можно было бы записать файлы за пределы dst.
+			if err := os.RemoveAll(dstPath); err != nil {
+				return err
+			}
+		}
+
+		if d.IsDir() {
+			return os.Mkdir(dstPath, 0777)
+		}
+		return os.Rename(srcPath, dstPath)
+	})
+	if err != nil {
+		return err
+	}
+
+	for path, mode := range dirModes {
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
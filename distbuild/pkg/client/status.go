@@ -0,0 +1,153 @@
+//go:build !solution
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+const (
+	// reattachTimeout ограничивает время, за которое координатор должен вернуться после разрыва
+	// соединения со статусом сборки.
+	reattachTimeout = 30 * time.Second
+
+	reattachInterval    = 100 * time.Millisecond
+	maxReattachInterval = 2 * time.Second
+)
+
+// buildStatus читает статус сборки и переподключается к нему через AttachBuild, если соединение
+// с координатором разорвалось, например из-за перезапуска координатора.
+type buildStatus struct {
+	c  *Client
+	id build.ID
+	r  api.StatusReader
+
+	// received - сколько обновлений с JobFinished и JobsAdded уже получено.
+	received int
+
+	// streamed хранит, сколько байт stdout и stderr джобов пришло в JobOutput через текущее
+	// подключение, а prior - через предыдущие.
+	streamed map[build.ID][2]int
+	prior    map[build.ID][2]int
+}
+
+func (c *Client) newBuildStatus(id build.ID, r api.StatusReader) *buildStatus {
+	return &buildStatus{
+		c:        c,
+		id:       id,
+		r:        r,
+		streamed: make(map[build.ID][2]int),
+		prior:    make(map[build.ID][2]int),
+	}
+}
+
+func (s *buildStatus) Close() error {
+	if s.r == nil {
+		return nil
+	}
+	return s.r.Close()
+}
+
+func (s *buildStatus) Next(ctx context.Context) (*api.StatusUpdate, error) {
+	for {
+		u, err := s.r.Next()
+		if err == nil {
+			return s.track(u), nil
+		}
+
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("build status stream closed unexpectedly")
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		if err := s.reattach(ctx, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// track учитывает полученное обновление. Из результата джоба, вывод которого приходил
+// до переподключения, track вырезает уже полученный вывод.
+func (s *buildStatus) track(u *api.StatusUpdate) *api.StatusUpdate {
+	switch {
+	case u.JobOutput != nil:
+		n := s.streamed[u.JobOutput.ID]
+		s.streamed[u.JobOutput.ID] = [2]int{n[0] + len(u.JobOutput.Stdout), n[1] + len(u.JobOutput.Stderr)}
+
+	case u.JobsAdded != nil:
+		s.received++
+
+	case u.JobFinished != nil:
+		s.received++
+
+		id := u.JobFinished.ID
+		prior, ok := s.prior[id]
+		_, streamed := s.streamed[id]
+		delete(s.prior, id)
+		delete(s.streamed, id)
+
+		// Новый координатор не знает, какой вывод клиент получил от старого, и присылает его заново.
+		if ok && !streamed {
+			res := *u.JobFinished
+			res.Stdout = res.Stdout[min(prior[0], len(res.Stdout)):]
+			res.Stderr = res.Stderr[min(prior[1], len(res.Stderr)):]
+			return &api.StatusUpdate{JobFinished: &res}
+		}
+	}
+
+	return u
+}
+
+func (s *buildStatus) reattach(ctx context.Context, cause error) error {
+	_ = s.r.Close()
+	s.r = nil
+
+	for id, n := range s.streamed {
+		p := s.prior[id]
+		s.prior[id] = [2]int{p[0] + n[0], p[1] + n[1]}
+	}
+	clear(s.streamed)
+
+	s.c.l.Warn("build status stream broken, reattaching",
+		zap.String("build_id", s.id.String()),
+		zap.Error(cause))
+
+	deadline := time.Now().Add(reattachTimeout)
+	delay := reattachInterval
+	for {
+		r, err := s.c.builds.AttachBuild(ctx, &api.AttachRequest{BuildID: s.id, Received: s.received})
+		if err == nil {
+			s.r = r
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("%w; reattach failed: %w", cause, err)
+		}
+
+		s.c.l.Debug("reattach failed", zap.String("build_id", s.id.String()), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, maxReattachInterval)
+	}
+}
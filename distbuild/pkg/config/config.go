@@ -0,0 +1,358 @@
+// Package config описывает конфигурацию бинарников distbuild в формате yaml.
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v2"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+)
+
+// Log задаёт, куда и с каким уровнем писать лог.
+type Log struct {
+	// Level - один из debug, info, warn, error. По умолчанию info.
+	Level string `yaml:"level"`
+
+	// File - путь к файлу лога. По умолчанию лог пишется в stderr.
+	File string `yaml:"file"`
+}
+
+// Build создаёт логгер по конфигурации.
+func (l *Log) Build() (*zap.Logger, error) {
+	cfg := zap.NewProductionConfig()
+	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	if l.Level != "" {
+		level, err := zap.ParseAtomicLevel(l.Level)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Level = level
+	}
+
+	if l.File != "" {
+		cfg.OutputPaths = []string{l.File}
+		cfg.ErrorOutputPaths = []string{l.File}
+	}
+
+	return cfg.Build()
+}
+
+// Coordinator - конфигурация distbuild-coordinator.
+type Coordinator struct {
+	// Listen - адрес, на котором координатор принимает запросы клиентов и воркеров.
+	Listen string `yaml:"listen"`
+
+	// GRPCListen - адрес, на котором координатор принимает gRPC вызовы сборок и heartbeat-ов.
+	// По умолчанию gRPC выключен.
+	GRPCListen string `yaml:"grpc_listen"`
+
+	// RootDir - директория, в которой координатор хранит файлы сборок и кеш результатов.
+	RootDir string `yaml:"root_dir"`
+
+	// WorkerTimeout задаёт, через сколько времени без heartbeat-ов воркер считается потерянным.
+	WorkerTimeout time.Duration `yaml:"worker_timeout"`
+
+	// RemoteCache включает удалённый кеш по HTTP протоколу Bazel.
+	RemoteCache bool `yaml:"remote_cache"`
+
+	// RemoteCacheGC ограничивает размер удалённого кеша. Без него кеш растёт без ограничений.
+	RemoteCacheGC *GC `yaml:"remote_cache_gc"`
+
+	// Speculative включает повторный запуск отстающих джобов на других воркерах.
+	Speculative *Speculative `yaml:"speculative"`
+
+	// TLS включает https и аутентификацию: воркеры предъявляют сертификаты, а клиенты - токены из TokensFile.
+	TLS        *TLS   `yaml:"tls"`
+	TokensFile string `yaml:"tokens_file"`
+
+	// Journal включает журнал в RootDir/journal. С журналом координатор продолжает незавершённые
+	// сборки после перезапуска.
+	Journal bool `yaml:"journal"`
+
+	// ShutdownTimeout ограничивает время, которое координатор ждёт завершения сборок при остановке.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Log Log `yaml:"log"`
+}
+
+// Worker - конфигурация distbuild-worker.
+type Worker struct {
+	// Endpoint - адрес воркера, по которому к нему обращаются координатор и другие воркеры.
+	// Он же служит ID воркера.
+	Endpoint string `yaml:"endpoint"`
+
+	// Listen - адрес, на котором воркер раздаёт артефакты.
+	Listen string `yaml:"listen"`
+
+	// Coordinator - адрес координатора.
+	Coordinator string `yaml:"coordinator"`
+
+	// CoordinatorGRPC - адрес gRPC сервера координатора в виде host:port. Если он задан,
+	// heartbeat-ы отправляются по gRPC.
+	CoordinatorGRPC string `yaml:"coordinator_grpc"`
+
+	// RootDir - директория, в которой воркер хранит файлы и артефакты.
+	RootDir string `yaml:"root_dir"`
+
+	// Capacity задаёт ресурсы воркера. По умолчанию воркер выполняет один джоб за раз.
+	Capacity *Resources `yaml:"capacity"`
+
+	// GC включает сборку мусора в кеше артефактов.
+	GC *GC `yaml:"gc"`
+
+	// Sandbox включает запуск джобов в песочнице.
+	Sandbox *Sandbox `yaml:"sandbox"`
+
+	// Hermetic включает проверку герметичности джобов.
+	Hermetic bool `yaml:"hermetic"`
+
+	// CompressArtifacts включает сжатие артефактов, скачиваемых с других воркеров.
+	CompressArtifacts bool `yaml:"compress_artifacts"`
+
+	// Tools задаёт пути инструментов, которые джобы получают через {{.Tool "<name>"}}.
+	Tools []Tool `yaml:"tools"`
+
+	// TLS включает https. Сертификат воркера должен содержать URI равный Endpoint: по нему
+	// координатор узнаёт воркера.
+	TLS *TLS `yaml:"tls"`
+
+	// ShutdownTimeout ограничивает время, которое воркер ждёт остановки джобов.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Log Log `yaml:"log"`
+}
+
+// TLS - файлы сертификата и ключа компонента и сертификата CA в формате PEM.
+type TLS struct {
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+	CA   string `yaml:"ca"`
+}
+
+func (t *TLS) Server() (*tls.Config, error) {
+	return auth.ServerTLS(t.Cert, t.Key, t.CA)
+}
+
+func (t *TLS) Client() (*tls.Config, error) {
+	return auth.ClientTLS(t.Cert, t.Key, t.CA)
+}
+
+// validate проверяет, что заданы нужные файлы. Сертификат необязателен только клиенту сборки.
+func (t *TLS) validate(path string, certRequired bool) error {
+	var errs []error
+	if t.CA == "" {
+		errs = append(errs, fmt.Errorf("%s: tls.ca is required", path))
+	}
+	if certRequired && t.Cert == "" {
+		errs = append(errs, fmt.Errorf("%s: tls.cert is required", path))
+	}
+	if (t.Cert == "") != (t.Key == "") {
+		errs = append(errs, fmt.Errorf("%s: tls.cert and tls.key must be set together", path))
+	}
+	return errors.Join(errs...)
+}
+
+type Speculative struct {
+	Factor   float64       `yaml:"factor"`
+	MinDelay time.Duration `yaml:"min_delay"`
+}
+
+type Resources struct {
+	MilliCPU int64 `yaml:"milli_cpu"`
+	Memory   int64 `yaml:"memory"`
+}
+
+func (r *Resources) Build() build.Resources {
+	return build.Resources{MilliCPU: r.MilliCPU, Memory: r.Memory}
+}
+
+type GC struct {
+	MaxSize  int64         `yaml:"max_size"`
+	MaxAge   time.Duration `yaml:"max_age"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+func (g *GC) Build() artifact.GCConfig {
+	return artifact.GCConfig{MaxSize: g.MaxSize, MaxAge: g.MaxAge}
+}
+
+// Tool - инструмент воркера, см. worker.Tool.
+type Tool struct {
+	Name     string `yaml:"name"`
+	Path     string `yaml:"path"`
+	Writable bool   `yaml:"writable"`
+}
+
+type Sandbox struct {
+	ReadOnlyPaths []string      `yaml:"read_only_paths"`
+	CgroupRoot    string        `yaml:"cgroup_root"`
+	CPUs          float64       `yaml:"cpus"`
+	Memory        int64         `yaml:"memory"`
+	Pids          int64         `yaml:"pids"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+func (s *Sandbox) Build() sandbox.Config {
+	return sandbox.Config{
+		ReadOnlyPaths: s.ReadOnlyPaths,
+		CgroupRoot:    s.CgroupRoot,
+		Limits: sandbox.Limits{
+			CPUs:    s.CPUs,
+			Memory:  s.Memory,
+			Pids:    s.Pids,
+			Timeout: s.Timeout,
+		},
+	}
+}
+
+// Client - конфигурация клиента distbuild.
+type Client struct {
+	// Coordinator - адрес координатора.
+	Coordinator string `yaml:"coordinator"`
+
+	// CoordinatorGRPC - адрес gRPC сервера координатора в виде host:port. Если он задан,
+	// сборки запускаются по gRPC.
+	CoordinatorGRPC string `yaml:"coordinator_grpc"`
+
+	// RemoteCache - адрес удалённого кеша. Обычно совпадает с адресом координатора.
+	RemoteCache string `yaml:"remote_cache"`
+
+	// CacheOnly запрещает выполнять джобы, которых нет в удалённом кеше.
+	CacheOnly bool `yaml:"cache_only"`
+
+	// Priority - один из normal, batch, interactive.
+	Priority string `yaml:"priority"`
+
+	// TLS задаёт CA координатора и, если нужно, клиентский сертификат.
+	TLS *TLS `yaml:"tls"`
+
+	// TokenFile - файл с токеном, который клиент предъявляет координатору.
+	TokenFile string `yaml:"token_file"`
+
+	// Log клиента по умолчанию имеет уровень warn, чтобы не мешать выводу прогресса.
+	Log Log `yaml:"log"`
+}
+
+// LoadCoordinator читает конфигурацию координатора.
+func LoadCoordinator(path string) (*Coordinator, error) {
+	c := &Coordinator{
+		Listen:          ":8080",
+		WorkerTimeout:   10 * time.Second,
+		ShutdownTimeout: 10 * time.Second,
+	}
+	if err := load(path, c); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if c.RootDir == "" {
+		errs = append(errs, fmt.Errorf("%s: root_dir is required", path))
+	}
+	if c.RemoteCacheGC != nil && c.RemoteCacheGC.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("%s: remote_cache_gc.interval must be positive", path))
+	}
+	if c.TLS != nil {
+		errs = append(errs, c.TLS.validate(path, true))
+	} else if c.TokensFile != "" {
+		errs = append(errs, fmt.Errorf("%s: tokens_file requires tls", path))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadWorker читает конфигурацию воркера.
+func LoadWorker(path string) (*Worker, error) {
+	w := &Worker{
+		Listen:          ":8081",
+		ShutdownTimeout: 10 * time.Second,
+	}
+	if err := load(path, w); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if w.Endpoint == "" {
+		errs = append(errs, fmt.Errorf("%s: endpoint is required", path))
+	}
+	if w.Coordinator == "" {
+		errs = append(errs, fmt.Errorf("%s: coordinator is required", path))
+	}
+	if w.RootDir == "" {
+		errs = append(errs, fmt.Errorf("%s: root_dir is required", path))
+	}
+	if w.GC != nil && w.GC.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("%s: gc.interval must be positive", path))
+	}
+	tools := map[string]bool{}
+	for _, tool := range w.Tools {
+		switch {
+		case tool.Name == "":
+			errs = append(errs, fmt.Errorf("%s: tools.name is required", path))
+		case tools[tool.Name]:
+			errs = append(errs, fmt.Errorf("%s: duplicate tool %q", path, tool.Name))
+		case !filepath.IsAbs(tool.Path):
+			errs = append(errs, fmt.Errorf("%s: tool %q: path must be absolute", path, tool.Name))
+		}
+		tools[tool.Name] = true
+	}
+	if w.TLS != nil {
+		errs = append(errs, w.TLS.validate(path, true))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// LoadClient читает конфигурацию клиента. Пустой path означает конфигурацию по умолчанию.
+func LoadClient(path string) (*Client, error) {
+	c := &Client{Log: Log{Level: "warn"}}
+	if err := load(path, c); err != nil {
+		return nil, err
+	}
+
+	if c.Priority != "" {
+		if _, err := api.ParsePriority(c.Priority); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if c.TLS != nil {
+		if err := c.TLS.validate(path, false); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// load читает yaml поверх значений по умолчанию. Неизвестные поля считаются ошибкой,
+// чтобы опечатка в конфиге не превращалась в молча проигнорированную настройку.
+func load(path string, v any) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.UnmarshalStrict(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0666))
+	return path
+}
+
+func TestLoadCoordinator(t *testing.T) {
+	c, err := LoadCoordinator(writeConfig(t, `
+root_dir: /var/lib/distbuild
+grpc_listen: :8082
+worker_timeout: 30s
+remote_cache: true
+remote_cache_gc:
+  max_size: 1073741824
+  interval: 1m
+speculative:
+  factor: 3
+  min_delay: 30s
+journal: true
+log:
+  level: debug
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, &Coordinator{
+		Listen:          ":8080",
+		GRPCListen:      ":8082",
+		RootDir:         "/var/lib/distbuild",
+		WorkerTimeout:   30 * time.Second,
+		RemoteCache:     true,
+		RemoteCacheGC:   &GC{MaxSize: 1 << 30, Interval: time.Minute},
+		Speculative:     &Speculative{Factor: 3, MinDelay: 30 * time.Second},
+		Journal:         true,
+		ShutdownTimeout: 10 * time.Second,
+		Log:             Log{Level: "debug"},
+	}, c)
+
+	_, err = c.Log.Build()
+	require.NoError(t, err)
+}
+
+func TestLoadWorker(t *testing.T) {
+	w, err := LoadWorker(writeConfig(t, `
+endpoint: http://worker0:8081
+coordinator: http://coordinator:8080
+root_dir: /var/lib/distbuild
+capacity:
+  milli_cpu: 4000
+  memory: 8589934592
+gc:
+  max_age: 24h
+  interval: 1m
+tools:
+  - name: GOROOT
+    path: /usr/local/go
+  - name: GOCACHE
+    path: /var/cache/go-build
+    writable: true
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, []Tool{
+		{Name: "GOROOT", Path: "/usr/local/go"},
+		{Name: "GOCACHE", Path: "/var/cache/go-build", Writable: true},
+	}, w.Tools)
+
+	require.Equal(t, build.Resources{MilliCPU: 4000, Memory: 8 << 30}, w.Capacity.Build())
+	require.Equal(t, 24*time.Hour, w.GC.Build().MaxAge)
+	require.Equal(t, ":8081", w.Listen)
+}
+
+func TestLoadClient(t *testing.T) {
+	c, err := LoadClient(writeConfig(t, `
+coordinator: https://coordinator:8080
+coordinator_grpc: coordinator:8082
+tls:
+  ca: /etc/distbuild/ca.crt
+token_file: /etc/distbuild/token
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, &Client{
+		Coordinator:     "https://coordinator:8080",
+		CoordinatorGRPC: "coordinator:8082",
+		TLS:             &TLS{CA: "/etc/distbuild/ca.crt"},
+		TokenFile:       "/etc/distbuild/token",
+		Log:             Log{Level: "warn"},
+	}, c)
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := LoadWorker(writeConfig(t, `root_dir: /tmp`))
+	require.ErrorContains(t, err, "endpoint is required")
+	require.ErrorContains(t, err, "coordinator is required")
+
+	_, err = LoadCoordinator(writeConfig(t, `
+root_dir: /tmp
+worker_timout: 1s
+`))
+	require.ErrorContains(t, err, "worker_timout")
+
+	_, err = LoadCoordinator(writeConfig(t, `
+root_dir: /tmp
+tokens_file: /etc/distbuild/tokens
+`))
+	require.ErrorContains(t, err, "tokens_file requires tls")
+
+	_, err = LoadCoordinator(writeConfig(t, `
+root_dir: /tmp
+remote_cache: true
+remote_cache_gc:
+  max_size: 1024
+`))
+	require.ErrorContains(t, err, "remote_cache_gc.interval must be positive")
+
+	_, err = LoadWorker(writeConfig(t, `
+endpoint: https://worker0:8081
+coordinator: https://coordinator:8080
+root_dir: /tmp
+tls:
+  ca: /etc/distbuild/ca.crt
+`))
+	require.ErrorContains(t, err, "tls.cert is required")
+
+	_, err = LoadWorker(writeConfig(t, `
+endpoint: http://worker0:8081
+coordinator: http://coordinator:8080
+root_dir: /tmp
+tools:
+  - name: GOROOT
+    path: go
+`))
+	require.ErrorContains(t, err, `tool "GOROOT": path must be absolute`)
+
+	_, err = LoadClient(writeConfig(t, `priority: urgent`))
+	require.ErrorContains(t, err, `unknown priority "urgent"`)
+
+	_, err = (&Log{Level: "loud"}).Build()
+	require.Error(t, err)
+}
@@ -0,0 +1,60 @@
+//go:build !solution
+
+package dist
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// registerArtifactProxy регистрирует запросы артефактов (см. artifact.Handler), которые координатор
+// проксирует воркеру с артефактом. Через них клиент скачивает выходы джобов, не обращаясь к воркерам напрямую.
+func (c *Coordinator) registerArtifactProxy() {
+	c.mux.HandleFunc("/artifact", c.proxyArtifact)
+	c.mux.HandleFunc("/artifact/manifest", c.proxyArtifact)
+	c.mux.HandleFunc("/artifact/fetch", c.proxyArtifact)
+}
+
+func (c *Coordinator) proxyArtifact(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workerID, ok := c.scheduler.LocateArtifact(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("artifact %s not found", id), http.StatusNotFound)
+		return
+	}
+
+	target, err := url.Parse(workerID.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+
+			// Токен клиента воркеру не нужен: координатор предъявляет ему свой сертификат.
+			pr.Out.Header.Del("Authorization")
+		},
+		Transport: c.http.Transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			c.log.Warn("failed to proxy artifact request",
+				zap.String("artifact_id", id.String()),
+				zap.String("worker_id", workerID.String()),
+				zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,696 @@
+//go:build !solution
+
+package dist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// maxAttempts ограничивает число запусков джоба, которые прервались из-за потери воркера.
+const maxAttempts = 3
+
+// errBuildCancelled возвращается из Run, если клиент отменил сборку или отключился.
+var errBuildCancelled = errors.New("build cancelled")
+
+// Build хранит состояние одной сборки на координаторе.
+type Build struct {
+	ID build.ID
+
+	c *Coordinator
+	l *zap.Logger
+
+	// graph и jobs пополняются джобами из фрагментов графа (см. build.Job.Fragment).
+	graphMu sync.RWMutex
+	graph   *build.Graph
+	jobs    map[build.ID]*build.Job
+
+	// generators отмечает джобы, чьи фрагменты графа уже добавлены в сборку до перезапуска координатора.
+	generators map[build.ID]bool
+
+	// cached отмечает джобы, которые клиент взял из удалённого кеша (см. api.BuildRequest.Cached).
+	cached map[build.ID]bool
+
+	// rebuilds объединяет повторные сборки артефактов, которые пропали вместе с воркером.
+	rebuilds singleflight.Group
+
+	// cancel отменяет контекст, в котором выполняется Run.
+	cancel context.CancelFunc
+
+	uploaded     chan struct{}
+	uploadedOnce sync.Once
+
+	// w равен nil, пока к восстановленной из журнала сборке не подключился клиент.
+	wMu sync.Mutex
+	w   api.StatusWriter
+
+	// history хранит отправленные клиенту обновления с JobFinished и JobsAdded. Клиент, который подключается
+	// через AttachBuild, получает те из них, которые не успел получить.
+	history []*api.StatusUpdate
+
+	// results хранит результаты джобов, которые завершились до перезапуска координатора.
+	results map[build.ID]*api.JobResult
+
+	// finished закрывается, когда восстановленная сборка завершилась с ошибкой result.
+	finished chan struct{}
+	result   error
+
+	// outputs хранит для выполняющихся джобов, сколько байт stdout и stderr уже отправлено клиенту.
+	outMu   sync.Mutex
+	outputs map[build.ID]*[2]int64
+
+	// trace равен nil, если клиент не запросил трейс сборки.
+	traceMu sync.Mutex
+	trace   *trace.Trace
+
+	// jobStates хранит состояние джобов для статуса координатора.
+	started   time.Time
+	priority  api.Priority
+	stateMu   sync.Mutex
+	jobStates map[build.ID]*jobState
+}
+
+type jobState struct {
+	JobStatus
+
+	// stderrSeen - сколько байт stderr выполняющегося джоба учтено в StderrTail.
+	stderrSeen int64
+}
+
+func newBuild(c *Coordinator, id build.ID, request *api.BuildRequest, w api.StatusWriter, cancel context.CancelFunc) *Build {
+	graph := &request.Graph
+
+	jobs := make(map[build.ID]*build.Job, len(graph.Jobs))
+	for i := range graph.Jobs {
+		jobs[graph.Jobs[i].ID] = &graph.Jobs[i]
+	}
+
+	var t *trace.Trace
+	if request.Trace {
+		t = &trace.Trace{BuildID: id, Start: time.Now()}
+	}
+
+	cached := make(map[build.ID]bool, len(request.Cached))
+	for _, id := range request.Cached {
+		cached[id] = true
+	}
+
+	states := make(map[build.ID]*jobState, len(graph.Jobs))
+	for _, job := range graph.Jobs {
+		states[job.ID] = &jobState{JobStatus: JobStatus{ID: job.ID, Name: job.Name, State: JobWaiting}}
+	}
+
+	return &Build{
+		ID:         id,
+		c:          c,
+		l:          c.log.With(zap.String("build_id", id.String())),
+		graph:      graph,
+		jobs:       jobs,
+		generators: make(map[build.ID]bool),
+		cached:     cached,
+		cancel:     cancel,
+		uploaded:   make(chan struct{}),
+		w:          w,
+		results:    make(map[build.ID]*api.JobResult),
+		finished:   make(chan struct{}),
+		outputs:    make(map[build.ID]*[2]int64),
+		trace:      t,
+
+		started:   time.Now(),
+		priority:  request.Priority,
+		jobStates: states,
+	}
+}
+
+// restore переносит в сборку состояние из журнала.
+func (b *Build) restore(recovered *journal.Build) {
+	b.history = recovered.Updates
+	for _, u := range recovered.Updates {
+		if u.JobsAdded != nil {
+			b.generators[u.JobsAdded.Generator] = true
+
+			b.graphMu.Lock()
+			b.graph.Jobs = append(b.graph.Jobs, u.JobsAdded.Jobs...)
+			b.addJobs(u.JobsAdded.Jobs)
+			b.graphMu.Unlock()
+		}
+
+		if u.JobFinished != nil {
+			b.results[u.JobFinished.ID] = u.JobFinished
+			b.jobFinished(u.JobFinished, "")
+		}
+	}
+
+	if recovered.Uploaded {
+		b.uploadDone()
+	}
+}
+
+// attach начинает отправлять статус сборки в w. Клиент уже получил первые received обновлений из history.
+func (b *Build) attach(received int, w api.StatusWriter) error {
+	b.wMu.Lock()
+	defer b.wMu.Unlock()
+
+	if b.w != nil {
+		return fmt.Errorf("build %s already has a client", b.ID)
+	}
+
+	if received < 0 || received > len(b.history) {
+		return fmt.Errorf("client received %d updates, but build %s has only %d", received, b.ID, len(b.history))
+	}
+
+	if err := w.Started(&api.BuildStarted{ID: b.ID}); err != nil {
+		return err
+	}
+
+	for _, u := range b.history[received:] {
+		if err := w.Updated(u); err != nil {
+			return err
+		}
+	}
+
+	b.w = w
+	return nil
+}
+
+func (b *Build) attached() bool {
+	b.wMu.Lock()
+	defer b.wMu.Unlock()
+
+	return b.w != nil
+}
+
+func (b *Build) uploadDone() {
+	b.uploadedOnce.Do(func() {
+		close(b.uploaded)
+	})
+}
+
+// update отправляет обновление клиенту. Результаты джобов и добавленные джобы сначала записываются в журнал.
+func (b *Build) update(u *api.StatusUpdate) error {
+	b.wMu.Lock()
+	defer b.wMu.Unlock()
+
+	if u.JobFinished != nil || u.JobsAdded != nil {
+		b.c.record(&journal.Record{StatusUpdate: &journal.StatusUpdate{BuildID: b.ID, Update: u}})
+		b.history = append(b.history, u)
+	}
+
+	if b.w == nil {
+		return nil
+	}
+	return b.w.Updated(u)
+}
+
+// Run дожидается заливки файлов и исполняет граф сборки.
+//
+// Если ctx отменён, Run снимает ещё не завершённые джобы сборки и возвращает errBuildCancelled.
+func (b *Build) Run(ctx context.Context) error {
+	err := b.run(ctx)
+	if err != nil && ctx.Err() != nil {
+		b.l.Info("build cancelled", zap.Error(err))
+		return errBuildCancelled
+	}
+
+	if b.trace != nil {
+		if traceErr := b.sendTrace(); err == nil {
+			err = traceErr
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	b.l.Info("build finished")
+	return b.update(&api.StatusUpdate{BuildFinished: &api.BuildFinished{}})
+}
+
+func (b *Build) run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.uploaded:
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// done[id] закрывается, когда джоб id успешно завершился. Джобы из фрагментов графа
+	// добавляются в done по ходу сборки.
+	var doneMu sync.Mutex
+	done := make(map[build.ID]chan struct{})
+
+	markDone := func(id build.ID) {
+		doneMu.Lock()
+		defer doneMu.Unlock()
+
+		close(done[id])
+	}
+
+	waitDone := func(id build.ID) error {
+		doneMu.Lock()
+		ch := done[id]
+		doneMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+			return nil
+		}
+	}
+
+	var start func(jobs []build.Job)
+	start = func(jobs []build.Job) {
+		doneMu.Lock()
+		for _, job := range jobs {
+			done[job.ID] = make(chan struct{})
+		}
+		doneMu.Unlock()
+
+		for i := range jobs {
+			job := &jobs[i]
+
+			g.Go(func() error {
+				// Клиент сам сообщил о результате джоба из удалённого кеша.
+				if b.cached[job.ID] {
+					b.jobFinished(&api.JobResult{ID: job.ID}, "")
+					markDone(job.ID)
+					return nil
+				}
+
+				// Клиент узнал результат джоба до перезапуска координатора.
+				if res, ok := b.results[job.ID]; ok {
+					if res.Error != nil || res.ExitCode != 0 {
+						return fmt.Errorf("job %q failed", job.Name)
+					}
+
+					markDone(job.ID)
+					return nil
+				}
+
+				for _, dep := range job.Deps {
+					if err := waitDone(dep); err != nil {
+						return err
+					}
+				}
+
+				runJob := b.runJob
+				if job.Test != nil {
+					runJob = b.runTest
+				}
+
+				res, err := runJob(ctx, job)
+				if err != nil {
+					return err
+				}
+
+				// Джобы фрагмента добавляются до JobFinished генератора, поэтому в журнале
+				// они всегда идут раньше его результата.
+				if job.Fragment != "" && res.Error == nil && res.ExitCode == 0 && !b.generators[job.ID] {
+					added, err := b.expand(ctx, job)
+					switch {
+					case ctx.Err() != nil:
+						return ctx.Err()
+					case err != nil:
+						res = b.fragmentFailed(job, res, err)
+					default:
+						start(added)
+					}
+				}
+
+				if err := b.update(&api.StatusUpdate{JobFinished: res}); err != nil {
+					return err
+				}
+
+				if res.Error != nil || res.ExitCode != 0 {
+					return fmt.Errorf("job %q failed", job.Name)
+				}
+
+				markDone(job.ID)
+				return nil
+			})
+		}
+	}
+
+	start(build.TopSort(b.graph.Jobs))
+	return g.Wait()
+}
+
+func (b *Build) jobSpec(ctx context.Context, job *build.Job) (*api.JobSpec, error) {
+	spec := &api.JobSpec{
+		Job:         *job,
+		SourceFiles: make(map[build.ID]string),
+		Artifacts:   make(map[build.ID]api.WorkerID),
+	}
+
+	inputs := make(map[string]struct{}, len(job.Inputs))
+	for _, path := range job.Inputs {
+		inputs[path] = struct{}{}
+	}
+
+	for id, path := range b.graph.SourceFiles {
+		if _, ok := inputs[path]; ok {
+			spec.SourceFiles[id] = path
+		}
+	}
+
+	for _, dep := range job.Deps {
+		if b.cached[dep] {
+			spec.CachedArtifacts = append(spec.CachedArtifacts, dep)
+			continue
+		}
+
+		workerID, err := b.locateArtifact(ctx, dep)
+		if err != nil {
+			return nil, err
+		}
+		spec.Artifacts[dep] = workerID
+	}
+
+	return spec, nil
+}
+
+// locateArtifact находит воркера с артефактом джоба. Если артефакт пропал вместе с воркером,
+// джоб собирается заново.
+func (b *Build) locateArtifact(ctx context.Context, id build.ID) (api.WorkerID, error) {
+	if workerID, ok := b.c.scheduler.LocateArtifact(id); ok {
+		return workerID, nil
+	}
+
+	job, ok := b.job(id)
+	if !ok {
+		return "", fmt.Errorf("artifact %s is missing", id)
+	}
+
+	b.l.Info("rebuilding lost artifact", zap.String("job_id", id.String()), zap.String("name", job.Name))
+
+	_, err, _ := b.rebuilds.Do(id.String(), func() (any, error) {
+		res, err := b.runJob(ctx, job)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Error != nil || res.ExitCode != 0 {
+			return nil, fmt.Errorf("rebuild of job %q failed", job.Name)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if workerID, ok := b.c.scheduler.LocateArtifact(id); ok {
+		return workerID, nil
+	}
+	return "", fmt.Errorf("artifact %s is missing", id)
+}
+
+func (b *Build) job(id build.ID) (*build.Job, bool) {
+	b.graphMu.RLock()
+	defer b.graphMu.RUnlock()
+
+	job, ok := b.jobs[id]
+	return job, ok
+}
+
+// runJob запускает джоб и перезапускает его, если воркер, на котором он выполнялся, пропал.
+func (b *Build) runJob(ctx context.Context, job *build.Job) (*api.JobResult, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := b.runJobOnce(ctx, job, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !res.WorkerLost || attempt == maxAttempts {
+			return res, nil
+		}
+
+		b.l.Warn("retrying job",
+			zap.String("job_id", job.ID.String()),
+			zap.String("name", job.Name),
+			zap.Int("attempt", attempt),
+			zap.Stringp("error", res.Error))
+	}
+}
+
+func (b *Build) runJobOnce(ctx context.Context, job *build.Job, attempt int) (*api.JobResult, error) {
+	spec, err := b.jobSpec(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	b.l.Debug("scheduling job", zap.String("job_id", job.ID.String()), zap.String("name", job.Name))
+
+	b.outMu.Lock()
+	b.outputs[job.ID] = &[2]int64{}
+	b.outMu.Unlock()
+
+	queued := time.Now()
+	pendingJob := b.c.scheduler.ScheduleBuildJob(b.ID, spec)
+	b.setJobState(job.ID, JobQueued)
+
+	select {
+	case <-ctx.Done():
+		b.c.scheduler.CancelJob(pendingJob)
+
+		b.outMu.Lock()
+		delete(b.outputs, job.ID)
+		b.outMu.Unlock()
+
+		return nil, ctx.Err()
+	case <-pendingJob.Finished:
+		b.c.metrics.jobDuration.WithLabelValues(jobResultLabel(pendingJob.Result)).Observe(time.Since(queued).Seconds())
+		b.traceJob(job, attempt, queued, pendingJob)
+		b.jobFinished(pendingJob.Result, pendingJob.PickedBy)
+		b.c.storeActionResult(pendingJob.PickedBy, pendingJob.Result)
+		return b.finishOutput(pendingJob.Result), nil
+	}
+}
+
+// jobOutput пересылает клиенту новую часть вывода выполняющегося джоба.
+//
+// Куски, которые клиент уже получил, отбрасываются. Если кусок пришёл с разрывом,
+// он тоже отбрасывается: пропущенный вывод клиент получит вместе с JobResult.
+func (b *Build) jobOutput(out *api.JobOutput) {
+	b.jobStderr(out)
+
+	// Без клиента вывод некуда отправить, весь вывод клиент получит вместе с JobResult.
+	if !b.attached() {
+		return
+	}
+
+	// Вывод тестовых джобов и их шардов клиент получает целиком в TestShard и JobResult.
+	if job, ok := b.job(out.ID); !ok || job.Test != nil {
+		return
+	}
+
+	b.outMu.Lock()
+	defer b.outMu.Unlock()
+
+	sent, ok := b.outputs[out.ID]
+	if !ok {
+		return
+	}
+
+	stdout, stdoutOK := unsent(out.Stdout, out.StdoutOffset, sent[0])
+	stderr, stderrOK := unsent(out.Stderr, out.StderrOffset, sent[1])
+	if !stdoutOK || !stderrOK || len(stdout)+len(stderr) == 0 {
+		return
+	}
+
+	update := &api.StatusUpdate{JobOutput: &api.JobOutput{
+		ID:           out.ID,
+		StdoutOffset: sent[0],
+		StderrOffset: sent[1],
+		Stdout:       stdout,
+		Stderr:       stderr,
+	}}
+
+	if err := b.update(update); err != nil {
+		b.l.Warn("failed to send job output", zap.String("job_id", out.ID.String()), zap.Error(err))
+		return
+	}
+
+	sent[0] += int64(len(stdout))
+	sent[1] += int64(len(stderr))
+}
+
+// unsent возвращает часть data, которую клиент ещё не получил. data начинается со смещения offset,
+// а клиент уже получил sent байт.
+func unsent(data []byte, offset, sent int64) ([]byte, bool) {
+	if offset > sent {
+		return nil, false
+	}
+
+	end := offset + int64(len(data))
+	if end <= sent {
+		return nil, true
+	}
+	return data[sent-offset:], true
+}
+
+// finishOutput перестаёт пересылать вывод джоба и убирает из результата вывод,
+// который клиент уже получил.
+func (b *Build) finishOutput(res *api.JobResult) *api.JobResult {
+	b.outMu.Lock()
+	sent := b.outputs[res.ID]
+	delete(b.outputs, res.ID)
+	b.outMu.Unlock()
+
+	if sent == nil || sent[0]+sent[1] == 0 {
+		return res
+	}
+
+	trimmed := *res
+	trimmed.Stdout = res.Stdout[min(sent[0], int64(len(res.Stdout))):]
+	trimmed.Stderr = res.Stderr[min(sent[1], int64(len(res.Stderr))):]
+	return &trimmed
+}
+
+// traceJob записывает в трейс сборки запуск джоба.
+func (b *Build) traceJob(job *build.Job, attempt int, queued time.Time, pendingJob *scheduler.PendingJob) {
+	if b.trace == nil {
+		return
+	}
+
+	res := pendingJob.Result
+	finished := time.Now()
+
+	// Джоб мог начаться раньше, чем его запланировала эта сборка, если его уже выполняла другая.
+	picked := pendingJob.PickedAt
+	if picked.IsZero() {
+		picked = finished
+	}
+	if picked.Before(queued) {
+		picked = queued
+	}
+
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+
+	b.trace.Jobs = append(b.trace.Jobs, trace.Job{
+		ID:       job.ID,
+		Name:     job.Name,
+		Deps:     job.Deps,
+		Attempt:  attempt,
+		Worker:   pendingJob.PickedBy.String(),
+		Queued:   queued,
+		Picked:   picked,
+		Finished: finished,
+		Timings:  res.Timings,
+		Failed:   res.Error != nil || res.ExitCode != 0,
+	})
+}
+
+// sendTrace отправляет клиенту трейс сборки.
+func (b *Build) sendTrace() error {
+	b.traceMu.Lock()
+	b.trace.End = time.Now()
+	t := *b.trace
+	b.traceMu.Unlock()
+
+	return b.update(&api.StatusUpdate{Trace: &t})
+}
+
+func (b *Build) setJobState(id build.ID, state JobState) {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	st, ok := b.jobStates[id]
+	if !ok {
+		return
+	}
+
+	st.State = state
+	st.Worker = ""
+	st.stderrSeen = 0
+	st.StderrTail = ""
+}
+
+// jobStderr дописывает в StderrTail новую часть stderr выполняющегося джоба.
+func (b *Build) jobStderr(out *api.JobOutput) {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	st, ok := b.jobStates[out.ID]
+	if !ok || st.State != JobQueued {
+		return
+	}
+
+	if stderr, ok := unsent(out.Stderr, out.StderrOffset, st.stderrSeen); ok {
+		st.StderrTail = appendTail(st.StderrTail, stderr)
+		st.stderrSeen += int64(len(stderr))
+	}
+}
+
+// jobFinished записывает в состояние джоба его результат. res содержит полный вывод джоба.
+func (b *Build) jobFinished(res *api.JobResult, workerID api.WorkerID) {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	st, ok := b.jobStates[res.ID]
+	if !ok {
+		return
+	}
+
+	st.State = JobFinished
+	if res.Error != nil || res.ExitCode != 0 {
+		st.State = JobFailed
+	}
+
+	st.Worker = workerID
+	st.ExitCode = res.ExitCode
+	st.Error = ""
+	if res.Error != nil {
+		st.Error = *res.Error
+	}
+	st.StderrTail = appendTail("", res.Stderr)
+}
+
+// status возвращает состояние сборки. running сообщает, какие джобы выполняют воркеры;
+// без running незавершённые джобы считаются отменёнными.
+func (b *Build) status(running map[build.ID]api.WorkerID) BuildStatus {
+	b.graphMu.RLock()
+	defer b.graphMu.RUnlock()
+
+	st := BuildStatus{
+		ID:       b.ID,
+		Priority: b.priority.String(),
+		Started:  b.started,
+		Jobs:     make([]JobStatus, 0, len(b.graph.Jobs)),
+	}
+
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	for _, job := range b.graph.Jobs {
+		js := b.jobStates[job.ID].JobStatus
+
+		switch {
+		case js.State == JobFinished || js.State == JobFailed:
+		case running == nil:
+			js.State = JobCancelled
+		case js.State == JobQueued && running[job.ID] != "":
+			js.State = JobRunning
+			js.Worker = running[job.ID]
+		}
+
+		st.Jobs = append(st.Jobs, js)
+	}
+
+	return st
+}
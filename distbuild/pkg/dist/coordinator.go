@@ -3,32 +3,556 @@
 package dist
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
 	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
 )
 
+const (
+	// pickTimeout ограничивает время, которое heartbeat воркера ждёт нового джоба.
+	pickTimeout = time.Second
+
+	// attachTimeout - сколько восстановленная из журнала сборка ждёт клиента. Сборка, к которой
+	// никто не подключился, отменяется.
+	attachTimeout = time.Minute
+)
+
 type Coordinator struct {
+	log       *zap.Logger
+	mux       *http.ServeMux
+	handler   http.Handler
+	auth      *auth.Authenticator
+	http      *http.Client
+	fileCache *filecache.Cache
+	config    scheduler.Config
+	scheduler *scheduler.Scheduler
+
+	actionCache      *artifact.Cache
+	cas              *filecache.Cache
+	remoteGC         *artifact.GCConfig
+	remoteGCInterval time.Duration
+	// stores объединяет сохранение в удалённый кеш результата джоба, который ждут несколько сборок,
+	// а storeSlots ограничивает число одновременных сохранений.
+	stores     singleflight.Group
+	storeSlots chan struct{}
+	metrics    *metrics
+
+	journal   *journal.Journal
+	recovered *journal.State
+
+	// ctx отменяется в Stop и останавливает восстановленные сборки, которые выполняются в resumed.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	resumed sync.WaitGroup
+	// background ждёт фоновые задачи координатора: очистку брошенных загрузок файлов,
+	// очистку и заполнение удалённого кеша.
+	background sync.WaitGroup
+
+	mu     sync.Mutex
+	builds map[build.ID]*Build
+
+	// workers и recent нужны только для статуса координатора.
+	workers map[api.WorkerID]*WorkerStatus
+	recent  []BuildStatus
+}
+
+var defaultConfig = scheduler.Config{
+	CacheTimeout:  time.Millisecond * 10,
+	DepsTimeout:   time.Millisecond * 100,
+	WorkerTimeout: time.Second * 10,
 }
 
-var _ = scheduler.Config{
-	CacheTimeout: time.Millisecond * 10,
-	DepsTimeout:  time.Millisecond * 100,
+// Option задаёт необязательный параметр координатора.
+type Option func(c *Coordinator)
+
+// WithWorkerTimeout задаёт, через сколько времени без heartbeat-ов воркер считается потерянным.
+//
+// Джобы потерянного воркера перезапускаются на других воркерах, а его артефакты
+// больше не используются.
+func WithWorkerTimeout(timeout time.Duration) Option {
+	return func(c *Coordinator) {
+		c.config.WorkerTimeout = timeout
+	}
+}
+
+// WithSpeculativeExecution включает повторный запуск отстающих джобов.
+//
+// Если джоб выполняется в factor раз дольше, чем обычно выполняются джобы с тем же именем,
+// но не меньше minDelay, его копия запускается на свободном воркере. Сборка получает первый
+// результат, а проигравший воркер убивает свою копию.
+func WithSpeculativeExecution(factor float64, minDelay time.Duration) Option {
+	return func(c *Coordinator) {
+		c.config.SpeculativeFactor = factor
+		c.config.SpeculativeMinDelay = minDelay
+	}
+}
+
+// WithAuth включает аутентификацию запросов к координатору через a.
+//
+// Клиенты сборки могут аутентифицироваться токеном, а heartbeat-ы и вывод джобов принимаются
+// только с клиентским сертификатом воркера. Heartbeat отклоняется, если сертификат выдан
+// не на WorkerID из запроса, а вывод джоба - если сертификат выдан не на воркер, который его выполняет.
+func WithAuth(a *auth.Authenticator) Option {
+	return func(c *Coordinator) {
+		c.auth = a
+	}
+}
+
+// WithHTTPClient задаёт http.Client, через который координатор проксирует воркерам запросы
+// артефактов, например с клиентским сертификатом из auth.NewHTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Coordinator) {
+		c.http = client
+	}
+}
+
+// WithRemoteCache включает удалённый кеш по HTTP протоколу Bazel.
+//
+// Координатор раздаёт /ac/ из actionCache и /cas/ из cas и сохраняет в /ac/ результаты всех
+// успешных джобов, а их выходы - в /cas/, чтобы клиент мог пропускать их в следующих сборках.
+// cas не должен совпадать с filecache.Cache исходных файлов.
+func WithRemoteCache(actionCache *artifact.Cache, cas *filecache.Cache) Option {
+	return func(c *Coordinator) {
+		c.actionCache = actionCache
+		c.cas = cas
+	}
+}
+
+// WithRemoteCacheGC включает периодическую очистку удалённого кеша.
+//
+// Раз в interval координатор удаляет давно не использованные записи /ac/ и блобы /cas/, пока каждый
+// из кешей не уложится в ограничения config. Без этой опции удалённый кеш растёт без ограничений.
+func WithRemoteCacheGC(config artifact.GCConfig, interval time.Duration) Option {
+	return func(c *Coordinator) {
+		c.remoteGC = &config
+		c.remoteGCInterval = interval
+	}
+}
+
+// WithJournal включает журнал j и восстанавливает состояние координатора state,
+// которое вернул journal.Open.
+//
+// Координатор продолжает незавершённые сборки из state и ждёт, пока к ним подключатся клиенты
+// через AttachBuild. Чтобы при остановке сборки не попали в журнал как завершённые,
+// журнал нужно закрыть до Stop и до остановки HTTP сервера.
+func WithJournal(j *journal.Journal, state *journal.State) Option {
+	return func(c *Coordinator) {
+		c.journal = j
+		c.recovered = state
+	}
 }
 
 func NewCoordinator(
 	log *zap.Logger,
 	fileCache *filecache.Cache,
+	opts ...Option,
 ) *Coordinator {
-	panic("implement me")
+	c := &Coordinator{
+		log:        log,
+		mux:        http.NewServeMux(),
+		http:       http.DefaultClient,
+		fileCache:  fileCache,
+		config:     defaultConfig,
+		builds:     make(map[build.ID]*Build),
+		storeSlots: make(chan struct{}, maxPendingStores),
+		workers:    make(map[api.WorkerID]*WorkerStatus),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.config.OnWorkerLost = c.onWorkerLost
+	c.scheduler = scheduler.NewScheduler(log.Named("scheduler"), c.config, time.After)
+	if c.recovered != nil {
+		c.recover(c.recovered)
+		c.recovered = nil
+	}
+	c.handler = c.mux
+	if c.auth != nil {
+		c.handler = c.auth.Handler(c.mux)
+	}
+
+	c.metrics = c.newMetrics()
+	c.mux.Handle("/metrics", c.metrics.handler())
+	c.mux.HandleFunc("/status", c.serveStatus)
+	c.mux.HandleFunc("/dashboard", c.serveDashboard)
+
+	api.NewBuildService(log, c).Register(c.mux)
+	api.NewHeartbeatHandler(log, c).Register(c.mux)
+	api.NewOutputHandler(log, c).Register(c.mux)
+	files := filecache.NewHandler(log, fileCache)
+	files.Register(c.mux)
+	c.registerArtifactProxy()
+
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		files.Run(c.ctx)
+	}()
+
+	if c.actionCache != nil {
+		remotecache.NewHandler(log.Named("remotecache"), c.actionCache, c.cas).Register(c.mux)
+
+		if c.remoteGC != nil {
+			c.background.Add(1)
+			go func() {
+				defer c.background.Done()
+				c.runRemoteCacheGC(c.ctx)
+			}()
+		}
+	}
+
+	return c
+}
+
+func (c *Coordinator) Stop() {
+	c.mu.Lock()
+	c.cancel()
+	c.mu.Unlock()
+
+	c.resumed.Wait()
+	c.background.Wait()
+	c.scheduler.Stop()
+}
+
+// record дописывает запись в журнал, если он включён.
+func (c *Coordinator) record(rec *journal.Record) {
+	if c.journal == nil {
+		return
+	}
+
+	if err := c.journal.Append(rec); err != nil && !errors.Is(err, journal.ErrClosed) {
+		c.log.Error("failed to write journal", zap.Error(err))
+	}
+}
+
+// onWorkerLost записывает в журнал потерю воркера, чтобы перезапущенный координатор
+// не искал артефакты на воркере, который их уже мог потерять.
+func (c *Coordinator) onWorkerLost(workerID api.WorkerID) {
+	c.record(&journal.Record{WorkerLost: &journal.WorkerEvent{WorkerID: workerID}})
+}
+
+// recover восстанавливает расположение артефактов и продолжает незавершённые сборки.
+func (c *Coordinator) recover(state *journal.State) {
+	for workerID, artifacts := range state.Artifacts {
+		// Воркер, который не вернётся, шедулер потеряет через WorkerTimeout вместе с его артефактами.
+		c.scheduler.RegisterWorker(workerID)
+		for id := range artifacts {
+			c.scheduler.AddArtifact(workerID, id)
+		}
+	}
+
+	for _, recovered := range state.Builds {
+		c.resume(recovered)
+	}
+
+	c.log.Info("state recovered from journal",
+		zap.Int("num_workers", len(state.Artifacts)),
+		zap.Int("num_builds", len(state.Builds)))
+}
+
+// resume продолжает сборку из журнала без клиента. Клиент подключается к ней через AttachBuild.
+func (c *Coordinator) resume(recovered *journal.Build) {
+	ctx, cancel := context.WithCancel(c.ctx)
+
+	b := newBuild(c, recovered.ID, recovered.Request, nil, cancel)
+	b.restore(recovered)
+	c.registerBuild(b, recovered.Request)
+
+	b.l.Info("build resumed", zap.Int("num_finished", len(b.results)))
+
+	c.resumed.Add(1)
+	go func() {
+		defer c.resumed.Done()
+		defer cancel()
+
+		timer := time.AfterFunc(attachTimeout, func() {
+			if !b.attached() {
+				b.l.Info("no client attached to resumed build")
+				cancel()
+			}
+		})
+
+		err := b.Run(ctx)
+		timer.Stop()
+
+		// Сборка, которую остановил Stop, должна остаться в журнале незавершённой.
+		if c.ctx.Err() == nil {
+			c.finishBuild(b, err)
+		} else {
+			c.unregisterBuild(b)
+		}
+
+		b.result = err
+		close(b.finished)
+	}()
+}
+
+func (c *Coordinator) registerBuild(b *Build, request *api.BuildRequest) {
+	c.mu.Lock()
+	c.builds[b.ID] = b
+	c.mu.Unlock()
+
+	c.scheduler.RegisterBuild(b.ID, request.Priority.Weight())
 }
 
-func (c *Coordinator) Stop() {}
+func (c *Coordinator) unregisterBuild(b *Build) {
+	c.scheduler.UnregisterBuild(b.ID)
+
+	c.mu.Lock()
+	delete(c.builds, b.ID)
+	c.mu.Unlock()
+}
+
+// finishBuild убирает сборку с координатора и отмечает её в журнале завершённой.
+func (c *Coordinator) finishBuild(b *Build, err error) {
+	c.rememberBuild(b, err)
+	c.unregisterBuild(b)
+
+	finished := &journal.BuildFinished{BuildID: b.ID}
+	if err != nil {
+		finished.Error = err.Error()
+	}
+	c.record(&journal.Record{BuildFinished: finished})
+}
 
 func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+	c.handler.ServeHTTP(w, r)
+}
+
+// NewGRPCServer создаёт gRPC сервер с API сборок и heartbeat-ов. Вывод джобов, файлы
+// и статус координатора доступны только по HTTP.
+//
+// Если config не nil, сервер принимает только TLS соединения. С WithAuth сервер аутентифицирует
+// вызовы так же, как HTTP API, поэтому требует config.
+func (c *Coordinator) NewGRPCServer(config *tls.Config, opts ...grpc.ServerOption) (*grpc.Server, error) {
+	switch {
+	case c.auth != nil && config == nil:
+		return nil, errors.New("dist: gRPC API with authentication requires tls")
+	case c.auth != nil:
+		opts = append(c.auth.ServerOptions(config), opts...)
+	case config != nil:
+		opts = append([]grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}, opts...)
+	}
+
+	s := grpc.NewServer(opts...)
+	api.NewBuildGRPCServer(c.log, c).Register(s)
+	api.NewHeartbeatGRPCServer(c.log, c).Register(s)
+	return s, nil
+}
+
+// checkWorker проверяет, что запрос отправил воркер workerID. Пустой workerID подходит любому воркеру.
+func (c *Coordinator) checkWorker(ctx context.Context, workerID api.WorkerID) error {
+	if c.auth == nil {
+		return nil
+	}
+
+	peer, ok := auth.PeerFromContext(ctx)
+	switch {
+	case !ok || peer.Certificate == nil:
+		return fmt.Errorf("%w: worker certificate required", auth.ErrForbidden)
+	case workerID != "" && !peer.HasURI(workerID.String()):
+		return fmt.Errorf("%w: certificate is not issued to worker %s", auth.ErrForbidden, workerID)
+	}
+	return nil
+}
+
+func (c *Coordinator) missingFiles(graph *build.Graph) ([]build.ID, error) {
+	var missing []build.ID
+	for id := range graph.SourceFiles {
+		// Файл под локом на запись ещё заливается, возможно оборванной загрузкой прошлой сборки.
+		// Клиент продолжит её через /file/upload.
+		_, unlock, err := c.fileCache.Get(id)
+		if errors.Is(err, filecache.ErrNotFound) || errors.Is(err, filecache.ErrWriteLocked) {
+			missing = append(missing, id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		unlock()
+	}
+	return missing, nil
+}
+
+func (c *Coordinator) StartBuild(ctx context.Context, request *api.BuildRequest, w api.StatusWriter) error {
+	if err := request.Graph.Validate(); err != nil {
+		return fmt.Errorf("invalid build graph: %w", err)
+	}
+
+	if err := c.checkCached(request); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b := newBuild(c, build.NewID(), request, w, cancel)
+
+	missing, err := c.missingFiles(&request.Graph)
+	if err != nil {
+		return err
+	}
+
+	c.registerBuild(b, request)
+	c.record(&journal.Record{BuildStarted: &journal.BuildStarted{BuildID: b.ID, Request: request}})
+
+	c.log.Info("build started",
+		zap.String("build_id", b.ID.String()),
+		zap.Int("num_jobs", len(request.Graph.Jobs)),
+		zap.Stringer("priority", request.Priority),
+		zap.Int("missing_files", len(missing)))
+
+	err = w.Started(&api.BuildStarted{ID: b.ID, MissingFiles: missing})
+	if err == nil {
+		err = b.Run(ctx)
+	}
+
+	c.finishBuild(b, err)
+	return err
+}
+
+// AttachBuild подключает клиента к сборке, которую координатор восстановил из журнала.
+//
+// Разрыв соединения с клиентом отменяет сборку. AttachBuild возвращается, когда сборка завершилась.
+func (c *Coordinator) AttachBuild(ctx context.Context, request *api.AttachRequest, w api.StatusWriter) error {
+	c.mu.Lock()
+	b, ok := c.builds[request.BuildID]
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("build %s not found", request.BuildID)
+	}
+
+	if err := b.attach(request.Received, w); err != nil {
+		return err
+	}
+
+	b.l.Info("client attached", zap.Int("received", request.Received))
+
+	select {
+	case <-ctx.Done():
+		b.l.Info("client disconnected")
+		b.cancel()
+
+		// Сборка пишет в w, пока не завершится.
+		<-b.finished
+		return errBuildCancelled
+	case <-b.finished:
+		return b.result
+	}
+}
+
+func (c *Coordinator) SignalBuild(ctx context.Context, buildID build.ID, signal *api.SignalRequest) (*api.SignalResponse, error) {
+	c.mu.Lock()
+	b, ok := c.builds[buildID]
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("build %s not found", buildID)
+	}
+
+	if signal.UploadDone != nil {
+		c.record(&journal.Record{UploadDone: &journal.BuildEvent{BuildID: buildID}})
+		b.uploadDone()
+	}
+
+	if signal.Cancel != nil {
+		b.l.Info("build cancelled by client")
+		b.cancel()
+	}
+
+	return &api.SignalResponse{}, nil
+}
+
+// JobOutput пересылает вывод джоба всем сборкам, которые его ждут.
+//
+// С аутентификацией вывод принимается только от воркера, который выполняет джоб.
+func (c *Coordinator) JobOutput(ctx context.Context, output *api.JobOutput) error {
+	if err := c.checkWorker(ctx, ""); err != nil {
+		return err
+	}
+
+	if c.auth != nil {
+		runners := c.scheduler.JobRunners(output.ID)
+		if !slices.ContainsFunc(runners, func(workerID api.WorkerID) bool { return c.checkWorker(ctx, workerID) == nil }) {
+			return fmt.Errorf("%w: job %s is not running on this worker", auth.ErrForbidden, output.ID)
+		}
+	}
+
+	c.mu.Lock()
+	builds := make([]*Build, 0, len(c.builds))
+	for _, b := range c.builds {
+		builds = append(builds, b)
+	}
+	c.mu.Unlock()
+
+	for _, b := range builds {
+		b.jobOutput(output)
+	}
+	return nil
+}
+
+func (c *Coordinator) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+	if err := c.checkWorker(ctx, req.WorkerID); err != nil {
+		return nil, err
+	}
+
+	c.scheduler.RegisterWorker(req.WorkerID)
+	c.onHeartbeat(req)
+
+	// Артефакты записываются в журнал раньше результатов джобов, которые их создали.
+	for _, id := range req.AddedArtifacts {
+		c.scheduler.AddArtifact(req.WorkerID, id)
+		c.record(&journal.Record{ArtifactAdded: &journal.ArtifactLocation{WorkerID: req.WorkerID, ArtifactID: id}})
+	}
+
+	for i := range req.FinishedJob {
+		res := req.FinishedJob[i]
+		c.scheduler.OnJobComplete(req.WorkerID, res.ID, &res)
+	}
+	c.scheduler.SyncRunningJobs(req.WorkerID, req.RunningJobs)
+
+	for _, id := range req.EvictedArtifacts {
+		c.scheduler.OnArtifactEvicted(req.WorkerID, id)
+		c.record(&journal.Record{ArtifactEvicted: &journal.ArtifactLocation{WorkerID: req.WorkerID, ArtifactID: id}})
+	}
+
+	rsp := &api.HeartbeatResponse{
+		JobsToRun: map[build.ID]api.JobSpec{},
+	}
+
+	if req.FreeSlots > 0 {
+		pickCtx, cancel := context.WithTimeout(ctx, pickTimeout)
+		defer cancel()
+
+		start := time.Now()
+		if pendingJob := c.scheduler.PickJobFor(pickCtx, req.WorkerID, req.Resources); pendingJob != nil {
+			rsp.JobsToRun[pendingJob.Job.ID] = *pendingJob.Job
+		}
+		rsp.Waited = time.Since(start)
+	}
+
+	rsp.JobsToCancel = c.scheduler.CancelledJobs(req.WorkerID)
+	return rsp, nil
 }
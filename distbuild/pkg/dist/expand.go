@@ -0,0 +1,138 @@
+//go:build !solution
+
+package dist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// maxFragmentSize ограничивает размер фрагмента графа, который координатор читает в память.
+const maxFragmentSize = 64 << 20
+
+// expand скачивает фрагмент графа, который записал джоб, добавляет его джобы в сборку
+// и сообщает о них клиенту. Ошибка означает, что фрагмент не удалось скачать или он невалиден.
+func (b *Build) expand(ctx context.Context, job *build.Job) ([]build.Job, error) {
+	workerID, err := b.locateArtifact(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := b.c.downloadFragment(ctx, workerID, job)
+	if err != nil {
+		return nil, err
+	}
+
+	var fragment build.Graph
+	if err := json.Unmarshal(data, &fragment); err != nil {
+		return nil, fmt.Errorf("%w: %w", build.ErrInvalidFragment, err)
+	}
+
+	b.graphMu.Lock()
+	added, err := b.graph.Expand(job.ID, &fragment)
+	if err == nil {
+		b.addJobs(added)
+	}
+	b.graphMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.l.Info("build graph expanded",
+		zap.String("job_id", job.ID.String()),
+		zap.String("name", job.Name),
+		zap.Int("jobs", len(added)))
+
+	if err := b.update(&api.StatusUpdate{JobsAdded: &api.JobsAdded{Generator: job.ID, Jobs: added}}); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// addJobs добавляет в jobs и jobStates джобы из фрагмента графа, которые уже есть в b.graph.Jobs.
+// Вызывающий держит graphMu.
+func (b *Build) addJobs(jobs []build.Job) {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	for i := range jobs {
+		job := &jobs[i]
+		b.jobs[job.ID] = job
+		b.jobStates[job.ID] = &jobState{JobStatus: JobStatus{ID: job.ID, Name: job.Name, State: JobWaiting}}
+	}
+}
+
+// fragmentFailed превращает успешный результат джоба в ошибку, потому что его фрагмент графа
+// не удалось добавить в сборку.
+func (b *Build) fragmentFailed(job *build.Job, res *api.JobResult, err error) *api.JobResult {
+	msg := fmt.Sprintf("graph fragment %s: %v", job.Fragment, err)
+
+	b.l.Warn("failed to expand build graph",
+		zap.String("job_id", job.ID.String()),
+		zap.String("name", job.Name),
+		zap.Error(err))
+
+	b.stateMu.Lock()
+	if st, ok := b.jobStates[job.ID]; ok {
+		st.State = JobFailed
+		st.Error = msg
+	}
+	b.stateMu.Unlock()
+
+	failed := *res
+	failed.Error = &msg
+	return &failed
+}
+
+// downloadFragment скачивает с воркера workerID файл фрагмента графа из выходной директории джоба.
+func (c *Coordinator) downloadFragment(ctx context.Context, workerID api.WorkerID, job *build.Job) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "distbuild-fragment-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	name := path.Join(artifact.JobOutputDir, job.Fragment)
+	include := []string{escapePattern(name)}
+
+	_, err = artifact.DownloadFiles(ctx, workerID.String(), dir, job.ID, include, artifact.DownloadOptions{Client: c.http})
+	if err != nil {
+		return nil, err
+	}
+
+	// Фрагмент читается процессом координатора, поэтому символическая ссылка из выхода джоба
+	// не должна открыть ему чужой файл.
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	st, err := os.Lstat(file)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("job did not write %s", job.Fragment)
+	case err != nil:
+		return nil, err
+	case !st.Mode().IsRegular():
+		return nil, fmt.Errorf("%s is not a regular file", job.Fragment)
+	case st.Size() > maxFragmentSize:
+		return nil, fmt.Errorf("%s is larger than %d bytes", job.Fragment, maxFragmentSize)
+	}
+
+	return os.ReadFile(file)
+}
+
+// escapePattern экранирует в path символы шаблона path.Match.
+func escapePattern(path string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`).Replace(path)
+}
@@ -0,0 +1,86 @@
+//go:build !solution
+
+package dist
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// metrics описывает метрики координатора. Метки не содержат ID сборок и джобов,
+// поэтому число временных рядов не растёт вместе с числом сборок.
+type metrics struct {
+	registry *prometheus.Registry
+
+	// jobDuration размечен result: success, failed, error, cached, worker_lost.
+	jobDuration *prometheus.HistogramVec
+}
+
+func (c *Coordinator) newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "distbuild",
+			Subsystem: "coordinator",
+			Name:      "job_duration_seconds",
+			Help:      "Time from scheduling a job to receiving its result, by result.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"result"}),
+	}
+
+	gauge := func(name, help string, value func() int, labels prometheus.Labels) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "distbuild",
+			Subsystem:   "coordinator",
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			return float64(value())
+		})
+	}
+
+	m.registry.MustRegister(
+		m.jobDuration,
+		gauge("queued_jobs", "Jobs waiting for a worker.",
+			c.scheduler.QueuedJobsLen, prometheus.Labels{"queue": "all"}),
+		gauge("queued_jobs", "Jobs waiting for a worker.",
+			c.scheduler.GlobalQueueLen, prometheus.Labels{"queue": "global"}),
+		gauge("workers", "Registered workers that are not considered lost.",
+			c.scheduler.WorkersLen, nil),
+		gauge("builds", "Builds in progress.", func() int {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return len(c.builds)
+		}, nil),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func jobResultLabel(res *api.JobResult) string {
+	switch {
+	case res.WorkerLost:
+		return "worker_lost"
+	case res.Timings != nil && res.Timings.CacheHit:
+		return "cached"
+	case res.Error != nil:
+		return "error"
+	case res.ExitCode != 0:
+		return "failed"
+	default:
+		return "success"
+	}
+}
@@ -0,0 +1,149 @@
+//go:build !solution
+
+package dist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+// checkCached проверяет, что джобы из request.Cached есть в графе и их результаты есть в удалённом кеше.
+func (c *Coordinator) checkCached(request *api.BuildRequest) error {
+	if len(request.Cached) == 0 {
+		return nil
+	}
+
+	if c.actionCache == nil {
+		return errors.New("cached jobs require remote cache, but it is disabled")
+	}
+
+	jobs := make(map[build.ID]struct{}, len(request.Graph.Jobs))
+	for _, job := range request.Graph.Jobs {
+		jobs[job.ID] = struct{}{}
+	}
+
+	for _, id := range request.Cached {
+		if _, ok := jobs[id]; !ok {
+			return fmt.Errorf("cached job %s is not in build graph", id)
+		}
+
+		if !remotecache.HasActionResult(c.actionCache, c.cas, id) {
+			return fmt.Errorf("cached job %s is missing from remote cache", id)
+		}
+	}
+	return nil
+}
+
+// maxPendingStores ограничивает число результатов джобов, которые координатор одновременно
+// сохраняет в удалённый кеш.
+const maxPendingStores = 4
+
+// storeActionResult в фоне сохраняет результат успешного джоба в удалённый кеш. Выходы джоба
+// скачиваются с воркера workerID и кладутся в /cas/.
+//
+// Сборка не ждёт сохранения. Если координатор уже сохраняет maxPendingStores результатов,
+// результат не сохраняется: его сохранит следующая сборка, которая выполнит джоб.
+// Ошибка не мешает сборке, поэтому только пишется в лог.
+func (c *Coordinator) storeActionResult(workerID api.WorkerID, res *api.JobResult) {
+	if c.actionCache == nil || res.Error != nil || res.ExitCode != 0 {
+		return
+	}
+
+	select {
+	case c.storeSlots <- struct{}{}:
+	default:
+		c.log.Debug("remote cache is busy, action result is not stored", zap.String("job_id", res.ID.String()))
+		return
+	}
+
+	// Stop отменяет c.ctx под c.mu, поэтому после Stop новые задачи не попадают в background.
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		<-c.storeSlots
+		return
+	}
+	c.background.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.background.Done()
+		defer func() { <-c.storeSlots }()
+
+		c.storeActionResultSync(c.ctx, workerID, res)
+	}()
+}
+
+func (c *Coordinator) storeActionResultSync(ctx context.Context, workerID api.WorkerID, res *api.JobResult) {
+	_, err, _ := c.stores.Do(res.ID.String(), func() (any, error) {
+		if remotecache.HasActionResult(c.actionCache, c.cas, res.ID) {
+			return nil, nil
+		}
+
+		result := &remotecache.ActionResult{
+			StdoutRaw: res.Stdout,
+			StderrRaw: res.Stderr,
+		}
+		if err := c.storeOutputs(ctx, workerID, res.ID, result); err != nil {
+			return nil, err
+		}
+		return nil, remotecache.StoreActionResult(c.actionCache, c.cas, res.ID, result)
+	})
+	if err != nil && ctx.Err() == nil {
+		c.log.Warn("failed to store action result", zap.String("job_id", res.ID.String()), zap.Error(err))
+	}
+}
+
+// storeOutputs скачивает с воркера workerID выходную директорию джоба и кладёт её файлы в /cas/.
+func (c *Coordinator) storeOutputs(ctx context.Context, workerID api.WorkerID, jobID build.ID, result *remotecache.ActionResult) error {
+	dir, err := os.MkdirTemp("", "distbuild-outputs-")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	include := []string{escapePattern(artifact.JobOutputDir)}
+	if _, err := artifact.DownloadFiles(ctx, workerID.String(), dir, jobID, include, artifact.DownloadOptions{Client: c.http}); err != nil {
+		return err
+	}
+
+	return remotecache.StoreOutputs(c.cas, filepath.Join(dir, artifact.JobOutputDir), result)
+}
+
+// runRemoteCacheGC периодически чистит удалённый кеш, пока не отменён ctx.
+func (c *Coordinator) runRemoteCacheGC(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.remoteGCInterval):
+		}
+
+		evicted, err := c.actionCache.GC(*c.remoteGC)
+		if len(evicted) != 0 {
+			c.log.Info("action results evicted", zap.Int("count", len(evicted)))
+		}
+		if err != nil {
+			c.log.Warn("action cache gc failed", zap.Error(err))
+		}
+
+		evictedBlobs, err := c.cas.GC(*c.remoteGC)
+		if len(evictedBlobs) != 0 {
+			c.log.Info("blobs evicted", zap.Int("count", len(evictedBlobs)))
+		}
+		if err != nil {
+			c.log.Warn("cas gc failed", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,213 @@
+//go:build !solution
+
+package dist
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+const (
+	// recentBuilds - сколько завершённых сборок показывает статус координатора.
+	recentBuilds = 20
+
+	// stderrTailSize - сколько последних байт stderr каждого джоба показывает статус координатора.
+	stderrTailSize = 4 << 10
+)
+
+// Status - состояние координатора, которое отдают GET /status в json и GET /dashboard в html.
+type Status struct {
+	// Builds содержит сначала выполняющиеся сборки, потом недавно завершённые, от новых к старым.
+	Builds  []BuildStatus
+	Workers []WorkerStatus
+	Queue   []scheduler.QueuedJob
+}
+
+type BuildStatus struct {
+	ID       build.ID
+	Priority string
+	Started  time.Time
+
+	// Finished и Error заполняются у завершённых сборок.
+	Finished *time.Time `json:",omitempty"`
+	Error    string     `json:",omitempty"`
+
+	// Jobs перечислены в порядке графа сборки.
+	Jobs []JobStatus
+}
+
+type JobState string
+
+const (
+	// JobWaiting - джоб ждёт свои зависимости.
+	JobWaiting JobState = "waiting"
+	// JobQueued - джоб ждёт свободного воркера.
+	JobQueued JobState = "queued"
+	// JobRunning - воркер сообщил в heartbeat-е, что выполняет джоб.
+	JobRunning   JobState = "running"
+	JobFinished  JobState = "finished"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+type JobStatus struct {
+	ID    build.ID
+	Name  string
+	State JobState
+
+	Worker   api.WorkerID `json:",omitempty"`
+	ExitCode int          `json:",omitempty"`
+	Error    string       `json:",omitempty"`
+
+	// StderrTail - последние байты stderr джоба, в том числе выполняющегося.
+	StderrTail string `json:",omitempty"`
+}
+
+// WorkerStatus - состояние воркера из его последнего heartbeat-а.
+type WorkerStatus struct {
+	ID            api.WorkerID
+	LastHeartbeat time.Time
+
+	// Alive равен false, если от воркера нет heartbeat-ов дольше WorkerTimeout.
+	Alive bool
+
+	FreeSlots   int
+	Resources   api.WorkerResources
+	RunningJobs []build.ID
+}
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"short": func(id build.ID) string {
+		return id.String()[:12]
+	},
+	"since": func(t time.Time) string {
+		return time.Since(t).Round(time.Second).String()
+	},
+	"duration": func(from time.Time, to *time.Time) string {
+		return to.Sub(from).Round(time.Millisecond).String()
+	},
+}).Parse(dashboardHTML))
+
+// appendTail дописывает data в tail и оставляет последние stderrTailSize байт.
+func appendTail(tail string, data []byte) string {
+	tail += string(data)
+	if len(tail) > stderrTailSize {
+		tail = tail[len(tail)-stderrTailSize:]
+	}
+	return tail
+}
+
+// onHeartbeat запоминает состояние воркера для статуса координатора.
+func (c *Coordinator) onHeartbeat(req *api.HeartbeatRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workers[req.WorkerID] = &WorkerStatus{
+		ID:            req.WorkerID,
+		LastHeartbeat: time.Now(),
+		FreeSlots:     req.FreeSlots,
+		Resources:     req.Resources,
+		RunningJobs:   slices.Clone(req.RunningJobs),
+	}
+}
+
+// rememberBuild сохраняет состояние завершённой сборки для статуса координатора.
+func (c *Coordinator) rememberBuild(b *Build, err error) {
+	st := b.status(nil)
+
+	finished := time.Now()
+	st.Finished = &finished
+	if err != nil {
+		st.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.recent = append(c.recent, st)
+	if len(c.recent) > recentBuilds {
+		c.recent = slices.Delete(c.recent, 0, len(c.recent)-recentBuilds)
+	}
+}
+
+func (c *Coordinator) status() *Status {
+	c.mu.Lock()
+	builds := make([]*Build, 0, len(c.builds))
+	for _, b := range c.builds {
+		builds = append(builds, b)
+	}
+
+	workers := make([]WorkerStatus, 0, len(c.workers))
+	for _, w := range c.workers {
+		workers = append(workers, *w)
+	}
+
+	recent := slices.Clone(c.recent)
+	c.mu.Unlock()
+
+	running := make(map[build.ID]api.WorkerID)
+	for i := range workers {
+		w := &workers[i]
+		w.Alive = c.config.WorkerTimeout == 0 || time.Since(w.LastHeartbeat) < c.config.WorkerTimeout
+		for _, id := range w.RunningJobs {
+			running[id] = w.ID
+		}
+	}
+	slices.SortFunc(workers, func(a, b WorkerStatus) int {
+		return strings.Compare(string(a.ID), string(b.ID))
+	})
+
+	st := &Status{
+		Workers: workers,
+		Queue:   c.scheduler.QueuedJobs(),
+	}
+
+	for _, b := range builds {
+		st.Builds = append(st.Builds, b.status(running))
+	}
+	slices.SortFunc(st.Builds, func(a, b BuildStatus) int {
+		return b.Started.Compare(a.Started)
+	})
+
+	slices.Reverse(recent)
+	st.Builds = append(st.Builds, recent...)
+	return st
+}
+
+func (c *Coordinator) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.status()); err != nil {
+		c.log.Warn("failed to write status", zap.Error(err))
+	}
+}
+
+func (c *Coordinator) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, c.status()); err != nil {
+		c.log.Warn("failed to render dashboard", zap.Error(err))
+	}
+}
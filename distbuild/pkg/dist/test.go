@@ -0,0 +1,128 @@
+//go:build !solution
+
+package dist
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// runTest запускает шарды тестового джоба параллельно и перезапускает упавшие шарды
+// не больше Test.Retries раз.
+//
+// Результат каждого шарда отправляется клиенту в TestShard, а результат джоба собирается
+// из результатов шардов.
+func (b *Build) runTest(ctx context.Context, job *build.Job) (*api.JobResult, error) {
+	shards, err := b.graph.TestShards(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// Статус координатора показывает не шарды, а джоб целиком.
+	if len(shards) > 1 {
+		b.setJobState(job.ID, JobQueued)
+	}
+
+	results := make([]*api.TestShard, len(shards))
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i := range shards {
+		g.Go(func() error {
+			shard, err := b.runShard(ctx, job, &shards[i], i, len(shards))
+			results[i] = shard
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	res := mergeShards(job.ID, results)
+	if len(shards) > 1 {
+		b.jobFinished(res, "")
+	}
+	return res, nil
+}
+
+func (b *Build) runShard(ctx context.Context, job, shardJob *build.Job, i, n int) (*api.TestShard, error) {
+	shard := &api.TestShard{JobID: job.ID, Shard: i, Shards: n}
+	if n > 1 {
+		shard.Tests = shardJob.Test.Names
+	}
+
+	var failed []string
+	for {
+		res, err := b.runJob(ctx, shardJob)
+		if err != nil {
+			return nil, err
+		}
+
+		shard.Attempts++
+		shard.Failed = build.FailedTests(res.Stdout)
+		shard.Result = *res
+
+		if res.Error == nil && res.ExitCode == 0 {
+			shard.Flaky = failed
+			break
+		}
+
+		if shard.Attempts > job.Test.Retries {
+			break
+		}
+
+		for _, name := range shard.Failed {
+			if !slices.Contains(failed, name) {
+				failed = append(failed, name)
+			}
+		}
+
+		b.l.Info("retrying test shard",
+			zap.String("job_id", job.ID.String()),
+			zap.String("name", shardJob.Name),
+			zap.Int("attempt", shard.Attempts),
+			zap.Strings("failed", shard.Failed))
+	}
+
+	if err := b.update(&api.StatusUpdate{TestShard: shard}); err != nil {
+		return nil, err
+	}
+	return shard, nil
+}
+
+// mergeShards собирает результат тестового джоба id из результатов его шардов. Вывод шардов
+// склеивается по порядку, а код возврата и ошибка берутся из первого упавшего шарда.
+func mergeShards(id build.ID, shards []*api.TestShard) *api.JobResult {
+	if len(shards) == 1 {
+		res := shards[0].Result
+		res.FlakyTests = shards[0].Flaky
+		return &res
+	}
+
+	res := &api.JobResult{ID: id}
+
+	var stdout, stderr bytes.Buffer
+	for _, shard := range shards {
+		stdout.Write(shard.Result.Stdout)
+		stderr.Write(shard.Result.Stderr)
+
+		res.Violations = append(res.Violations, shard.Result.Violations...)
+		res.FlakyTests = append(res.FlakyTests, shard.Flaky...)
+
+		if res.Error == nil && res.ExitCode == 0 {
+			res.ExitCode = shard.Result.ExitCode
+			res.Error = shard.Result.Error
+		}
+	}
+
+	res.Stdout = stdout.Bytes()
+	res.Stderr = stderr.Bytes()
+	return res
+}
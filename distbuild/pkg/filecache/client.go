@@ -3,7 +3,17 @@
 package filecache
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -11,16 +21,268 @@ import (
 )
 
 type Client struct {
+	l        *zap.Logger
+	endpoint string
+	http     *http.Client
 }
 
-func NewClient(l *zap.Logger, endpoint string) *Client {
-	panic("implement me")
+// ClientOption задаёт необязательный параметр клиента.
+type ClientOption func(c *Client)
+
+// WithHTTPClient задаёт http.Client, через который клиент отправляет запросы, например
+// с TLS и токеном из auth.NewHTTPClient. По умолчанию используется http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+func NewClient(l *zap.Logger, endpoint string, opts ...ClientOption) *Client {
+	c := &Client{l: l, endpoint: endpoint, http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *Client) Upload(ctx context.Context, id build.ID, localPath string) error {
-	panic("implement me")
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+"/file?id="+id.String(), f)
+	if err != nil {
+		return err
+	}
+
+	c.l.Debug("uploading file", zap.String("file_id", id.String()), zap.String("path", localPath))
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return fmt.Errorf("upload failed: %s", errorMsg)
+	}
+
+	return nil
+}
+
+// Missing возвращает ID файлов из ids, которых нет в кеше на endpoint.
+func (c *Client) Missing(ctx context.Context, ids []build.ID) ([]build.ID, error) {
+	body, err := json.Marshal(MissingRequest{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/file/missing", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return nil, fmt.Errorf("missing files check failed: %s", errorMsg)
+	}
+
+	var missing MissingResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&missing); err != nil {
+		return nil, err
+	}
+	return missing.Missing, nil
+}
+
+const (
+	// uploadChunkSize - размер куска, который UploadFile отправляет одним запросом.
+	uploadChunkSize = 4 << 20
+
+	// uploadAttempts ограничивает число подряд неудачных запросов в UploadFile.
+	uploadAttempts = 5
+
+	uploadRetryInterval = 100 * time.Millisecond
+)
+
+// UploadFile загружает файл localPath по кускам. path - путь файла в Graph.SourceFiles,
+// endpoint проверяет, что id совпадает с build.FileID(path, содержимое).
+//
+// Если загрузка оборвалась, UploadFile спрашивает у endpoint, сколько байт уже получено,
+// и продолжает с этого места. Загрузку, начатую другим клиентом, UploadFile тоже продолжает.
+func (c *Client) UploadFile(ctx context.Context, id build.ID, path, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := st.Size()
+
+	c.l.Debug("uploading file", zap.String("file_id", id.String()), zap.String("path", localPath))
+
+	offset, complete, err := c.uploadStatus(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	failures := 0
+	for !complete {
+		end := min(offset+uploadChunkSize, size)
+
+		var next int64
+		next, complete, err = c.uploadChunk(ctx, id, path, io.NewSectionReader(f, offset, end-offset), offset, end == size)
+		if err == nil {
+			failures = 0
+			offset = next
+			continue
+		}
+
+		var corrupted *corruptedError
+		if errors.As(err, &corrupted) || ctx.Err() != nil {
+			return err
+		}
+
+		failures++
+		if failures == uploadAttempts {
+			return err
+		}
+
+		c.l.Debug("file upload interrupted, resuming",
+			zap.String("file_id", id.String()),
+			zap.Int64("offset", offset),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(uploadRetryInterval):
+		}
+
+		if offset, complete, err = c.uploadStatus(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type corruptedError struct {
+	msg string
+}
+
+func (e *corruptedError) Error() string {
+	return "upload failed: " + e.msg
+}
+
+func (c *Client) uploadStatus(ctx context.Context, id build.ID) (offset int64, complete bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, c.endpoint+"/file/upload?id="+id.String(), nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("upload status failed: %s", rsp.Status)
+	}
+
+	return parseUploadState(rsp)
+}
+
+func (c *Client) uploadChunk(ctx context.Context, id build.ID, path string, chunk io.Reader, offset int64, final bool) (next int64, complete bool, err error) {
+	query := url.Values{}
+	query.Set("id", id.String())
+	query.Set("path", path)
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	if final {
+		query.Set("final", "1")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.endpoint+"/file/upload?"+query.Encode(), chunk)
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	switch rsp.StatusCode {
+	case http.StatusOK, http.StatusConflict:
+		// На 409 endpoint сообщает, с какого места продолжать.
+		return parseUploadState(rsp)
+	case http.StatusUnprocessableEntity:
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return 0, false, &corruptedError{msg: string(errorMsg)}
+	default:
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return 0, false, fmt.Errorf("upload failed: %s", errorMsg)
+	}
+}
+
+func parseUploadState(rsp *http.Response) (offset int64, complete bool, err error) {
+	if rsp.Header.Get(uploadCompleteHeader) == "1" {
+		return 0, true, nil
+	}
+
+	offset, err = strconv.ParseInt(rsp.Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s: %w", uploadOffsetHeader, err)
+	}
+	return offset, false, nil
 }
 
 func (c *Client) Download(ctx context.Context, localCache *Cache, id build.ID) error {
-	panic("implement me")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/file?id="+id.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	c.l.Debug("downloading file", zap.String("file_id", id.String()))
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return fmt.Errorf("download failed: %s", errorMsg)
+	}
+
+	w, abort, err := localCache.Write(id)
+	if errors.Is(err, ErrExists) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, rsp.Body); err != nil {
+		_ = abort()
+		return err
+	}
+
+	return w.Close()
 }
@@ -19,9 +19,10 @@ import (
 )
 
 type env struct {
-	cache  *testCache
-	server *httptest.Server
-	client *filecache.Client
+	cache   *testCache
+	handler *filecache.Handler
+	server  *httptest.Server
+	client  *filecache.Client
 }
 
 func newEnv(t *testing.T) *env {
@@ -39,9 +40,10 @@ func newEnv(t *testing.T) *env {
 	client := filecache.NewClient(l, server.URL)
 
 	env := &env{
-		cache:  cache,
-		server: server,
-		client: client,
+		cache:   cache,
+		handler: handler,
+		server:  server,
+		client:  client,
 	}
 
 	return env
@@ -127,3 +129,104 @@ func TestFileDownload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foobar"), content)
 }
+
+func TestResumableUpload(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("foobar"), 1024*1024)
+	tmpFilePath := filepath.Join(env.cache.tmpDir, "foo.txt")
+	require.NoError(t, os.WriteFile(tmpFilePath, content, 0666))
+
+	id, err := build.FileID("src/foo.txt", bytes.NewReader(content))
+	require.NoError(t, err)
+
+	t.Run("Missing", func(t *testing.T) {
+		missing, err := env.client.Missing(ctx, []build.ID{id})
+		require.NoError(t, err)
+		require.Equal(t, []build.ID{id}, missing)
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		// Имитируем оборванную загрузку: до координатора дошла только часть файла.
+		req, err := http.NewRequest(http.MethodPatch,
+			env.server.URL+"/file/upload?id="+id.String()+"&path=src/foo.txt&offset=0",
+			bytes.NewReader(content[:1000]))
+		require.NoError(t, err)
+
+		rsp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = rsp.Body.Close()
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, "1000", rsp.Header.Get("Upload-Offset"))
+
+		require.NoError(t, env.client.UploadFile(ctx, id, "src/foo.txt", tmpFilePath))
+
+		path, unlock, err := env.cache.Get(id)
+		require.NoError(t, err)
+		defer unlock()
+
+		actualContent, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, content, actualContent)
+
+		missing, err := env.client.Missing(ctx, []build.ID{id, {0x42}})
+		require.NoError(t, err)
+		require.Equal(t, []build.ID{{0x42}}, missing)
+
+		require.NoError(t, env.client.UploadFile(ctx, id, "src/foo.txt", tmpFilePath))
+	})
+
+	t.Run("Corrupted", func(t *testing.T) {
+		id := build.ID{0x01}
+
+		err := env.client.UploadFile(ctx, id, "src/foo.txt", tmpFilePath)
+		require.ErrorContains(t, err, filecache.ErrCorrupted.Error())
+
+		_, _, err = env.cache.Get(id)
+		require.ErrorIs(t, err, filecache.ErrNotFound)
+	})
+}
+
+func TestAbortUploadsOnStop(t *testing.T) {
+	env := newEnv(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		env.handler.Run(ctx)
+	}()
+
+	content := []byte("foobar")
+	id, err := build.FileID("foo.txt", bytes.NewReader(content))
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPatch,
+		env.server.URL+"/file/upload?id="+id.String()+"&path=foo.txt&offset=0",
+		bytes.NewReader(content[:3]))
+	require.NoError(t, err)
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, "3", rsp.Header.Get("Upload-Offset"))
+
+	_, _, err = env.cache.Get(id)
+	require.ErrorIs(t, err, filecache.ErrWriteLocked)
+
+	cancel()
+	<-stopped
+
+	// Незаконченная загрузка отменена: файл больше не залочен, а загрузка начинается заново.
+	_, _, err = env.cache.Get(id)
+	require.ErrorIs(t, err, filecache.ErrNotFound)
+
+	req, err = http.NewRequest(http.MethodHead, env.server.URL+"/file/upload?id="+id.String(), nil)
+	require.NoError(t, err)
+
+	rsp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, "0", rsp.Header.Get("Upload-Offset"))
+}
@@ -2,6 +2,8 @@ package filecache
 
 import (
 	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -15,6 +17,7 @@ var (
 	ErrExists      = errors.New("file exists")
 	ErrWriteLocked = errors.New("file is locked for write")
 	ErrReadLocked  = errors.New("file is locked for read")
+	ErrCorrupted   = errors.New("file content does not match its id")
 )
 
 const fileName = "file"
@@ -56,16 +59,44 @@ func (c *Cache) Remove(file build.ID) error {
 	return convertErr(c.cache.Remove(file))
 }
 
+// GC удаляет давно не использованные файлы, пока кеш не уложится в ограничения config.
+//
+// Правила те же, что у artifact.Cache.GC: файлы удаляются в порядке последнего Get,
+// а файлы под локом не удаляются.
+func (c *Cache) GC(config artifact.GCConfig) ([]build.ID, error) {
+	evicted, err := c.cache.GC(config)
+	return evicted, convertErr(err)
+}
+
 type fileWriter struct {
 	f      *os.File
 	commit func() error
+
+	// Если hash != nil, Close сверяет хеш записанного содержимого с id.
+	id    build.ID
+	hash  hash.Hash
+	abort func() error
 }
 
 func (f *fileWriter) Write(p []byte) (int, error) {
-	return f.f.Write(p)
+	n, err := f.f.Write(p)
+	if f.hash != nil {
+		_, _ = f.hash.Write(p[:n])
+	}
+	return n, err
 }
 
 func (f *fileWriter) Close() error {
+	if f.hash != nil {
+		var id build.ID
+		copy(id[:], f.hash.Sum(nil))
+
+		if id != f.id {
+			_ = f.abort()
+			return fmt.Errorf("%w: expected %s, got %s", ErrCorrupted, f.id, id)
+		}
+	}
+
 	closeErr := f.f.Close()
 	commitErr := f.commit()
 
@@ -77,6 +108,16 @@ func (f *fileWriter) Close() error {
 }
 
 func (c *Cache) Write(file build.ID) (w io.WriteCloser, abort func() error, err error) {
+	return c.write(file, nil)
+}
+
+// WriteVerified работает как Write, но проверяет, что file - это build.FileID файла path с записанным
+// содержимым. Если ID не совпал, Close удаляет файл и возвращает ErrCorrupted.
+func (c *Cache) WriteVerified(file build.ID, path string) (w io.WriteCloser, abort func() error, err error) {
+	return c.write(file, build.NewFileHash(path))
+}
+
+func (c *Cache) write(file build.ID, h hash.Hash) (w io.WriteCloser, abort func() error, err error) {
 	path, commit, abortDir, err := c.cache.Create(file)
 	if err != nil {
 		err = convertErr(err)
@@ -85,11 +126,10 @@ func (c *Cache) Write(file build.ID) (w io.WriteCloser, abort func() error, err
 
 	f, err := os.Create(filepath.Join(path, fileName))
 	if err != nil {
-		_ = abort()
+		_ = abortDir()
 		return
 	}
 
-	w = &fileWriter{f: f, commit: commit}
 	abort = func() error {
 		closeErr := f.Close()
 		abortErr := abortDir()
@@ -100,6 +140,7 @@ func (c *Cache) Write(file build.ID) (w io.WriteCloser, abort func() error, err
 
 		return abortErr
 	}
+	w = &fileWriter{f: f, commit: commit, id: file, hash: h, abort: abort}
 	return
 }
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
 	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
 )
@@ -57,3 +58,27 @@ func TestFileCache(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, []byte("foo bar"), content)
 }
+
+func TestFileCacheGC(t *testing.T) {
+	cache := newCache(t)
+
+	for _, id := range []build.ID{{01}, {02}} {
+		f, _, err := cache.Write(id)
+		require.NoError(t, err)
+
+		_, err = f.Write([]byte("foo bar"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	_, unlock, err := cache.Get(build.ID{01})
+	require.NoError(t, err)
+	defer unlock()
+
+	evicted, err := cache.GC(artifact.GCConfig{MaxSize: 1})
+	require.NoError(t, err)
+	require.Equal(t, []build.ID{{02}}, evicted)
+
+	_, _, err = cache.Get(build.ID{02})
+	require.Truef(t, errors.Is(err, filecache.ErrNotFound), "%v", err)
+}
@@ -3,18 +3,109 @@
 package filecache
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"os"
+	"sync"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 )
 
 type Handler struct {
+	l     *zap.Logger
+	cache *Cache
+
+	uploads singleflight.Group
+
+	mu      sync.Mutex
+	partial map[build.ID]*partialUpload
 }
 
 func NewHandler(l *zap.Logger, cache *Cache) *Handler {
-	panic("implement me")
+	return &Handler{l: l, cache: cache, partial: make(map[build.ID]*partialUpload)}
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	panic("implement me")
+	mux.HandleFunc("/file", h.file)
+	mux.HandleFunc("/file/missing", h.missing)
+	mux.HandleFunc("/file/upload", h.upload)
+}
+
+func (h *Handler) file(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, id)
+	case http.MethodPut:
+		h.put(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, id build.ID) {
+	path, unlock, err := h.cache.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.l.Warn("failed to open file", zap.String("file_id", id.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	h.l.Debug("sending file", zap.String("file_id", id.String()))
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		h.l.Warn("failed to send file", zap.String("file_id", id.String()), zap.Error(err))
+	}
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, id build.ID) {
+	_, err, _ := h.uploads.Do(id.String(), func() (any, error) {
+		return nil, h.write(id, r.Body)
+	})
+
+	if err != nil {
+		h.l.Warn("file upload failed", zap.String("file_id", id.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.l.Debug("file uploaded", zap.String("file_id", id.String()))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) write(id build.ID, r io.Reader) error {
+	fw, abort, err := h.cache.Write(id)
+	if errors.Is(err, ErrExists) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(fw, r); err != nil {
+		_ = abort()
+		return err
+	}
+
+	return fw.Close()
 }
@@ -0,0 +1,314 @@
+//go:build !solution
+
+package filecache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+const (
+	// Заголовки протокола загрузки по кускам.
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadCompleteHeader = "Upload-Complete"
+
+	// partialUploadTTL - время, через которое брошенная загрузка удаляется.
+	partialUploadTTL = 10 * time.Minute
+
+	// staleUploadsInterval - период фоновой очистки брошенных загрузок в Run.
+	staleUploadsInterval = time.Minute
+
+	// maxMissingRequestSize ограничивает размер запроса POST /file/missing.
+	maxMissingRequestSize = 64 << 20
+)
+
+// MissingRequest - тело запроса POST /file/missing.
+type MissingRequest struct {
+	IDs []build.ID
+}
+
+// MissingResponse перечисляет файлы из MissingRequest.IDs, которых нет в кеше.
+type MissingResponse struct {
+	Missing []build.ID
+}
+
+func (h *Handler) missing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MissingRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMissingRequestSize)).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	h.dropStale()
+	h.mu.Unlock()
+
+	rsp := MissingResponse{Missing: []build.ID{}}
+	for _, id := range req.IDs {
+		_, unlock, err := h.cache.Get(id)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrWriteLocked) {
+			rsp.Missing = append(rsp.Missing, id)
+			continue
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		unlock()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rsp)
+}
+
+// partialUpload - файл, загрузка которого начата, но ещё не закончена.
+type partialUpload struct {
+	mu       sync.Mutex
+	path     string
+	w        io.WriteCloser
+	abort    func() error
+	offset   int64
+	lastUsed time.Time
+	done     bool
+}
+
+// upload реализует загрузку файла по кускам.
+//
+// HEAD /file/upload?id= возвращает в заголовке Upload-Offset, сколько байт файла уже получено.
+// PATCH /file/upload?id=&path=&offset= дописывает тело запроса к файлу начиная с offset. Если offset
+// не совпадает с числом полученных байт, хендлер отвечает 409 и текущим Upload-Offset. Последний кусок
+// отправляется с final=1: хендлер сверяет содержимое с build.FileID и кладёт файл в кеш.
+//
+// Если файл уже есть в кеше, оба запроса отвечают заголовком Upload-Complete: 1.
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
+	var id build.ID
+	if err := id.UnmarshalText([]byte(r.URL.Query().Get("id"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		h.uploadStatus(w, id)
+	case http.MethodPatch:
+		h.uploadChunk(w, r, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) uploadStatus(w http.ResponseWriter, id build.ID) {
+	if h.exists(id) {
+		w.Header().Set(uploadCompleteHeader, "1")
+		return
+	}
+
+	h.mu.Lock()
+	h.dropStale()
+	u, ok := h.partial[id]
+	h.mu.Unlock()
+
+	var offset int64
+	if ok {
+		u.mu.Lock()
+		if !u.done {
+			offset = u.offset
+		}
+		u.mu.Unlock()
+	}
+
+	w.Header().Set(uploadOffsetHeader, strconv.FormatInt(offset, 10))
+}
+
+func (h *Handler) exists(id build.ID) bool {
+	_, unlock, err := h.cache.Get(id)
+	if err != nil {
+		return false
+	}
+	unlock()
+	return true
+}
+
+func (h *Handler) uploadChunk(w http.ResponseWriter, r *http.Request, id build.ID) {
+	query := r.URL.Query()
+
+	path := query.Get("path")
+	if path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid offset: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.partialUpload(id, path)
+	if errors.Is(err, ErrExists) {
+		w.Header().Set(uploadCompleteHeader, "1")
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch {
+	case u.done:
+		// Загрузку только что завершил или отменил другой запрос.
+		if h.exists(id) {
+			w.Header().Set(uploadCompleteHeader, "1")
+		} else {
+			w.Header().Set(uploadOffsetHeader, "0")
+			w.WriteHeader(http.StatusConflict)
+		}
+		return
+
+	case u.path != path:
+		http.Error(w, fmt.Sprintf("file is being uploaded as %q", u.path), http.StatusConflict)
+		return
+
+	case u.offset != offset:
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.offset, 10))
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	// Оборванный запрос оставляет в файле всё, что успело дойти: клиент продолжит с нового Upload-Offset.
+	n, copyErr := io.Copy(u.w, r.Body)
+	u.offset += n
+	u.lastUsed = time.Now()
+
+	if copyErr != nil {
+		h.l.Debug("file chunk interrupted",
+			zap.String("file_id", id.String()),
+			zap.Int64("offset", u.offset),
+			zap.Error(copyErr))
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.offset, 10))
+		http.Error(w, copyErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if query.Get("final") != "1" {
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(u.offset, 10))
+		return
+	}
+
+	u.done = true
+	h.mu.Lock()
+	delete(h.partial, id)
+	h.mu.Unlock()
+
+	if err := u.w.Close(); errors.Is(err, ErrCorrupted) {
+		h.l.Warn("corrupted file upload", zap.String("file_id", id.String()), zap.String("path", path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		h.l.Warn("file upload failed", zap.String("file_id", id.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.l.Debug("file uploaded", zap.String("file_id", id.String()), zap.Int64("size", u.offset))
+	w.Header().Set(uploadCompleteHeader, "1")
+}
+
+// partialUpload возвращает начатую загрузку файла id или начинает новую.
+func (h *Handler) partialUpload(id build.ID, path string) (*partialUpload, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.dropStale()
+
+	if u, ok := h.partial[id]; ok {
+		return u, nil
+	}
+
+	fw, abort, err := h.cache.WriteVerified(id, path)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &partialUpload{path: path, w: fw, abort: abort, lastUsed: time.Now()}
+	h.partial[id] = u
+	return u, nil
+}
+
+// Run раз в staleUploadsInterval удаляет брошенные загрузки, пока не отменён ctx.
+// После отмены ctx Run отменяет все незаконченные загрузки: продолжить их больше некому.
+func (h *Handler) Run(ctx context.Context) {
+	ticker := time.NewTicker(staleUploadsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.abortUploads()
+			return
+
+		case <-ticker.C:
+			h.mu.Lock()
+			h.dropStale()
+			h.mu.Unlock()
+		}
+	}
+}
+
+// abortUploads отменяет все незаконченные загрузки.
+//
+// В отличие от dropStale, abortUploads дожидается кусков, которые сейчас пишутся. Чтобы не взять
+// u.mu под h.mu, загрузки сначала забираются из h.partial.
+func (h *Handler) abortUploads() {
+	h.mu.Lock()
+	partial := h.partial
+	h.partial = make(map[build.ID]*partialUpload)
+	h.mu.Unlock()
+
+	for id, u := range partial {
+		u.mu.Lock()
+		if !u.done {
+			h.l.Debug("aborting file upload", zap.String("file_id", id.String()))
+			u.done = true
+			_ = u.abort()
+		}
+		u.mu.Unlock()
+	}
+}
+
+// dropStale удаляет загрузки, которые не продолжались дольше partialUploadTTL. Вызывается под h.mu
+// из Run и перед каждым запросом о состоянии загрузок: POST /file/missing, HEAD и PATCH /file/upload.
+//
+// Загрузки, чей кусок сейчас пишется, пропускаются: uploadChunk берёт h.mu, держа u.mu.
+func (h *Handler) dropStale() {
+	for id, u := range h.partial {
+		if !u.mu.TryLock() {
+			continue
+		}
+
+		if time.Since(u.lastUsed) > partialUploadTTL {
+			h.l.Debug("dropping stale file upload", zap.String("file_id", id.String()))
+			u.done = true
+			_ = u.abort()
+			delete(h.partial, id)
+		}
+		u.mu.Unlock()
+	}
+}
@@ -0,0 +1,764 @@
+package gograph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Раскладка выходных директорий джобов.
+const (
+	archiveName  = "pkg.a"
+	importcfg    = "importcfg"
+	embedcfgName = "embedcfg"
+	vetcfgName   = "vet.cfg"
+)
+
+// Шаблоны go list -export, которые превращают export data стандартной библиотеки в строки конфигов.
+const (
+	packagefileFormat = `{{if .Export}}packagefile {{.ImportPath}}={{.Export}}{{end}}`
+	vetFormat         = `{{if .Export}}{{printf "%q:%q," .ImportPath .Export}}{{end}}`
+)
+
+// Config задаёт параметры генерации графа.
+type Config struct {
+	// Dir задаёт директорию, в которой запускается go list.
+	//
+	// Пути в Graph.SourceFiles отсчитываются от корня главного модуля. Клиент должен
+	// использовать корень модуля как директорию с исходным кодом.
+	Dir string
+
+	// Patterns задаёт пакеты, для которых нужно построить граф. По умолчанию ./...
+	Patterns []string
+
+	// Env задаёт окружение команд джобов. По умолчанию используется DefaultEnv.
+	Env []string
+
+	// SkipVet и SkipTests отключают генерацию vet и test джобов.
+	SkipVet   bool
+	SkipTests bool
+
+	// TestShards и TestRetries задают build.Test для test джобов: на сколько шардов координатор
+	// делит тесты пакета и сколько раз перезапускает упавший шард. Без них test джоб - обычный джоб.
+	TestShards  int
+	TestRetries int
+}
+
+// Tools перечисляет инструменты воркера, на которые ссылается DefaultEnv. Воркер задаёт их пути
+// через worker.WithTools, а писать джобы должны иметь право только в GOCACHE.
+var Tools = []string{"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE"}
+
+// DefaultEnv возвращает окружение, в котором джобы запускают go toolchain.
+//
+// Стандартная библиотека и внешние модули не собираются в графе. Джобы получают их export data
+// через go list -export из кеша воркера. Пути до go toolchain и кешей зависят от воркера, поэтому
+// окружение ссылается на них через {{.Tool "<name>"}}, и в ID джобов они не попадают.
+func DefaultEnv() []string {
+	return []string{
+		`PATH={{.Tool "GOROOT"}}/bin:/usr/local/bin:/usr/bin:/bin`,
+		`GOPATH={{.Tool "GOPATH"}}`,
+		`GOCACHE={{.Tool "GOCACHE"}}`,
+		`GOMODCACHE={{.Tool "GOMODCACHE"}}`,
+		"GOFLAGS=-mod=mod",
+		"GOPROXY=off",
+		"GOTOOLCHAIN=local",
+		"CGO_ENABLED=0",
+	}
+}
+
+// LocalTools возвращает пути инструментов из Tools на этой машине по выводу go env.
+func LocalTools(ctx context.Context) (map[string]string, error) {
+	return goEnv(ctx, Tools...)
+}
+
+type generator struct {
+	config Config
+
+	root string
+	lang string
+
+	pkgs  map[string]*Package
+	graph build.Graph
+
+	files    map[string]build.ID
+	compiled map[string]build.ID
+	linked   map[string]build.ID
+}
+
+// Generate строит граф сборки для пакетов главного модуля.
+//
+// Для каждого пакета генерируются джобы:
+//
+//	compile <pkg> - компилирует пакет в {{.OutputDir}}/pkg.a.
+//	link <pkg>    - линкует main пакет или тестовый бинарь.
+//	vet <pkg>     - запускает go tool vet.
+//	test <pkg>    - запускает тестовый бинарь в директории пакета.
+//
+// ID джобов и файлов вычисляются из их содержимого, поэтому повторная генерация даёт тот же граф.
+func Generate(ctx context.Context, config Config) (*build.Graph, error) {
+	if len(config.Patterns) == 0 {
+		config.Patterns = []string{"./..."}
+	}
+
+	if config.Env == nil {
+		config.Env = DefaultEnv()
+	}
+
+	pkgs, err := list(ctx, config.Dir, config.Patterns, !config.SkipTests)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		config: config,
+
+		pkgs:  map[string]*Package{},
+		graph: build.Graph{SourceFiles: map[build.ID]string{}},
+
+		files:    map[string]build.ID{},
+		compiled: map[string]build.ID{},
+		linked:   map[string]build.ID{},
+	}
+
+	for _, p := range pkgs {
+		g.pkgs[p.ImportPath] = p
+
+		if g.local(p) && g.root == "" {
+			g.root = p.Module.Dir
+			if p.Module.GoVersion != "" {
+				g.lang = languageVersion(p.Module.GoVersion)
+			}
+		}
+	}
+
+	if g.root == "" {
+		return nil, errors.New("no packages from the main module")
+	}
+
+	for _, p := range pkgs {
+		if !g.local(p) || p.DepOnly {
+			continue
+		}
+
+		switch {
+		case isTestMain(p):
+			_, err = g.test(p)
+		case p.ForTest != "" || len(p.GoFiles) == 0:
+			// Тестовые варианты собираются по требованию тестов.
+		default:
+			err = g.build(p)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &g.graph, nil
+}
+
+// languageVersion превращает версию из директивы go в go.mod в значение флага -lang: "1.24.0" -> "go1.24".
+func languageVersion(version string) string {
+	if parts := strings.SplitN(version, ".", 3); len(parts) == 3 {
+		version = parts[0] + "." + parts[1]
+	}
+	return "go" + version
+}
+
+func (g *generator) build(p *Package) error {
+	if _, err := g.compile(p); err != nil {
+		return err
+	}
+
+	if p.Name == "main" {
+		if _, err := g.link(p); err != nil {
+			return err
+		}
+	}
+
+	if !g.config.SkipVet {
+		if _, err := g.vet(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) local(p *Package) bool {
+	return !p.Standard && p.Module != nil && p.Module.Main
+}
+
+func isTestMain(p *Package) bool {
+	return p.Name == "main" && p.ForTest == "" && strings.HasSuffix(p.ImportPath, ".test")
+}
+
+// packagePath возвращает путь пакета без суффикса тестового варианта: "p [p.test]" -> "p".
+func packagePath(importPath string) string {
+	if i := strings.IndexByte(importPath, ' '); i != -1 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
+// compilePath возвращает значение флага -p для пакета.
+func compilePath(p *Package) string {
+	if p.Name == "main" && p.ForTest == "" {
+		return "main"
+	}
+	return packagePath(p.ImportPath)
+}
+
+// rawImport возвращает путь импорта в том виде, в котором он записан в исходном коде.
+func rawImport(p *Package, imp string) string {
+	for raw, resolved := range p.ImportMap {
+		if resolved == imp {
+			return raw
+		}
+	}
+	return imp
+}
+
+// escape экранирует текст, чтобы Cmd.Render оставил его без изменений.
+func escape(s string) string {
+	return strings.ReplaceAll(s, "{{", `{{"{{"}}`)
+}
+
+func depPath(id build.ID, name string) string {
+	return fmt.Sprintf("{{index .Deps %q}}/%s", id.String(), name)
+}
+
+func checkSupported(p *Package) error {
+	switch {
+	case len(p.CgoFiles) != 0, len(p.CFiles) != 0, len(p.CXXFiles) != 0:
+		return fmt.Errorf("package %s: cgo is not supported", p.ImportPath)
+	case len(p.SFiles) != 0, len(p.SysoFiles) != 0:
+		return fmt.Errorf("package %s: assembly is not supported", p.ImportPath)
+	}
+	return nil
+}
+
+// rel возвращает путь файла пакета относительно корня модуля.
+func (g *generator) rel(p *Package, file string) (string, bool) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(p.Dir, file)
+	}
+
+	rel, err := filepath.Rel(g.root, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// addFile добавляет файл в Graph.SourceFiles.
+func (g *generator) addFile(rel string) (build.ID, error) {
+	if id, ok := g.files[rel]; ok {
+		return id, nil
+	}
+
+	f, err := os.Open(filepath.Join(g.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return build.ID{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	id, err := build.FileID(rel, f)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	g.files[rel] = id
+	g.graph.SourceFiles[id] = rel
+	return id, nil
+}
+
+// moduleFiles возвращает go.mod и go.sum, которые нужны go list внутри джоба.
+func (g *generator) moduleFiles() []string {
+	files := []string{"go.mod"}
+	if _, err := os.Stat(filepath.Join(g.root, "go.sum")); err == nil {
+		files = append(files, "go.sum")
+	}
+	return files
+}
+
+// add вычисляет ID джоба и добавляет его в граф.
+func (g *generator) add(job *build.Job) (build.ID, error) {
+	inputs := map[string]struct{}{}
+	for _, input := range job.Inputs {
+		inputs[input] = struct{}{}
+	}
+
+	job.Inputs = job.Inputs[:0]
+	for input := range inputs {
+		job.Inputs = append(job.Inputs, input)
+	}
+	sort.Strings(job.Inputs)
+
+	for _, input := range job.Inputs {
+		if _, err := g.addFile(input); err != nil {
+			return build.ID{}, err
+		}
+	}
+
+	id, err := build.JobID(job, g.files)
+	if err != nil {
+		return build.ID{}, err
+	}
+	job.ID = id
+
+	g.graph.Jobs = append(g.graph.Jobs, *job)
+	return job.ID, nil
+}
+
+// goList возвращает команду, которая дописывает в output вывод go list -export по пакетам pkgs,
+// а затем строку suffix.
+func (g *generator) goList(output, format, suffix string, pkgs []string, deps bool) build.Cmd {
+	args := []string{
+		"sh", "-c", `out="$1"; suffix="$2"; shift 2; go list -export "$@" >> "$out" && printf '%s' "$suffix" >> "$out"`,
+		"sh", output, suffix, "-f", escape(format),
+	}
+	if deps {
+		args = append(args, "-deps")
+	}
+	args = append(args, "--")
+	args = append(args, pkgs...)
+
+	return build.Cmd{Exec: args, Environ: g.config.Env, WorkingDirectory: "{{.SourceDir}}"}
+}
+
+// imports делит импорты пакета на пакеты главного модуля и внешние пакеты.
+func (g *generator) imports(p *Package) (local []*Package, external []string, err error) {
+	seen := map[string]bool{}
+	for _, imp := range p.Imports {
+		if imp == "unsafe" || seen[imp] {
+			continue
+		}
+		seen[imp] = true
+
+		dep, ok := g.pkgs[imp]
+		if !ok {
+			return nil, nil, fmt.Errorf("package %s: import %q is missing from go list output", p.ImportPath, imp)
+		}
+
+		if g.local(dep) {
+			local = append(local, dep)
+		} else {
+			external = append(external, imp)
+		}
+	}
+
+	sort.Strings(external)
+	return local, external, nil
+}
+
+func (g *generator) compile(p *Package) (build.ID, error) {
+	if id, ok := g.compiled[p.ImportPath]; ok {
+		return id, nil
+	}
+
+	if err := checkSupported(p); err != nil {
+		return build.ID{}, err
+	}
+
+	job := &build.Job{Name: "compile " + p.ImportPath}
+
+	local, external, err := g.imports(p)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	var cfg strings.Builder
+	for _, dep := range local {
+		id, err := g.compile(dep)
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		job.Deps = append(job.Deps, id)
+		_, _ = fmt.Fprintf(&cfg, "packagefile %s=%s\n", rawImport(p, dep.ImportPath), depPath(id, archiveName))
+	}
+
+	for _, imp := range external {
+		if raw := rawImport(p, imp); raw != imp {
+			_, _ = fmt.Fprintf(&cfg, "importmap %s=%s\n", raw, imp)
+		}
+	}
+
+	job.Cmds = append(job.Cmds, build.Cmd{CatTemplate: cfg.String(), CatOutput: "{{.OutputDir}}/" + importcfg})
+	if len(external) != 0 {
+		job.Inputs = append(job.Inputs, g.moduleFiles()...)
+		job.Cmds = append(job.Cmds, g.goList("{{.OutputDir}}/"+importcfg, packagefileFormat, "", external, false))
+	}
+
+	args := []string{
+		"go", "tool", "compile",
+		"-o", "{{.OutputDir}}/" + archiveName,
+		"-p", compilePath(p),
+		"-trimpath", "{{.SourceDir}}=>;{{.OutputDir}}=>",
+		"-complete",
+		"-nolocalimports",
+		"-importcfg", "{{.OutputDir}}/" + importcfg,
+		"-pack",
+	}
+	if g.lang != "" {
+		args = append(args, "-lang="+g.lang)
+	}
+
+	if len(p.EmbedFiles) != 0 {
+		embedcfg, inputs, err := g.embedConfig(p)
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		job.Inputs = append(job.Inputs, inputs...)
+		job.Cmds = append(job.Cmds, build.Cmd{CatTemplate: escape(embedcfg), CatOutput: "{{.OutputDir}}/" + embedcfgName})
+		args = append(args, "-embedcfg", "{{.OutputDir}}/"+embedcfgName)
+	}
+
+	for _, file := range p.GoFiles {
+		if rel, ok := g.rel(p, file); ok {
+			job.Inputs = append(job.Inputs, rel)
+			args = append(args, escape(rel))
+			continue
+		}
+
+		// Сгенерированные go list файлы, например _testmain.go, лежат вне модуля.
+		// Их содержимое записывается прямо в граф. Префикс _ прячет такие файлы от go list.
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		output := "{{.OutputDir}}/_" + escape(filepath.Base(file)) + ".go"
+		job.Cmds = append(job.Cmds, build.Cmd{CatTemplate: escape(string(content)), CatOutput: output})
+		args = append(args, output)
+	}
+
+	job.Cmds = append(job.Cmds, build.Cmd{Exec: args, Environ: g.config.Env, WorkingDirectory: "{{.SourceDir}}"})
+
+	id, err := g.add(job)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	g.compiled[p.ImportPath] = id
+	return id, nil
+}
+
+type embedConfig struct {
+	Patterns map[string][]string
+	Files    map[string]string
+}
+
+// matchEmbed проверяет, что файл name попадает под шаблон //go:embed напрямую или через одну из своих директорий.
+func matchEmbed(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "all:")
+	for {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+
+		if name = path.Dir(name); name == "." || name == "/" {
+			return false
+		}
+	}
+}
+
+func (g *generator) embedConfig(p *Package) (string, []string, error) {
+	cfg := embedConfig{Patterns: map[string][]string{}, Files: map[string]string{}}
+
+	var inputs []string
+	for _, file := range p.EmbedFiles {
+		rel, ok := g.rel(p, file)
+		if !ok {
+			return "", nil, fmt.Errorf("package %s: embedded file %s is outside of the module", p.ImportPath, file)
+		}
+
+		inputs = append(inputs, rel)
+		cfg.Files[file] = rel
+	}
+
+	for _, pattern := range p.EmbedPatterns {
+		files := []string{}
+		for _, file := range p.EmbedFiles {
+			if matchEmbed(pattern, file) {
+				files = append(files, file)
+			}
+		}
+		cfg.Patterns[pattern] = files
+	}
+
+	js, err := json.Marshal(cfg)
+	return string(js), inputs, err
+}
+
+// closure возвращает все пакеты главного модуля, от которых зависит p, и все внешние пакеты.
+func (g *generator) closure(p *Package) ([]*Package, []string, error) {
+	visited := map[string]bool{}
+	externals := map[string]struct{}{"runtime": {}}
+
+	var local []*Package
+	var visit func(p *Package) error
+	visit = func(p *Package) error {
+		if visited[p.ImportPath] {
+			return nil
+		}
+		visited[p.ImportPath] = true
+
+		deps, external, err := g.imports(p)
+		if err != nil {
+			return err
+		}
+
+		for _, imp := range external {
+			externals[imp] = struct{}{}
+		}
+
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		local = append(local, p)
+		return nil
+	}
+
+	if err := visit(p); err != nil {
+		return nil, nil, err
+	}
+
+	var external []string
+	for imp := range externals {
+		external = append(external, imp)
+	}
+	sort.Strings(external)
+
+	return local, external, nil
+}
+
+func (g *generator) link(p *Package) (build.ID, error) {
+	if id, ok := g.linked[p.ImportPath]; ok {
+		return id, nil
+	}
+
+	main, err := g.compile(p)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	local, external, err := g.closure(p)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	job := &build.Job{
+		Name:   "link " + p.ImportPath,
+		Inputs: g.moduleFiles(),
+		Deps:   []build.ID{main},
+	}
+
+	var cfg strings.Builder
+	for _, dep := range local {
+		if dep == p {
+			continue
+		}
+
+		id, err := g.compile(dep)
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		job.Deps = append(job.Deps, id)
+		_, _ = fmt.Fprintf(&cfg, "packagefile %s=%s\n", packagePath(dep.ImportPath), depPath(id, archiveName))
+	}
+
+	cfgPath := "{{.OutputDir}}/" + importcfg + ".link"
+	job.Cmds = []build.Cmd{
+		{CatTemplate: cfg.String(), CatOutput: cfgPath},
+		g.goList(cfgPath, packagefileFormat, "", external, true),
+		{
+			Exec: []string{
+				"go", "tool", "link",
+				"-o", "{{.OutputDir}}/" + path.Base(p.ImportPath),
+				"-importcfg", cfgPath,
+				"-buildmode=exe",
+				depPath(main, archiveName),
+			},
+			Environ:          g.config.Env,
+			WorkingDirectory: "{{.SourceDir}}",
+		},
+	}
+
+	id, err := g.add(job)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	g.linked[p.ImportPath] = id
+	return id, nil
+}
+
+type vetConfig struct {
+	ID         string
+	Compiler   string
+	Dir        string
+	ImportPath string
+	GoFiles    []string
+	ImportMap  map[string]string
+	Standard   map[string]bool
+	GoVersion  string `json:",omitempty"`
+}
+
+func (g *generator) vet(p *Package) (build.ID, error) {
+	local, external, err := g.imports(p)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	dir, _ := g.rel(p, p.Dir)
+	cfg := vetConfig{
+		ID:         p.ImportPath,
+		Compiler:   "gc",
+		Dir:        dir,
+		ImportPath: p.ImportPath,
+		ImportMap:  map[string]string{},
+		Standard:   map[string]bool{},
+		GoVersion:  g.lang,
+	}
+
+	job := &build.Job{Name: "vet " + p.ImportPath}
+	for _, file := range p.GoFiles {
+		rel, ok := g.rel(p, file)
+		if !ok {
+			return build.ID{}, fmt.Errorf("package %s: file %s is outside of the module", p.ImportPath, file)
+		}
+
+		job.Inputs = append(job.Inputs, rel)
+		cfg.GoFiles = append(cfg.GoFiles, rel)
+	}
+
+	for _, imp := range p.Imports {
+		cfg.ImportMap[rawImport(p, imp)] = imp
+		if dep, ok := g.pkgs[imp]; ok && dep.Standard {
+			cfg.Standard[imp] = true
+		}
+	}
+
+	// PackageFile дописывается вручную: пути до архивов зависимостей известны только воркеру.
+	head, err := json.Marshal(cfg)
+	if err != nil {
+		return build.ID{}, err
+	}
+	head = append(head[:len(head)-1], `,"PackageFile":{`...)
+
+	var tail strings.Builder
+	for _, dep := range local {
+		id, err := g.compile(dep)
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		job.Deps = append(job.Deps, id)
+		_, _ = fmt.Fprintf(&tail, "%q:\"%s\",", dep.ImportPath, depPath(id, archiveName))
+	}
+	tail.WriteString(`"":""},"VetxOutput":"{{.OutputDir}}/vet.out"}`)
+
+	cfgPath := "{{.OutputDir}}/" + vetcfgName
+	if len(external) != 0 {
+		job.Inputs = append(job.Inputs, g.moduleFiles()...)
+		job.Cmds = append(job.Cmds,
+			build.Cmd{CatTemplate: escape(string(head)), CatOutput: cfgPath},
+			g.goList(cfgPath, vetFormat, tail.String(), external, false),
+		)
+	} else {
+		job.Cmds = append(job.Cmds, build.Cmd{CatTemplate: escape(string(head)) + tail.String(), CatOutput: cfgPath})
+	}
+
+	job.Cmds = append(job.Cmds, build.Cmd{
+		Exec:             []string{"go", "tool", "vet", cfgPath},
+		Environ:          g.config.Env,
+		WorkingDirectory: "{{.SourceDir}}",
+	})
+
+	return g.add(job)
+}
+
+// test добавляет джоб, который запускает тестовый бинарь пакета p.test.
+func (g *generator) test(p *Package) (build.ID, error) {
+	base, ok := g.pkgs[strings.TrimSuffix(p.ImportPath, ".test")]
+	if !ok {
+		return build.ID{}, fmt.Errorf("package %s: tested package is missing from go list output", p.ImportPath)
+	}
+
+	link, err := g.link(p)
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	job := &build.Job{
+		Name: "test " + base.ImportPath,
+		Deps: []build.ID{link},
+	}
+
+	var files []string
+	for _, list := range [][]string{base.GoFiles, base.TestGoFiles, base.XTestGoFiles, base.OtherFiles, base.EmbedFiles} {
+		files = append(files, list...)
+	}
+
+	for _, file := range files {
+		if rel, ok := g.rel(base, file); ok {
+			job.Inputs = append(job.Inputs, rel)
+		}
+	}
+
+	// Тесты обычно читают файлы из testdata, которые go list не перечисляет.
+	err = filepath.WalkDir(filepath.Join(base.Dir, "testdata"), func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if d.Type().IsRegular() {
+			if rel, ok := g.rel(base, path); ok {
+				job.Inputs = append(job.Inputs, rel)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return build.ID{}, err
+	}
+
+	if g.config.TestShards > 1 || g.config.TestRetries > 0 {
+		names, err := testNames(base.Dir, append(slices.Clone(base.TestGoFiles), base.XTestGoFiles...))
+		if err != nil {
+			return build.ID{}, err
+		}
+
+		job.Test = &build.Test{Names: names, Shards: g.config.TestShards, Retries: g.config.TestRetries}
+	}
+
+	dir, _ := g.rel(base, base.Dir)
+	job.Cmds = []build.Cmd{
+		{
+			Exec:             []string{depPath(link, path.Base(p.ImportPath))},
+			Environ:          g.config.Env,
+			WorkingDirectory: "{{.SourceDir}}/" + escape(dir),
+		},
+	}
+
+	return g.add(job)
+}
@@ -0,0 +1,142 @@
+package gograph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/gograph"
+)
+
+func generate(t *testing.T, config gograph.Config) *build.Graph {
+	t.Helper()
+
+	config.Dir = "testdata/hello"
+	config.Env = []string{"GOFLAGS=-mod=mod"}
+
+	graph, err := gograph.Generate(t.Context(), config)
+	require.NoError(t, err)
+	return graph
+}
+
+func jobsByName(graph *build.Graph) map[string]build.Job {
+	jobs := map[string]build.Job{}
+	for _, job := range graph.Jobs {
+		jobs[job.Name] = job
+	}
+	return jobs
+}
+
+func TestGenerate(t *testing.T) {
+	graph := generate(t, gograph.Config{})
+	jobs := jobsByName(graph)
+
+	assert.ElementsMatch(t, []string{
+		"compile example.com/hello/greeting",
+		"vet example.com/hello/greeting",
+		"compile example.com/hello/greeting [example.com/hello/greeting.test]",
+		"compile example.com/hello/greeting_test [example.com/hello/greeting.test]",
+		"compile example.com/hello/greeting.test",
+		"link example.com/hello/greeting.test",
+		"test example.com/hello/greeting",
+		"compile example.com/hello/cmd/hello",
+		"link example.com/hello/cmd/hello",
+		"vet example.com/hello/cmd/hello",
+	}, func() []string {
+		var names []string
+		for name := range jobs {
+			names = append(names, name)
+		}
+		return names
+	}())
+
+	greeting := jobs["compile example.com/hello/greeting"]
+	assert.Equal(t, []string{"go.mod", "greeting/greeting.go", "greeting/greeting.txt"}, greeting.Inputs)
+	assert.Empty(t, greeting.Deps)
+
+	main := jobs["compile example.com/hello/cmd/hello"]
+	assert.Equal(t, []string{"cmd/hello/main.go", "go.mod"}, main.Inputs)
+	assert.Equal(t, []build.ID{greeting.ID}, main.Deps)
+
+	link := jobs["link example.com/hello/cmd/hello"]
+	assert.ElementsMatch(t, []build.ID{main.ID, greeting.ID}, link.Deps)
+
+	test := jobs["test example.com/hello/greeting"]
+	assert.Contains(t, test.Inputs, "greeting/testdata/gopher.golden")
+	assert.Equal(t, []build.ID{jobs["link example.com/hello/greeting.test"].ID}, test.Deps)
+
+	files := map[string]bool{}
+	for _, path := range graph.SourceFiles {
+		files[path] = true
+	}
+
+	for _, job := range graph.Jobs {
+		for _, input := range job.Inputs {
+			assert.True(t, files[input], "%s: input %s is missing from SourceFiles", job.Name, input)
+		}
+	}
+
+	require.NoError(t, graph.Validate())
+
+	for _, job := range graph.Jobs {
+		id, err := graph.JobID(&job)
+		require.NoError(t, err)
+		assert.Equal(t, id, job.ID, job.Name)
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	first := generate(t, gograph.Config{})
+	second := generate(t, gograph.Config{})
+
+	require.Equal(t, first, second)
+}
+
+func TestGenerateSkip(t *testing.T) {
+	graph := generate(t, gograph.Config{SkipVet: true, SkipTests: true})
+
+	for name := range jobsByName(graph) {
+		assert.NotContains(t, name, "vet ")
+		assert.NotContains(t, name, "test")
+	}
+}
+
+func TestGenerateTestShards(t *testing.T) {
+	test := jobsByName(generate(t, gograph.Config{}))["test example.com/hello/greeting"]
+	assert.Nil(t, test.Test)
+
+	graph := generate(t, gograph.Config{TestShards: 2, TestRetries: 1})
+	test = jobsByName(graph)["test example.com/hello/greeting"]
+	require.NotNil(t, test.Test)
+	assert.Equal(t, &build.Test{
+		Names:   []string{"ExampleHello", "TestHello", "TestHelloWorld"},
+		Shards:  2,
+		Retries: 1,
+	}, test.Test)
+
+	shards, err := graph.TestShards(&test)
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+	assert.Contains(t, shards[0].Cmds[len(shards[0].Cmds)-1].Exec, "-test.run=^(?:ExampleHello|TestHelloWorld)$")
+}
+
+func TestGenerateDefaultEnv(t *testing.T) {
+	graph, err := gograph.Generate(t.Context(), gograph.Config{Dir: "testdata/hello"})
+	require.NoError(t, err)
+
+	paths, err := gograph.LocalTools(t.Context())
+	require.NoError(t, err)
+
+	// Пути воркера не должны попадать в команды, иначе ID джобов зависят от машины.
+	for _, job := range graph.Jobs {
+		for _, cmd := range job.Cmds {
+			for _, v := range cmd.Environ {
+				for name, path := range paths {
+					assert.NotContains(t, v, path, "%s: %s", job.Name, name)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package gograph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Package описывает пакет из вывода go list -json.
+type Package struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	ForTest    string
+	Standard   bool
+	DepOnly    bool
+	Module     *Module
+
+	GoFiles      []string
+	CgoFiles     []string
+	CFiles       []string
+	CXXFiles     []string
+	SFiles       []string
+	SysoFiles    []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	OtherFiles   []string
+
+	EmbedPatterns []string
+	EmbedFiles    []string
+
+	Imports   []string
+	ImportMap map[string]string
+
+	Error      *PackageError
+	DepsErrors []*PackageError
+}
+
+type Module struct {
+	Path      string
+	Dir       string
+	Main      bool
+	GoVersion string
+}
+
+type PackageError struct {
+	Err string
+}
+
+// list запускает go list -json -deps и возвращает пакеты в порядке вывода.
+//
+// Если tests == true, в вывод попадают тестовые варианты пакетов и пакеты p.test.
+func list(ctx context.Context, dir string, patterns []string, tests bool) ([]*Package, error) {
+	args := []string{"list", "-json", "-deps"}
+	if tests {
+		args = append(args, "-test")
+	}
+	args = append(args, "--")
+	args = append(args, patterns...)
+
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "go", args...)
+	c.Dir = dir
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []*Package
+	dec := json.NewDecoder(&stdout)
+	for {
+		var p Package
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+
+		if p.Error != nil {
+			return nil, fmt.Errorf("package %s: %s", p.ImportPath, p.Error.Err)
+		}
+
+		pkgs = append(pkgs, &p)
+	}
+
+	return pkgs, nil
+}
+
+// goEnv возвращает значения переменных из go env.
+func goEnv(ctx context.Context, vars ...string) (map[string]string, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "go", append([]string{"env", "-json"}, vars...)...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("go env: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	env := map[string]string{}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		return nil, fmt.Errorf("go env: %w", err)
+	}
+	return env, nil
+}
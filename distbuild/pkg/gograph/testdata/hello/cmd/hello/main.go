@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/hello/greeting"
+)
+
+func main() {
+	fmt.Println(greeting.Hello("distbuild"))
+}
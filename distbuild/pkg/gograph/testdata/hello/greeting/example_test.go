@@ -0,0 +1,19 @@
+package greeting_test
+
+import (
+	"fmt"
+	"testing"
+
+	"example.com/hello/greeting"
+)
+
+func TestHelloWorld(t *testing.T) {
+	if got := greeting.Hello("world"); got != "Hello, world!" {
+		t.Errorf("Hello() = %q", got)
+	}
+}
+
+func ExampleHello() {
+	fmt.Println(greeting.Hello("distbuild"))
+	// Output: Hello, distbuild!
+}
@@ -0,0 +1,14 @@
+package greeting
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed greeting.txt
+var greeting string
+
+// Hello возвращает приветствие для name.
+func Hello(name string) string {
+	return strings.TrimSpace(greeting) + ", " + name + "!"
+}
@@ -0,0 +1,18 @@
+package greeting
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHello(t *testing.T) {
+	golden, err := os.ReadFile("testdata/gopher.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := Hello("gopher"), strings.TrimSpace(string(golden)); got != want {
+		t.Errorf("Hello() = %q, want %q", got, want)
+	}
+}
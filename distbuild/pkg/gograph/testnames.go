@@ -0,0 +1,60 @@
+package gograph
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// testNames возвращает тесты, примеры и fuzz тесты, которые go test найдёт в файлах files
+// директории dir. Бенчмарки не входят, потому что без -test.bench они не запускаются.
+func testNames(dir string, files []string) ([]string, error) {
+	fset := token.NewFileSet()
+
+	var names []string
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, file), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Type.TypeParams != nil {
+				continue
+			}
+
+			name := fn.Name.Name
+			params := fn.Type.Params.NumFields()
+
+			switch {
+			case name == "TestMain":
+			case isTest(name, "Test") && params == 1, isTest(name, "Fuzz") && params == 1:
+				names = append(names, name)
+			case isTest(name, "Example") && params == 0:
+				names = append(names, name)
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+// isTest повторяет правило go test: после префикса идёт конец имени или не строчная буква.
+func isTest(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
@@ -0,0 +1,128 @@
+package hermetic
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+var ErrNotSupported = errors.New("tracing is not supported on this platform")
+
+// DefaultSystemPaths перечисляет директории, которые джоб может читать, не объявляя их.
+var DefaultSystemPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc", "/dev", "/proc", "/sys"}
+
+// DefaultWritablePaths перечисляет директории вне {{.OutputDir}}, в которые джоб может писать.
+var DefaultWritablePaths = []string{"/dev"}
+
+// Access описывает одно обращение процесса к файловой системе.
+type Access struct {
+	Path  string
+	Write bool
+}
+
+// Policy описывает, к каким файлам может обращаться джоб.
+type Policy struct {
+	// SourceDir и Inputs задают директорию с исходным кодом и список объявленных в ней файлов.
+	SourceDir string
+	Inputs    []string
+
+	// OutputDir доступна и на чтение, и на запись.
+	OutputDir string
+
+	// ReadPaths перечисляет директории, доступные на чтение: выходы зависимостей и системные директории.
+	ReadPaths []string
+
+	// WritePaths перечисляет директории вне OutputDir, доступные на запись.
+	WritePaths []string
+}
+
+func isWithin(root, path string) bool {
+	if root == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func withinAny(roots []string, path string) bool {
+	for _, root := range roots {
+		if isWithin(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAncestor проверяет, что path - одна из директорий на пути к какому-то из roots.
+//
+// Программы часто обходят все компоненты пути, например в realpath(3).
+func isAncestor(roots []string, path string) bool {
+	for _, root := range roots {
+		if root != "" && isWithin(path, root) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) checkSource(path string) bool {
+	rel, err := filepath.Rel(p.SourceDir, path)
+	if err != nil || rel == "." {
+		return true
+	}
+
+	for _, input := range p.Inputs {
+		input = filepath.Clean(input)
+		if input == rel || isWithin(rel, input) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check возвращает отсортированный список нарушений без повторов.
+func (p *Policy) Check(accesses []Access) []api.Violation {
+	roots := append([]string{p.SourceDir, p.OutputDir}, p.ReadPaths...)
+	roots = append(roots, p.WritePaths...)
+
+	seen := map[api.Violation]struct{}{}
+	add := func(kind api.ViolationKind, path string) {
+		seen[api.Violation{Kind: kind, Path: path}] = struct{}{}
+	}
+
+	for _, a := range accesses {
+		path := filepath.Clean(a.Path)
+
+		switch {
+		case isWithin(p.OutputDir, path):
+		case a.Write:
+			if !withinAny(p.WritePaths, path) {
+				add(api.WriteOutside, path)
+			}
+		case isWithin(p.SourceDir, path):
+			if !p.checkSource(path) {
+				add(api.UndeclaredInput, path)
+			}
+		case withinAny(p.ReadPaths, path), withinAny(p.WritePaths, path), isAncestor(roots, path):
+		default:
+			add(api.ReadOutside, path)
+		}
+	}
+
+	var violations []api.Violation
+	for v := range seen {
+		violations = append(violations, v)
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Kind != violations[j].Kind {
+			return violations[i].Kind < violations[j].Kind
+		}
+		return violations[i].Path < violations[j].Path
+	})
+	return violations
+}
@@ -0,0 +1,46 @@
+package hermetic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &hermetic.Policy{
+		SourceDir:  "/distbuild/src",
+		Inputs:     []string{"a.txt", "b/c.txt"},
+		OutputDir:  "/distbuild/out",
+		ReadPaths:  []string{"/distbuild/deps/a", "/usr"},
+		WritePaths: []string{"/dev"},
+	}
+
+	accesses := []hermetic.Access{
+		{Path: "/distbuild/src/a.txt"},
+		{Path: "/distbuild/src/b"},
+		{Path: "/distbuild/src/b/../b/c.txt"},
+		{Path: "/distbuild"},
+		{Path: "/distbuild/deps/a/lib.a"},
+		{Path: "/usr/bin/cat"},
+		{Path: "/dev/null", Write: true},
+		{Path: "/distbuild/out/x", Write: true},
+
+		{Path: "/distbuild/src/d.txt"},
+		{Path: "/distbuild/src/d.txt"},
+		{Path: "/distbuild/deps/b/lib.a"},
+		{Path: "/home/user/secret"},
+		{Path: "/distbuild/src/a.txt", Write: true},
+		{Path: "/tmp/x", Write: true},
+	}
+
+	require.Equal(t, []api.Violation{
+		{Kind: api.ReadOutside, Path: "/distbuild/deps/b/lib.a"},
+		{Kind: api.ReadOutside, Path: "/home/user/secret"},
+		{Kind: api.UndeclaredInput, Path: "/distbuild/src/d.txt"},
+		{Kind: api.WriteOutside, Path: "/distbuild/src/a.txt"},
+		{Kind: api.WriteOutside, Path: "/tmp/x"},
+	}, policy.Check(accesses))
+}
@@ -0,0 +1,43 @@
+//go:build linux && (amd64 || arm64)
+
+package hermetic
+
+import "golang.org/x/sys/unix"
+
+// pathArg описывает аргумент системного вызова, содержащий путь.
+type pathArg struct {
+	// dirfd - индекс аргумента с дескриптором директории, или -1 для путей относительно cwd.
+	dirfd int
+	path  int
+}
+
+type syscallDesc struct {
+	paths []pathArg
+	write bool
+
+	// flags - индекс аргумента с флагами open(2), или 0, если флагов нет.
+	flags int
+	// how - индекс аргумента со struct open_how для openat2(2).
+	how int
+}
+
+var syscalls = map[uint64]syscallDesc{
+	unix.SYS_OPENAT:     {paths: []pathArg{{0, 1}}, flags: 2},
+	unix.SYS_OPENAT2:    {paths: []pathArg{{0, 1}}, how: 2},
+	unix.SYS_EXECVE:     {paths: []pathArg{{-1, 0}}},
+	unix.SYS_EXECVEAT:   {paths: []pathArg{{0, 1}}},
+	unix.SYS_NEWFSTATAT: {paths: []pathArg{{0, 1}}},
+	unix.SYS_STATX:      {paths: []pathArg{{0, 1}}},
+	unix.SYS_FACCESSAT:  {paths: []pathArg{{0, 1}}},
+	unix.SYS_FACCESSAT2: {paths: []pathArg{{0, 1}}},
+	unix.SYS_READLINKAT: {paths: []pathArg{{0, 1}}},
+
+	unix.SYS_MKDIRAT:   {paths: []pathArg{{0, 1}}, write: true},
+	unix.SYS_UNLINKAT:  {paths: []pathArg{{0, 1}}, write: true},
+	unix.SYS_RENAMEAT:  {paths: []pathArg{{0, 1}, {2, 3}}, write: true},
+	unix.SYS_RENAMEAT2: {paths: []pathArg{{0, 1}, {2, 3}}, write: true},
+	unix.SYS_LINKAT:    {paths: []pathArg{{0, 1}, {2, 3}}, write: true},
+	unix.SYS_SYMLINKAT: {paths: []pathArg{{1, 2}}, write: true},
+	unix.SYS_TRUNCATE:  {paths: []pathArg{{-1, 0}}, write: true},
+	unix.SYS_FCHMODAT:  {paths: []pathArg{{0, 1}}, write: true},
+}
@@ -0,0 +1,27 @@
+package hermetic
+
+import "golang.org/x/sys/unix"
+
+// Устаревшие системные вызовы, которых нет на arm64.
+func init() {
+	legacy := map[uint64]syscallDesc{
+		unix.SYS_OPEN:     {paths: []pathArg{{-1, 0}}, flags: 1},
+		unix.SYS_STAT:     {paths: []pathArg{{-1, 0}}},
+		unix.SYS_LSTAT:    {paths: []pathArg{{-1, 0}}},
+		unix.SYS_ACCESS:   {paths: []pathArg{{-1, 0}}},
+		unix.SYS_READLINK: {paths: []pathArg{{-1, 0}}},
+
+		unix.SYS_CREAT:   {paths: []pathArg{{-1, 0}}, write: true},
+		unix.SYS_MKDIR:   {paths: []pathArg{{-1, 0}}, write: true},
+		unix.SYS_RMDIR:   {paths: []pathArg{{-1, 0}}, write: true},
+		unix.SYS_UNLINK:  {paths: []pathArg{{-1, 0}}, write: true},
+		unix.SYS_RENAME:  {paths: []pathArg{{-1, 0}, {-1, 1}}, write: true},
+		unix.SYS_LINK:    {paths: []pathArg{{-1, 0}, {-1, 1}}, write: true},
+		unix.SYS_SYMLINK: {paths: []pathArg{{-1, 1}}, write: true},
+		unix.SYS_CHMOD:   {paths: []pathArg{{-1, 0}}, write: true},
+	}
+
+	for nr, desc := range legacy {
+		syscalls[nr] = desc
+	}
+}
@@ -0,0 +1,323 @@
+//go:build linux && (amd64 || arm64)
+
+package hermetic
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// syscallInfo повторяет struct ptrace_syscall_info из linux/ptrace.h.
+type syscallInfo struct {
+	Op   uint8
+	_    [3]uint8
+	Arch uint32
+	IP   uint64
+	SP   uint64
+	Data [7]uint64
+}
+
+type process struct {
+	pending []Access
+}
+
+type tracer struct {
+	procs    map[int]*process
+	accesses []Access
+}
+
+// Run выполняет команду cmd под ptrace и возвращает её код возврата и все её обращения к файлам.
+func Run(ctx context.Context, cmd *build.Cmd, stdout, stderr io.Writer) (int, []Access, error) {
+	if len(cmd.Exec) == 0 {
+		return 0, nil, nil
+	}
+
+	c := exec.Command(cmd.Exec[0], cmd.Exec[1:]...)
+	c.Env = append([]string{}, cmd.Environ...)
+	c.Dir = cmd.WorkingDirectory
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+
+	return Trace(ctx, c)
+}
+
+// output заменяет io.Writer команды на пайп, чтобы не зависеть от exec.Cmd.Wait.
+type output struct {
+	r, w *os.File
+	dst  io.Writer
+}
+
+func redirect(dst io.Writer, set func(w io.Writer)) (*output, error) {
+	if dst == nil {
+		return nil, nil
+	}
+
+	if _, ok := dst.(*os.File); ok {
+		return nil, nil
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	set(w)
+	return &output{r: r, w: w, dst: dst}, nil
+}
+
+// Trace запускает процесс c и трассирует его и всех его потомков до завершения.
+//
+// Процесс должен остановиться на первом exec под ptrace: либо через SysProcAttr.Ptrace,
+// либо вызвав PTRACE_TRACEME самостоятельно. Trace не вызывает c.Wait.
+func Trace(ctx context.Context, c *exec.Cmd) (int, []Access, error) {
+	var outputs []*output
+	for _, o := range []struct {
+		dst io.Writer
+		set func(w io.Writer)
+	}{
+		{c.Stdout, func(w io.Writer) { c.Stdout = w }},
+		{c.Stderr, func(w io.Writer) { c.Stderr = w }},
+	} {
+		out, err := redirect(o.dst, o.set)
+		if err != nil {
+			return 0, nil, err
+		}
+		if out != nil {
+			outputs = append(outputs, out)
+		}
+	}
+
+	var copies sync.WaitGroup
+	defer copies.Wait()
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	err := c.Start()
+	for _, out := range outputs {
+		_ = out.w.Close()
+		if err != nil {
+			_ = out.r.Close()
+			continue
+		}
+
+		copies.Add(1)
+		go func(out *output) {
+			defer copies.Done()
+			defer func() { _ = out.r.Close() }()
+			_, _ = io.Copy(out.dst, out.r)
+		}(out)
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pid := c.Process.Pid
+	defer func() { _ = c.Process.Release() }()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+
+	t := &tracer{procs: map[int]*process{}}
+	exitCode, err := t.run(pid)
+	return exitCode, t.accesses, err
+}
+
+func wait(pid int, ws *unix.WaitStatus) (int, error) {
+	for {
+		wpid, err := unix.Wait4(pid, ws, unix.WALL|unix.WNOTHREAD, nil)
+		if !errors.Is(err, unix.EINTR) {
+			return wpid, err
+		}
+	}
+}
+
+func exitCode(ws unix.WaitStatus) int {
+	if ws.Exited() {
+		return ws.ExitStatus()
+	}
+	return -1
+}
+
+func (t *tracer) run(pid int) (int, error) {
+	var ws unix.WaitStatus
+	if _, err := wait(pid, &ws); err != nil {
+		return 0, err
+	}
+
+	if !ws.Stopped() {
+		return exitCode(ws), fmt.Errorf("process exited before exec")
+	}
+
+	options := unix.PTRACE_O_TRACESYSGOOD | unix.PTRACE_O_TRACEFORK | unix.PTRACE_O_TRACEVFORK |
+		unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_TRACEEXEC | unix.PTRACE_O_EXITKILL
+	if err := unix.PtraceSetOptions(pid, options); err != nil {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+		return 0, fmt.Errorf("ptrace: %w", err)
+	}
+
+	t.procs[pid] = &process{}
+	if err := unix.PtraceSyscall(pid, 0); err != nil {
+		return 0, fmt.Errorf("ptrace: %w", err)
+	}
+
+	code := 0
+	for {
+		wpid, err := wait(-1, &ws)
+		if errors.Is(err, unix.ECHILD) {
+			return code, nil
+		} else if err != nil {
+			return code, err
+		}
+
+		if ws.Exited() || ws.Signaled() {
+			delete(t.procs, wpid)
+			if wpid == pid {
+				code = exitCode(ws)
+			}
+			continue
+		}
+
+		if !ws.Stopped() {
+			continue
+		}
+
+		p, known := t.procs[wpid]
+		if !known {
+			p = &process{}
+			t.procs[wpid] = p
+		}
+
+		inject := 0
+		switch sig := ws.StopSignal(); {
+		case sig == unix.SIGTRAP|0x80:
+			t.onSyscall(wpid, p)
+		case sig == unix.SIGTRAP && ws.TrapCause() > 0:
+		case sig == unix.SIGSTOP && !known:
+		default:
+			inject = int(sig)
+		}
+
+		// Процесс мог умереть от SIGKILL между остановкой и этим вызовом.
+		_ = unix.PtraceSyscall(wpid, inject)
+	}
+}
+
+func (t *tracer) onSyscall(pid int, p *process) {
+	var info syscallInfo
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_GET_SYSCALL_INFO,
+		uintptr(pid), unsafe.Sizeof(info), uintptr(unsafe.Pointer(&info)), 0, 0)
+	if errno != 0 {
+		return
+	}
+
+	switch info.Op {
+	case unix.PTRACE_SYSCALL_INFO_ENTRY:
+		p.pending = t.decode(pid, info.Data[0], info.Data[1:])
+	case unix.PTRACE_SYSCALL_INFO_EXIT:
+		t.accesses = append(t.accesses, p.pending...)
+		p.pending = nil
+	}
+}
+
+func (t *tracer) decode(pid int, nr uint64, args []uint64) []Access {
+	desc, ok := syscalls[nr]
+	if !ok {
+		return nil
+	}
+
+	write := desc.write
+	if desc.flags != 0 {
+		write = isWriteFlags(int(args[desc.flags]))
+	} else if desc.how != 0 {
+		var how [8]byte
+		if _, err := unix.PtracePeekData(pid, uintptr(args[desc.how]), how[:]); err == nil {
+			write = isWriteFlags(int(binary.NativeEndian.Uint64(how[:])))
+		}
+	}
+
+	var accesses []Access
+	for _, arg := range desc.paths {
+		path, err := readString(pid, uintptr(args[arg.path]))
+		if err != nil || path == "" {
+			continue
+		}
+
+		if !filepath.IsAbs(path) {
+			dirfd := unix.AT_FDCWD
+			if arg.dirfd >= 0 {
+				dirfd = int(int32(args[arg.dirfd]))
+			}
+
+			dir, err := resolveDir(pid, dirfd)
+			if err != nil {
+				continue
+			}
+			path = filepath.Join(dir, path)
+		}
+
+		accesses = append(accesses, Access{Path: filepath.Clean(path), Write: write})
+	}
+	return accesses
+}
+
+func isWriteFlags(flags int) bool {
+	return flags&(unix.O_WRONLY|unix.O_RDWR|unix.O_CREAT|unix.O_TRUNC) != 0
+}
+
+func resolveDir(pid int, dirfd int) (string, error) {
+	proc := "/proc/" + strconv.Itoa(pid)
+	if dirfd == unix.AT_FDCWD {
+		return os.Readlink(proc + "/cwd")
+	}
+	return os.Readlink(proc + "/fd/" + strconv.Itoa(dirfd))
+}
+
+// readString читает строку, оканчивающуюся нулём, из памяти процесса.
+func readString(pid int, addr uintptr) (string, error) {
+	if addr == 0 {
+		return "", nil
+	}
+
+	var buf []byte
+	chunk := make([]byte, 64)
+	for len(buf) < unix.PathMax {
+		n, err := unix.PtracePeekData(pid, addr+uintptr(len(buf)), chunk)
+		if err != nil {
+			return "", err
+		}
+
+		if i := bytes.IndexByte(chunk[:n], 0); i >= 0 {
+			return string(append(buf, chunk[:i]...)), nil
+		}
+		buf = append(buf, chunk[:n]...)
+	}
+
+	return "", fmt.Errorf("path is too long")
+}
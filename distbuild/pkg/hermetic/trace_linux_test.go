@@ -0,0 +1,48 @@
+//go:build linux && (amd64 || arm64)
+
+package hermetic_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foo"), 0666))
+
+	cmd := &build.Cmd{
+		Exec:             []string{"bash", "-c", "cat a.txt; cat missing.txt; (echo bar > b.txt)"},
+		WorkingDirectory: dir,
+	}
+
+	var stdout, stderr bytes.Buffer
+	code, accesses, err := hermetic.Run(context.Background(), cmd, &stdout, &stderr)
+	if err != nil {
+		t.Skipf("ptrace is not available: %v", err)
+	}
+
+	require.Equal(t, 0, code)
+	require.Equal(t, "foo", stdout.String())
+	require.Contains(t, accesses, hermetic.Access{Path: filepath.Join(dir, "a.txt")})
+	require.Contains(t, accesses, hermetic.Access{Path: filepath.Join(dir, "missing.txt")})
+	require.Contains(t, accesses, hermetic.Access{Path: filepath.Join(dir, "b.txt"), Write: true})
+}
+
+func TestRunExitCode(t *testing.T) {
+	cmd := &build.Cmd{Exec: []string{"bash", "-c", "exit 3"}}
+
+	code, _, err := hermetic.Run(context.Background(), cmd, nil, nil)
+	if err != nil {
+		t.Skipf("ptrace is not available: %v", err)
+	}
+	require.Equal(t, 3, code)
+}
@@ -0,0 +1,19 @@
+//go:build !linux || !(amd64 || arm64)
+
+package hermetic
+
+import (
+	"context"
+	"io"
+	"os/exec"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+func Run(ctx context.Context, cmd *build.Cmd, stdout, stderr io.Writer) (int, []Access, error) {
+	return 0, nil, ErrNotSupported
+}
+
+func Trace(ctx context.Context, c *exec.Cmd) (int, []Access, error) {
+	return 0, nil, ErrNotSupported
+}
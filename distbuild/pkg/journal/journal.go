@@ -0,0 +1,192 @@
+// Package journal реализует журнал координатора, по которому он восстанавливает состояние после перезапуска.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrClosed = errors.New("journal is closed")
+
+// Record - одна запись журнала. Заполнено ровно одно поле.
+type Record struct {
+	BuildStarted  *BuildStarted  `json:",omitempty"`
+	UploadDone    *BuildEvent    `json:",omitempty"`
+	StatusUpdate  *StatusUpdate  `json:",omitempty"`
+	BuildFinished *BuildFinished `json:",omitempty"`
+
+	ArtifactAdded   *ArtifactLocation `json:",omitempty"`
+	ArtifactEvicted *ArtifactLocation `json:",omitempty"`
+
+	// WorkerLost записывается, когда координатор потерял воркера. Все его артефакты забываются.
+	WorkerLost *WorkerEvent `json:",omitempty"`
+}
+
+type BuildStarted struct {
+	BuildID build.ID
+	Request *api.BuildRequest
+}
+
+type BuildEvent struct {
+	BuildID build.ID
+}
+
+// StatusUpdate - обновление статуса, которое координатор отправил клиенту сборки.
+// После перезапуска координатор повторяет клиенту обновления, которые тот не успел получить.
+type StatusUpdate struct {
+	BuildID build.ID
+	Update  *api.StatusUpdate
+}
+
+// BuildFinished записывается, когда сборка завершилась успешно, с ошибкой или была отменена.
+type BuildFinished struct {
+	BuildID build.ID
+	Error   string `json:",omitempty"`
+}
+
+type WorkerEvent struct {
+	WorkerID api.WorkerID
+}
+
+type ArtifactLocation struct {
+	WorkerID   api.WorkerID
+	ArtifactID build.ID
+}
+
+// Journal - журнал упреждающей записи в файле. Каждая запись - строка JSON.
+//
+// Append возвращается только после того, как запись попала на диск. Все методы Journal concurrency safe.
+type Journal struct {
+	path string
+
+	mu     sync.Mutex
+	f      *os.File
+	closed bool
+}
+
+// Open открывает журнал path и восстанавливает по нему состояние.
+//
+// Перед тем как дописывать новые записи, Open сжимает журнал: в нём остаются только незавершённые
+// сборки и текущее расположение артефактов. Недописанная последняя запись, которая остаётся после
+// падения посреди Append, отбрасывается.
+func Open(path string) (*Journal, *State, error) {
+	state, err := replay(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := compact(path, state); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Journal{path: path, f: f}, state, nil
+}
+
+func replay(path string) (*State, error) {
+	state := newState()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Строка без перевода строки в конце - запись, которую не успели дописать.
+			return state, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("journal %s: %w", path, err)
+		}
+		state.apply(&rec)
+	}
+}
+
+// compact атомарно заменяет журнал path записями, из которых получается state.
+func compact(path string, state *State) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, rec := range state.records() {
+		if err := enc.Encode(rec); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Append дописывает запись в журнал.
+func (j *Journal) Append(rec *Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+
+	if _, err := j.f.Write(line); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Close закрывает журнал. После Close все вызовы Append возвращают ErrClosed.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return nil
+	}
+
+	j.closed = true
+	return j.f.Close()
+}
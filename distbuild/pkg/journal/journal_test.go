@@ -0,0 +1,73 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/journal"
+)
+
+func TestRecovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	j, state, err := journal.Open(path)
+	require.NoError(t, err)
+	require.Empty(t, state.Builds)
+
+	request := &api.BuildRequest{Graph: build.Graph{Jobs: []build.Job{{ID: build.ID{'a'}, Name: "a"}}}}
+	result := &api.JobResult{ID: build.ID{'a'}, Stdout: []byte("OK")}
+
+	for _, rec := range []*journal.Record{
+		{BuildStarted: &journal.BuildStarted{BuildID: build.ID{1}, Request: request}},
+		{BuildStarted: &journal.BuildStarted{BuildID: build.ID{2}, Request: request}},
+		{UploadDone: &journal.BuildEvent{BuildID: build.ID{1}}},
+		{StatusUpdate: &journal.StatusUpdate{BuildID: build.ID{1}, Update: &api.StatusUpdate{JobFinished: result}}},
+		{BuildFinished: &journal.BuildFinished{BuildID: build.ID{2}, Error: "build cancelled"}},
+		{ArtifactAdded: &journal.ArtifactLocation{WorkerID: "w0", ArtifactID: build.ID{'a'}}},
+		{ArtifactAdded: &journal.ArtifactLocation{WorkerID: "w1", ArtifactID: build.ID{'a'}}},
+		{ArtifactEvicted: &journal.ArtifactLocation{WorkerID: "w1", ArtifactID: build.ID{'a'}}},
+		{ArtifactAdded: &journal.ArtifactLocation{WorkerID: "w2", ArtifactID: build.ID{'a'}}},
+		{ArtifactAdded: &journal.ArtifactLocation{WorkerID: "w2", ArtifactID: build.ID{'b'}}},
+		{WorkerLost: &journal.WorkerEvent{WorkerID: "w2"}},
+	} {
+		require.NoError(t, j.Append(rec))
+	}
+	require.NoError(t, j.Close())
+	require.ErrorIs(t, j.Append(&journal.Record{}), journal.ErrClosed)
+
+	// Падение посреди записи оставляет в конце журнала обрывок строки.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"BuildStarted":{"Buil`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	check := func(state *journal.State) {
+		t.Helper()
+
+		require.Len(t, state.Builds, 1)
+		b := state.Builds[0]
+		require.Equal(t, build.ID{1}, b.ID)
+		require.Equal(t, request, b.Request)
+		require.True(t, b.Uploaded)
+		require.Equal(t, []*api.StatusUpdate{{JobFinished: result}}, b.Updates)
+
+		require.Equal(t, map[api.WorkerID]map[build.ID]struct{}{"w0": {{'a'}: {}}}, state.Artifacts)
+	}
+
+	j, state, err = journal.Open(path)
+	require.NoError(t, err)
+	check(state)
+	require.NoError(t, j.Close())
+
+	// После сжатия журнал восстанавливается в то же состояние.
+	j, state, err = journal.Open(path)
+	require.NoError(t, err)
+	check(state)
+	require.NoError(t, j.Close())
+}
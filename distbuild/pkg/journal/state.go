@@ -0,0 +1,109 @@
+package journal
+
+import (
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Build - незавершённая сборка, восстановленная из журнала.
+type Build struct {
+	ID      build.ID
+	Request *api.BuildRequest
+
+	// Uploaded равен true, если клиент успел залить исходные файлы.
+	Uploaded bool
+
+	// Updates - обновления статуса, отправленные клиенту, в порядке отправки.
+	Updates []*api.StatusUpdate
+}
+
+// State - состояние координатора, восстановленное из журнала.
+type State struct {
+	// Builds содержит незавершённые сборки в порядке запуска.
+	Builds []*Build
+
+	// Artifacts хранит, какие артефакты есть в кеше каждого воркера.
+	Artifacts map[api.WorkerID]map[build.ID]struct{}
+
+	builds map[build.ID]*Build
+}
+
+func newState() *State {
+	return &State{
+		Artifacts: make(map[api.WorkerID]map[build.ID]struct{}),
+		builds:    make(map[build.ID]*Build),
+	}
+}
+
+func (s *State) apply(rec *Record) {
+	switch {
+	case rec.BuildStarted != nil:
+		b := &Build{ID: rec.BuildStarted.BuildID, Request: rec.BuildStarted.Request}
+		s.builds[b.ID] = b
+		s.Builds = append(s.Builds, b)
+
+	case rec.UploadDone != nil:
+		if b, ok := s.builds[rec.UploadDone.BuildID]; ok {
+			b.Uploaded = true
+		}
+
+	case rec.StatusUpdate != nil:
+		if b, ok := s.builds[rec.StatusUpdate.BuildID]; ok {
+			b.Updates = append(b.Updates, rec.StatusUpdate.Update)
+		}
+
+	case rec.BuildFinished != nil:
+		id := rec.BuildFinished.BuildID
+		if _, ok := s.builds[id]; !ok {
+			return
+		}
+
+		delete(s.builds, id)
+		for i, b := range s.Builds {
+			if b.ID == id {
+				s.Builds = append(s.Builds[:i], s.Builds[i+1:]...)
+				break
+			}
+		}
+
+	case rec.ArtifactAdded != nil:
+		loc := rec.ArtifactAdded
+		if s.Artifacts[loc.WorkerID] == nil {
+			s.Artifacts[loc.WorkerID] = make(map[build.ID]struct{})
+		}
+		s.Artifacts[loc.WorkerID][loc.ArtifactID] = struct{}{}
+
+	case rec.ArtifactEvicted != nil:
+		loc := rec.ArtifactEvicted
+		delete(s.Artifacts[loc.WorkerID], loc.ArtifactID)
+		if len(s.Artifacts[loc.WorkerID]) == 0 {
+			delete(s.Artifacts, loc.WorkerID)
+		}
+
+	case rec.WorkerLost != nil:
+		delete(s.Artifacts, rec.WorkerLost.WorkerID)
+	}
+}
+
+// records возвращает минимальный набор записей, из которого получается s.
+func (s *State) records() []*Record {
+	var records []*Record
+
+	for workerID, artifacts := range s.Artifacts {
+		for id := range artifacts {
+			records = append(records, &Record{ArtifactAdded: &ArtifactLocation{WorkerID: workerID, ArtifactID: id}})
+		}
+	}
+
+	for _, b := range s.Builds {
+		records = append(records, &Record{BuildStarted: &BuildStarted{BuildID: b.ID, Request: b.Request}})
+		if b.Uploaded {
+			records = append(records, &Record{UploadDone: &BuildEvent{BuildID: b.ID}})
+		}
+		for _, u := range b.Updates {
+			records = append(records, &Record{StatusUpdate: &StatusUpdate{BuildID: b.ID, Update: u}})
+		}
+	}
+
+	return records
+}
@@ -0,0 +1,161 @@
+package remotecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var ErrNotFound = errors.New("cache entry not found")
+
+// Client ходит в удалённый кеш по HTTP протоколу Bazel.
+type Client struct {
+	l        *zap.Logger
+	endpoint string
+	http     *http.Client
+}
+
+// ClientOption задаёт необязательный параметр клиента.
+type ClientOption func(c *Client)
+
+// WithHTTPClient задаёт http.Client, через который клиент отправляет запросы, например
+// с TLS и токеном из auth.NewHTTPClient. По умолчанию используется http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+func NewClient(l *zap.Logger, endpoint string, opts ...ClientOption) *Client {
+	c := &Client{l: l, endpoint: endpoint, http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	body, err := c.open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	return io.ReadAll(body)
+}
+
+// open возвращает тело ответа на GET path. Тело нужно закрыть.
+func (c *Client) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rsp.StatusCode {
+	case http.StatusOK:
+		return rsp.Body, nil
+	case http.StatusNotFound:
+		_ = rsp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		_ = rsp.Body.Close()
+		return nil, fmt.Errorf("GET %s failed: %s", path, bytes.TrimSpace(errorMsg))
+	}
+}
+
+func (c *Client) put(ctx context.Context, path string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		errorMsg, _ := io.ReadAll(rsp.Body)
+		return fmt.Errorf("PUT %s failed: %s", path, bytes.TrimSpace(errorMsg))
+	}
+	return nil
+}
+
+// GetBlob скачивает блоб из /cas/ в w и возвращает его размер. Блоб не читается в память целиком,
+// а его sha256 считается по ходу записи.
+//
+// Если sha256 не совпал с key, GetBlob возвращает ошибку, и всё записанное в w нужно выбросить.
+func (c *Client) GetBlob(ctx context.Context, key Hash, w io.Writer) (int64, error) {
+	body, err := c.open(ctx, "/cas/"+key.String())
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = body.Close() }()
+
+	hw := &hashWriter{w: w, h: sha256.New()}
+	n, err := io.Copy(hw, body)
+	if err != nil {
+		return n, err
+	}
+
+	var got Hash
+	copy(got[:], hw.h.Sum(nil))
+	if got != key {
+		return n, fmt.Errorf("blob %s: digest mismatch: got %s", key, got)
+	}
+	return n, nil
+}
+
+// PutBlob загружает data в /cas/ и возвращает его ключ.
+func (c *Client) PutBlob(ctx context.Context, data []byte) (Hash, error) {
+	key := BlobHash(data)
+	return key, c.put(ctx, "/cas/"+key.String(), data)
+}
+
+func (c *Client) GetAction(ctx context.Context, key Hash) ([]byte, error) {
+	return c.get(ctx, "/ac/"+key.String())
+}
+
+func (c *Client) PutAction(ctx context.Context, key Hash, data []byte) error {
+	return c.put(ctx, "/ac/"+key.String(), data)
+}
+
+// GetActionResult возвращает сохранённый результат джоба. Если результата нет, возвращается ErrNotFound.
+func (c *Client) GetActionResult(ctx context.Context, jobID build.ID) (*ActionResult, error) {
+	data, err := c.GetAction(ctx, ActionKey(jobID))
+	if err != nil {
+		return nil, err
+	}
+
+	var res ActionResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("action result of job %s: %w", jobID, err)
+	}
+
+	c.l.Debug("action result found", zap.String("job_id", jobID.String()))
+	return &res, nil
+}
+
+func (c *Client) PutActionResult(ctx context.Context, jobID build.ID, res *ActionResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return c.PutAction(ctx, ActionKey(jobID), data)
+}
@@ -0,0 +1,334 @@
+package remotecache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+// actionFileName - файл внутри артефакта, в котором хранится запись /ac/.
+const actionFileName = "action"
+
+// maxActionSize ограничивает размер записи в /ac/.
+const maxActionSize = 64 << 20
+
+const (
+	// putActionTimeout ограничивает время, которое PUT в /ac/ ждёт, пока другой запрос создаёт ту же запись.
+	putActionTimeout    = 5 * time.Second
+	putActionRetryDelay = 10 * time.Millisecond
+)
+
+// Handler реализует HTTP протокол удалённого кеша Bazel.
+//
+//	GET /ac/<sha256>, PUT /ac/<sha256>   - action cache, записи хранятся в artifact.Cache.
+//	GET /cas/<sha256>, PUT /cas/<sha256> - content addressable storage, блобы хранятся в filecache.Cache.
+//
+// Для CAS нужен отдельный filecache.Cache: в нём записи адресуются префиксом sha256, а не build.FileID,
+// и очищаются по своим ограничениям на размер.
+//
+// PUT в /cas/ проверяет, что sha256 тела запроса совпадает с ключом.
+type Handler struct {
+	l   *zap.Logger
+	ac  *artifact.Cache
+	cas *filecache.Cache
+}
+
+func NewHandler(l *zap.Logger, ac *artifact.Cache, cas *filecache.Cache) *Handler {
+	return &Handler{l: l, ac: ac, cas: cas}
+}
+
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/ac/{hash}", h.action)
+	mux.HandleFunc("/cas/{hash}", h.blob)
+}
+
+func (h *Handler) parse(w http.ResponseWriter, r *http.Request) (Hash, bool) {
+	key, err := ParseHash(r.PathValue("hash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return key, false
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return key, false
+	}
+	return key, true
+}
+
+func (h *Handler) action(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.parse(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		h.putAction(w, r, key)
+		return
+	}
+
+	// Запись, которую сейчас создаёт PUT, ещё не видна.
+	dir, unlock, err := h.ac.Get(key.ID())
+	if errors.Is(err, artifact.ErrNotFound) || errors.Is(err, artifact.ErrWriteLocked) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.l.Warn("failed to open action", zap.String("key", key.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
+	h.serveFile(w, r, filepath.Join(dir, actionFileName))
+}
+
+func (h *Handler) putAction(w http.ResponseWriter, r *http.Request, key Hash) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxActionSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if len(data) > maxActionSize {
+		http.Error(w, "action is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := putAction(h.ac, key, data); err != nil {
+		h.writeError(w, key, err)
+		return
+	}
+
+	h.l.Debug("action stored", zap.String("key", key.String()))
+}
+
+// putAction сохраняет запись /ac/. Запись можно перезаписать, как и в Bazel.
+//
+// Конкурентные PUT и GET одного ключа не мешают друг другу: существующая запись подменяется атомарно,
+// а запись, которую сейчас создаёт другой запрос, putAction ждёт до putActionTimeout.
+func putAction(ac *artifact.Cache, key Hash, data []byte) error {
+	deadline := time.Now().Add(putActionTimeout)
+	for {
+		err := tryPutAction(ac, key, data)
+		if !isBusy(err) || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(putActionRetryDelay)
+	}
+}
+
+func tryPutAction(ac *artifact.Cache, key Hash, data []byte) error {
+	dir, commit, abort, err := ac.Create(key.ID())
+	if errors.Is(err, artifact.ErrExists) {
+		return replaceAction(ac, key, data)
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, actionFileName), data, 0666); err != nil {
+		_ = abort()
+		return err
+	}
+
+	return commit()
+}
+
+// replaceAction записывает data во временный файл внутри существующей записи и переименовывает его
+// поверх старого файла, поэтому GET видит либо старую, либо новую запись. Лок на чтение не даёт удалить
+// запись, пока она подменяется. Размер записи в artifact.Cache не пересчитывается: записи /ac/ маленькие.
+func replaceAction(ac *artifact.Cache, key Hash, data []byte) error {
+	dir, unlock, err := ac.Get(key.ID())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := filepath.Join(dir, actionFileName)
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(dir, actionFileName+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isBusy проверяет, что запись создаёт другой запрос или она исчезла между проверками,
+// и её можно попробовать записать ещё раз.
+func isBusy(err error) bool {
+	return errors.Is(err, artifact.ErrWriteLocked) || errors.Is(err, artifact.ErrNotFound)
+}
+
+// StoreActionResult сохраняет результат джоба в локальный action cache, который раздаёт Handler.
+//
+// Если результат джоба уже сохранён и все его выходы есть в cas, StoreActionResult ничего не делает.
+func StoreActionResult(ac *artifact.Cache, cas *filecache.Cache, jobID build.ID, res *ActionResult) error {
+	if HasActionResult(ac, cas, jobID) {
+		return nil
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return putAction(ac, ActionKey(jobID), data)
+}
+
+// HasActionResult проверяет, что результат джоба есть в локальном action cache, а файлы
+// его выходов не удалены из cas.
+func HasActionResult(ac *artifact.Cache, cas *filecache.Cache, jobID build.ID) bool {
+	res, err := loadActionResult(ac, jobID)
+	if err != nil {
+		return false
+	}
+
+	for _, f := range res.OutputFiles {
+		_, unlock, err := cas.Get(f.Digest.Hash.ID())
+		if err != nil {
+			return false
+		}
+		unlock()
+	}
+	return true
+}
+
+func loadActionResult(ac *artifact.Cache, jobID build.ID) (*ActionResult, error) {
+	dir, unlock, err := ac.Get(ActionKey(jobID).ID())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(filepath.Join(dir, actionFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var res ActionResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (h *Handler) blob(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.parse(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		h.putBlob(w, r, key)
+		return
+	}
+
+	path, unlock, err := h.cas.Get(key.ID())
+	if errors.Is(err, filecache.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.l.Warn("failed to open blob", zap.String("key", key.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer unlock()
+
+	h.serveFile(w, r, path)
+}
+
+func (h *Handler) putBlob(w http.ResponseWriter, r *http.Request, key Hash) {
+	f, abort, err := h.cas.Write(key.ID())
+	if errors.Is(err, filecache.ErrExists) {
+		// Блоб с таким содержимым уже есть.
+		_, _ = io.Copy(io.Discard, r.Body)
+		return
+	} else if err != nil {
+		h.writeError(w, key, err)
+		return
+	}
+
+	hw := &hashWriter{w: f, h: sha256.New()}
+	if _, err := io.Copy(hw, r.Body); err != nil {
+		_ = abort()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var got Hash
+	copy(got[:], hw.h.Sum(nil))
+	if got != key {
+		_ = abort()
+		h.l.Warn("blob digest mismatch", zap.String("key", key.String()), zap.String("got", got.String()))
+		http.Error(w, fmt.Sprintf("digest mismatch: got %s", got), http.StatusBadRequest)
+		return
+	}
+
+	if err := f.Close(); err != nil {
+		h.writeError(w, key, err)
+		return
+	}
+
+	h.l.Debug("blob stored", zap.String("key", key.String()))
+}
+
+func (h *Handler) serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		h.l.Warn("failed to send cache entry", zap.String("path", path), zap.Error(err))
+	}
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, key Hash, err error) {
+	switch {
+	case errors.Is(err, artifact.ErrWriteLocked), errors.Is(err, artifact.ErrReadLocked),
+		errors.Is(err, filecache.ErrWriteLocked), errors.Is(err, filecache.ErrReadLocked):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.l.Warn("failed to store cache entry", zap.String("key", key.String()), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type hashWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	_, _ = w.h.Write(p)
+	return w.w.Write(p)
+}
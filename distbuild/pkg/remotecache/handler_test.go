@@ -0,0 +1,253 @@
+package remotecache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+type env struct {
+	ac     *artifact.Cache
+	cas    *filecache.Cache
+	server *httptest.Server
+	client *remotecache.Client
+}
+
+func newEnv(t *testing.T) *env {
+	l := zaptest.NewLogger(t)
+
+	ac, err := artifact.NewCache(t.TempDir())
+	require.NoError(t, err)
+
+	cas, err := filecache.New(t.TempDir())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	remotecache.NewHandler(l, ac, cas).Register(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &env{ac: ac, cas: cas, server: server, client: remotecache.NewClient(l, server.URL)}
+}
+
+func TestBlobs(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	content := bytes.Repeat([]byte("foobar"), 1024)
+
+	_, err := env.client.GetBlob(ctx, remotecache.BlobHash(content), io.Discard)
+	require.Truef(t, errors.Is(err, remotecache.ErrNotFound), "%v", err)
+
+	key, err := env.client.PutBlob(ctx, content)
+	require.NoError(t, err)
+	require.Equal(t, remotecache.BlobHash(content), key)
+
+	// Повторная загрузка того же блоба не ошибка.
+	_, err = env.client.PutBlob(ctx, content)
+	require.NoError(t, err)
+
+	var data bytes.Buffer
+	n, err := env.client.GetBlob(ctx, key, &data)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), n)
+	require.Equal(t, content, data.Bytes())
+}
+
+func TestBlobDigestMismatch(t *testing.T) {
+	env := newEnv(t)
+
+	key := remotecache.BlobHash([]byte("foo"))
+	req, err := http.NewRequest(http.MethodPut, env.server.URL+"/cas/"+key.String(), bytes.NewReader([]byte("bar")))
+	require.NoError(t, err)
+
+	rsp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	_, err = env.client.GetBlob(context.Background(), key, io.Discard)
+	require.Truef(t, errors.Is(err, remotecache.ErrNotFound), "%v", err)
+}
+
+func TestInvalidHash(t *testing.T) {
+	env := newEnv(t)
+
+	rsp, err := http.Get(env.server.URL + "/ac/1234")
+	require.NoError(t, err)
+	_ = rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+}
+
+func TestActionResults(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	jobID := build.ID{'a'}
+
+	_, err := env.client.GetActionResult(ctx, jobID)
+	require.Truef(t, errors.Is(err, remotecache.ErrNotFound), "%v", err)
+
+	res := &remotecache.ActionResult{StdoutRaw: []byte("OK\n")}
+	require.NoError(t, env.client.PutActionResult(ctx, jobID, res))
+
+	got, err := env.client.GetActionResult(ctx, jobID)
+	require.NoError(t, err)
+	require.Equal(t, res, got)
+
+	// Запись в /ac/ можно перезаписать.
+	res.StderrRaw = []byte("warning\n")
+	require.NoError(t, env.client.PutActionResult(ctx, jobID, res))
+
+	got, err = env.client.GetActionResult(ctx, jobID)
+	require.NoError(t, err)
+	require.Equal(t, res, got)
+
+	// StoreActionResult не перезаписывает существующую запись.
+	require.NoError(t, remotecache.StoreActionResult(env.ac, env.cas, jobID, &remotecache.ActionResult{}))
+
+	got, err = env.client.GetActionResult(ctx, jobID)
+	require.NoError(t, err)
+	require.Equal(t, res, got)
+}
+
+func TestConcurrentActionPuts(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	key := remotecache.ActionKey(build.ID{'a'})
+
+	// Одинаковые и разные записи одного ключа, а также чтения во время записи.
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				assert.NoError(t, env.client.PutAction(ctx, key, []byte{byte('a' + i%2)}))
+
+				_, err := env.client.GetAction(ctx, key)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := env.client.GetAction(ctx, key)
+	require.NoError(t, err)
+	require.Contains(t, []string{"a", "b"}, string(data))
+}
+
+func TestOutputs(t *testing.T) {
+	env := newEnv(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0777))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "tool"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), []byte("OK\n"), 0644))
+	require.NoError(t, os.Symlink("bin/tool", filepath.Join(dir, "link")))
+
+	res := &remotecache.ActionResult{}
+	require.NoError(t, remotecache.StoreOutputs(env.cas, dir, res))
+
+	require.Equal(t, []remotecache.OutputDirectory{{Path: "bin"}, {Path: "empty"}}, res.OutputDirectories)
+	require.Equal(t, []remotecache.OutputSymlink{{Path: "link", Target: "bin/tool"}}, res.OutputSymlinks)
+	require.Equal(t, []remotecache.OutputFile{
+		{
+			Path:         "bin/tool",
+			Digest:       remotecache.Digest{Hash: remotecache.BlobHash([]byte("#!/bin/sh\n")), SizeBytes: 10},
+			IsExecutable: true,
+		},
+		{
+			Path:   "out.txt",
+			Digest: remotecache.Digest{Hash: remotecache.BlobHash([]byte("OK\n")), SizeBytes: 3},
+		},
+	}, res.OutputFiles)
+
+	// Запись переживает json, в котором хранится в /ac/.
+	require.NoError(t, env.client.PutActionResult(ctx, build.ID{'a'}, res))
+	res, err := env.client.GetActionResult(ctx, build.ID{'a'})
+	require.NoError(t, err)
+
+	restored := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, env.client.DownloadOutputs(ctx, res, restored))
+
+	data, err := os.ReadFile(filepath.Join(restored, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh\n", string(data))
+
+	st, err := os.Stat(filepath.Join(restored, "bin", "tool"))
+	require.NoError(t, err)
+	require.NotZero(t, st.Mode()&0100)
+
+	st, err = os.Stat(filepath.Join(restored, "empty"))
+	require.NoError(t, err)
+	require.True(t, st.IsDir())
+
+	// Пути из кеша не выходят за пределы выходной директории.
+	res = &remotecache.ActionResult{OutputDirectories: []remotecache.OutputDirectory{{Path: "../escape"}}}
+	require.ErrorContains(t, env.client.DownloadOutputs(ctx, res, restored), "invalid output path")
+}
+
+func TestHasActionResult(t *testing.T) {
+	env := newEnv(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.txt"), []byte("OK\n"), 0666))
+
+	jobID := build.ID{'a'}
+	require.False(t, remotecache.HasActionResult(env.ac, env.cas, jobID))
+
+	res := &remotecache.ActionResult{}
+	require.NoError(t, remotecache.StoreOutputs(env.cas, dir, res))
+	require.NoError(t, remotecache.StoreActionResult(env.ac, env.cas, jobID, res))
+	require.True(t, remotecache.HasActionResult(env.ac, env.cas, jobID))
+
+	// Результат без выходов в CAS нельзя использовать.
+	require.NoError(t, env.cas.Remove(res.OutputFiles[0].Digest.Hash.ID()))
+	require.False(t, remotecache.HasActionResult(env.ac, env.cas, jobID))
+}
+
+func TestDownloadOutputsDigestMismatch(t *testing.T) {
+	env := newEnv(t)
+
+	// Блоб в CAS испорчен на диске.
+	key := remotecache.BlobHash([]byte("OK\n"))
+	w, _, err := env.cas.Write(key.ID())
+	require.NoError(t, err)
+	_, err = w.Write([]byte("KO\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	res := &remotecache.ActionResult{OutputFiles: []remotecache.OutputFile{
+		{Path: "out.txt", Digest: remotecache.Digest{Hash: key, SizeBytes: 3}},
+	}}
+
+	dir := t.TempDir()
+	require.ErrorContains(t, env.client.DownloadOutputs(context.Background(), res, dir), "digest mismatch")
+
+	// Недокачанный файл не остаётся в выходной директории.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
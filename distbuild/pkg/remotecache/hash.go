@@ -0,0 +1,97 @@
+package remotecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Hash - sha256 digest, которым адресуются записи в /ac/ и /cas/.
+type Hash [sha256.Size]byte
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(b []byte) error {
+	var err error
+	*h, err = ParseHash(string(b))
+	return err
+}
+
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != hex.EncodedLen(len(h)) {
+		return h, fmt.Errorf("invalid hash %q: want %d hex digits", s, hex.EncodedLen(len(h)))
+	}
+
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, fmt.Errorf("invalid hash %q: %w", s, err)
+	}
+	return h, nil
+}
+
+// BlobHash вычисляет ключ, под которым data хранится в CAS.
+func BlobHash(data []byte) Hash {
+	return sha256.Sum256(data)
+}
+
+// ID возвращает ID, под которым запись хранится в artifact.Cache или filecache.Cache.
+//
+// Кеши адресуются 20-байтными ID, поэтому используется префикс хеша.
+func (h Hash) ID() build.ID {
+	var id build.ID
+	copy(id[:], h[:])
+	return id
+}
+
+// ActionKey возвращает ключ в /ac/, под которым хранится ActionResult джоба.
+func ActionKey(jobID build.ID) Hash {
+	return sha256.Sum256(append([]byte("distbuild action\x00"), jobID[:]...))
+}
+
+// ActionResult описывает результат успешного джоба, сохранённый в /ac/.
+//
+// Запись кодируется в json. Другие инструменты могут хранить в /ac/ данные в своём формате.
+//
+// Как и в Bazel, выходы джоба перечислены в OutputFiles, OutputSymlinks и OutputDirectories, а содержимое
+// файлов лежит в /cas/. Пути задаются относительно выходной директории джоба ({{.OutputDir}}).
+// В отличие от Bazel, директории не ссылаются на Tree: все файлы, в том числе вложенные, перечислены
+// в OutputFiles, а OutputDirectories нужен, чтобы восстановить пустые директории.
+type ActionResult struct {
+	ExitCode int
+
+	OutputFiles       []OutputFile      `json:",omitempty"`
+	OutputSymlinks    []OutputSymlink   `json:",omitempty"`
+	OutputDirectories []OutputDirectory `json:",omitempty"`
+
+	StdoutRaw []byte `json:",omitempty"`
+	StderrRaw []byte `json:",omitempty"`
+}
+
+// Digest ссылается на блоб в /cas/.
+type Digest struct {
+	Hash      Hash
+	SizeBytes int64
+}
+
+type OutputFile struct {
+	Path         string
+	Digest       Digest
+	IsExecutable bool `json:",omitempty"`
+}
+
+type OutputSymlink struct {
+	Path   string
+	Target string
+}
+
+type OutputDirectory struct {
+	Path string
+}
@@ -0,0 +1,179 @@
+package remotecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+)
+
+// StoreOutputs кладёт файлы выходной директории джоба dir в cas и перечисляет её содержимое в res.
+//
+// Блобы, которые уже есть в cas, не перезаписываются.
+func StoreOutputs(cas *filecache.Cache, dir string, res *ActionResult) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir():
+			res.OutputDirectories = append(res.OutputDirectories, OutputDirectory{Path: rel})
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			res.OutputSymlinks = append(res.OutputSymlinks, OutputSymlink{Path: rel, Target: target})
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			digest, err := storeBlob(cas, path)
+			if err != nil {
+				return err
+			}
+			res.OutputFiles = append(res.OutputFiles, OutputFile{Path: rel, Digest: digest, IsExecutable: info.Mode()&0111 != 0})
+		default:
+			return fmt.Errorf("output %s: unsupported file type %s", rel, d.Type())
+		}
+		return nil
+	})
+}
+
+func storeBlob(cas *filecache.Cache, path string) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return Digest{}, err
+	}
+
+	digest := Digest{SizeBytes: n}
+	copy(digest.Hash[:], h.Sum(nil))
+
+	w, abort, err := cas.Write(digest.Hash.ID())
+	if errors.Is(err, filecache.ErrExists) {
+		return digest, nil
+	} else if err != nil {
+		return digest, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		_ = abort()
+		return digest, err
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		_ = abort()
+		return digest, err
+	}
+	return digest, w.Close()
+}
+
+// DownloadOutputs восстанавливает в директории dir выходы джоба, перечисленные в res. Файлы скачиваются из /cas/.
+func (c *Client) DownloadOutputs(ctx context.Context, res *ActionResult, dir string) error {
+	for _, d := range res.OutputDirectories {
+		path, err := outputPath(dir, d.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(path, 0777); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range res.OutputFiles {
+		path, err := outputPath(dir, f.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := c.downloadFile(ctx, f, path); err != nil {
+			return fmt.Errorf("output %s: %w", f.Path, err)
+		}
+	}
+
+	for _, s := range res.OutputSymlinks {
+		path, err := outputPath(dir, s.Path)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return err
+		}
+
+		if err := os.Symlink(s.Target, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadFile скачивает файл f во временный файл рядом с path и переименовывает его в path,
+// только если содержимое совпало с digest.
+func (c *Client) downloadFile(ctx context.Context, f OutputFile, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	n, err := c.GetBlob(ctx, f.Digest.Hash, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if n != f.Digest.SizeBytes {
+		return fmt.Errorf("size mismatch: got %d, want %d", n, f.Digest.SizeBytes)
+	}
+
+	perm := fs.FileMode(0644)
+	if f.IsExecutable {
+		perm = 0755
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// outputPath проверяет, что путь из ActionResult не выходит за пределы выходной директории.
+func outputPath(dir, name string) (string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return "", fmt.Errorf("invalid output path %q", name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
@@ -0,0 +1,196 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cgroupPeriod = 100000
+
+// findCgroupRoot возвращает директорию cgroup v2 текущего процесса.
+func findCgroupRoot() (string, error) {
+	mountinfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return "", err
+	}
+
+	var mountPoint string
+	for _, line := range strings.Split(string(mountinfo), "\n") {
+		// 36 35 0:30 / /sys/fs/cgroup rw,nosuid - cgroup2 cgroup2 rw
+		pre, post, ok := strings.Cut(line, " - ")
+		if !ok || !strings.HasPrefix(post, "cgroup2 ") {
+			continue
+		}
+
+		fields := strings.Fields(pre)
+		if len(fields) >= 5 {
+			mountPoint = fields[4]
+			break
+		}
+	}
+
+	if mountPoint == "" {
+		return "", errors.New("cgroup v2 is not mounted")
+	}
+
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(self), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(mountPoint, path), nil
+		}
+	}
+
+	return "", errors.New("process is not attached to cgroup v2 hierarchy")
+}
+
+func (l Limits) controllers() []string {
+	var controllers []string
+	if l.CPUs > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if l.Memory > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if l.Pids > 0 {
+		controllers = append(controllers, "pids")
+	}
+	return controllers
+}
+
+// enableControllers включает контроллеры для дочерних cgroup директории root.
+func enableControllers(root string, controllers []string) error {
+	available, err := os.ReadFile(filepath.Join(root, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+
+	enabled, err := os.ReadFile(filepath.Join(root, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, c := range controllers {
+		if !hasWord(available, c) {
+			return fmt.Errorf("cgroup v2 controller %q is not available in %s", c, root)
+		}
+
+		if !hasWord(enabled, c) {
+			missing = append(missing, "+"+c)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte(strings.Join(missing, " ")), 0); err != nil {
+		return fmt.Errorf("enable cgroup controllers in %s: %w (set Config.CgroupRoot to a delegated cgroup)", root, err)
+	}
+
+	return nil
+}
+
+func hasWord(b []byte, word string) bool {
+	for _, w := range strings.Fields(string(b)) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+type cgroup struct {
+	path   string
+	limits Limits
+}
+
+func newCgroup(root string, name string, limits Limits) (*cgroup, error) {
+	cg := &cgroup{path: filepath.Join(root, name), limits: limits}
+	if err := os.Mkdir(cg.path, 0755); err != nil {
+		return nil, err
+	}
+
+	write := func(file, value string) error {
+		return os.WriteFile(filepath.Join(cg.path, file), []byte(value), 0)
+	}
+
+	var err error
+	if limits.CPUs > 0 {
+		quota := int64(limits.CPUs * cgroupPeriod)
+		err = errors.Join(err, write("cpu.max", fmt.Sprintf("%d %d", quota, cgroupPeriod)))
+	}
+	if limits.Memory > 0 {
+		err = errors.Join(err, write("memory.max", strconv.FormatInt(limits.Memory, 10)))
+		if _, statErr := os.Stat(filepath.Join(cg.path, "memory.swap.max")); statErr == nil {
+			err = errors.Join(err, write("memory.swap.max", "0"))
+		}
+	}
+	if limits.Pids > 0 {
+		err = errors.Join(err, write("pids.max", strconv.FormatInt(limits.Pids, 10)))
+	}
+
+	if err != nil {
+		_ = cg.destroy()
+		return nil, fmt.Errorf("configure cgroup %s: %w", cg.path, err)
+	}
+
+	return cg, nil
+}
+
+// readEvent читает счётчик из файла вида memory.events.
+func (cg *cgroup) readEvent(file, key string) int64 {
+	f, err := os.Open(filepath.Join(cg.path, file))
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = f.Close() }()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		k, v, ok := bytes.Cut(s.Bytes(), []byte(" "))
+		if ok && string(k) == key {
+			n, _ := strconv.ParseInt(string(v), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// checkLimits возвращает *LimitError, если команда упёрлась в ограничение.
+func (cg *cgroup) checkLimits() error {
+	if cg.limits.Memory > 0 && cg.readEvent("memory.events", "oom_kill") > 0 {
+		return &LimitError{Resource: "memory", Limit: strconv.FormatInt(cg.limits.Memory, 10) + " bytes"}
+	}
+
+	if cg.limits.Pids > 0 && cg.readEvent("pids.events", "max") > 0 {
+		return &LimitError{Resource: "pids", Limit: strconv.FormatInt(cg.limits.Pids, 10)}
+	}
+
+	return nil
+}
+
+// destroy убивает оставшиеся процессы и удаляет cgroup.
+func (cg *cgroup) destroy() error {
+	_ = os.WriteFile(filepath.Join(cg.path, "cgroup.kill"), []byte("1"), 0)
+
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = os.Remove(cg.path); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotSupported = errors.New("sandbox is not supported on this platform")
+
+// DefaultReadOnlyPaths перечисляет системные директории, которые нужны для запуска
+// типичных команд сборки.
+var DefaultReadOnlyPaths = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc"}
+
+// Limits задаёт ограничения на ресурсы одной команды.
+//
+// Нулевое значение поля означает отсутствие ограничения.
+type Limits struct {
+	// CPUs ограничивает процессорное время, например 1.5 - полтора ядра.
+	CPUs float64
+
+	// Memory ограничивает память в байтах.
+	Memory int64
+
+	// Pids ограничивает число процессов и потоков.
+	Pids int64
+
+	// Timeout ограничивает время работы команды.
+	Timeout time.Duration
+}
+
+type Config struct {
+	// ReadOnlyPaths задаёт директории хоста, доступные внутри песочницы на чтение.
+	//
+	// Если поле не задано, используется DefaultReadOnlyPaths.
+	ReadOnlyPaths []string
+
+	// CgroupRoot задаёт директорию в иерархии cgroup v2, внутри которой создаются
+	// cgroup команд. Если поле не задано, используется cgroup текущего процесса.
+	CgroupRoot string
+
+	Limits Limits
+}
+
+// Mount описывает директорию, которая будет видна внутри песочницы по тому же пути.
+type Mount struct {
+	Path     string
+	Writable bool
+}
+
+// LimitError сообщает, что команда упёрлась в ограничение песочницы.
+type LimitError struct {
+	Resource string
+	Limit    string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("sandbox: %s limit exceeded (limit %s)", e.Resource, e.Limit)
+}
+
+// Writable проверяет, что path находится внутри одной из директорий, доступных на запись.
+func Writable(mounts []Mount, path string) bool {
+	for _, m := range mounts {
+		if m.Writable && inside(m.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Readable проверяет, что path находится внутри одной из директорий mounts.
+func Readable(mounts []Mount, path string) bool {
+	for _, m := range mounts {
+		if inside(m.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func inside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,419 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+)
+
+const (
+	// initArg подменяет argv[0] процесса, который настраивает песочницу
+	// и затем выполняет exec команды.
+	initArg = "distbuild-sandbox-init"
+
+	initConfigEnv = "DISTBUILD_SANDBOX_CONFIG"
+)
+
+// initConfig передаётся из воркера в init процесс песочницы.
+type initConfig struct {
+	Root   string
+	Mounts []Mount
+	Path   string
+	Args   []string
+	Env    []string
+	Dir    string
+
+	// Trace просит init процесс вызвать PTRACE_TRACEME перед exec команды.
+	Trace bool
+}
+
+func init() {
+	if len(os.Args) != 0 && os.Args[0] == initArg {
+		runInit()
+	}
+}
+
+type Sandbox struct {
+	config     Config
+	cgroupRoot string
+}
+
+func New(config Config) (*Sandbox, error) {
+	if config.ReadOnlyPaths == nil {
+		config.ReadOnlyPaths = DefaultReadOnlyPaths
+	}
+
+	s := &Sandbox{config: config}
+
+	if controllers := config.Limits.controllers(); len(controllers) != 0 {
+		s.cgroupRoot = config.CgroupRoot
+		if s.cgroupRoot == "" {
+			root, err := findCgroupRoot()
+			if err != nil {
+				return nil, err
+			}
+			s.cgroupRoot = root
+		}
+
+		if err := enableControllers(s.cgroupRoot, controllers); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Sandbox) mounts(jobMounts []Mount) []Mount {
+	var mounts []Mount
+	for _, path := range s.config.ReadOnlyPaths {
+		if _, err := os.Stat(path); err == nil {
+			mounts = append(mounts, Mount{Path: path})
+		}
+	}
+	mounts = append(mounts, jobMounts...)
+
+	sort.SliceStable(mounts, func(i, j int) bool {
+		return strings.Count(mounts[i].Path, "/") < strings.Count(mounts[j].Path, "/")
+	})
+	return mounts
+}
+
+// Run выполняет команду cmd внутри песочницы и возвращает её код возврата.
+//
+// Внутри песочницы видны только системные директории из Config.ReadOnlyPaths и mounts.
+// Сеть недоступна. Если команда упёрлась в ограничение, возвращается *LimitError.
+func (s *Sandbox) Run(ctx context.Context, cmd *build.Cmd, mounts []Mount, stdout, stderr io.Writer) (int, error) {
+	code, _, err := s.run(ctx, cmd, mounts, stdout, stderr, false)
+	return code, err
+}
+
+// RunTraced работает как Run, и дополнительно возвращает все обращения команды к файлам.
+func (s *Sandbox) RunTraced(ctx context.Context, cmd *build.Cmd, mounts []Mount, stdout, stderr io.Writer) (int, []hermetic.Access, error) {
+	return s.run(ctx, cmd, mounts, stdout, stderr, true)
+}
+
+func (s *Sandbox) run(ctx context.Context, cmd *build.Cmd, mounts []Mount, stdout, stderr io.Writer, trace bool) (int, []hermetic.Access, error) {
+	if len(cmd.Exec) == 0 {
+		return 0, nil, nil
+	}
+
+	path, err := exec.LookPath(cmd.Exec[0])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if path, err = filepath.Abs(path); err != nil {
+		return 0, nil, err
+	}
+
+	root, err := os.MkdirTemp("", "distbuild-sandbox-")
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = os.Remove(root) }()
+
+	config, err := json.Marshal(&initConfig{
+		Root:   root,
+		Mounts: s.mounts(mounts),
+		Path:   path,
+		Args:   cmd.Exec,
+		Env:    append([]string{}, cmd.Environ...),
+		Dir:    cmd.WorkingDirectory,
+		Trace:  trace,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if s.config.Limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Limits.Timeout)
+		defer cancel()
+	}
+
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = errR.Close() }()
+	defer func() { _ = errW.Close() }()
+
+	// Trace сам следит за отменой контекста и не вызывает c.Wait.
+	c := exec.Command("/proc/self/exe")
+	if !trace {
+		c = exec.CommandContext(ctx, "/proc/self/exe")
+	}
+
+	c.Args = []string{initArg}
+	c.Env = []string{initConfigEnv + "=" + string(config)}
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.ExtraFiles = []*os.File{errW}
+	c.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Pdeathsig: syscall.SIGKILL,
+	}
+
+	if os.Geteuid() != 0 {
+		c.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		c.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}}
+		c.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}}
+	}
+
+	var cg *cgroup
+	if s.cgroupRoot != "" {
+		cg, err = newCgroup(s.cgroupRoot, "job-"+hex.EncodeToString([]byte(filepath.Base(root))), s.config.Limits)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer func() { _ = cg.destroy() }()
+
+		cgroupDir, err := os.Open(cg.path)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer func() { _ = cgroupDir.Close() }()
+
+		c.SysProcAttr.UseCgroupFD = true
+		c.SysProcAttr.CgroupFD = int(cgroupDir.Fd())
+	}
+
+	var (
+		exitCode int
+		accesses []hermetic.Access
+	)
+
+	if trace {
+		exitCode, accesses, err = hermetic.Trace(ctx, c)
+	} else {
+		exitCode, err = startAndWait(c)
+	}
+
+	// Сообщение об ошибке настройки помещается в буфер пайпа, поэтому его можно
+	// прочитать уже после завершения процесса.
+	_ = errW.Close()
+	setupErr, _ := io.ReadAll(errR)
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("sandbox: %w", err)
+	}
+
+	if len(setupErr) != 0 {
+		return 0, nil, fmt.Errorf("sandbox setup: %s", setupErr)
+	}
+
+	if cg != nil {
+		if limitErr := cg.checkLimits(); limitErr != nil {
+			return exitCode, accesses, limitErr
+		}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && s.config.Limits.Timeout > 0 {
+		return exitCode, accesses, &LimitError{Resource: "time", Limit: s.config.Limits.Timeout.String()}
+	}
+
+	return exitCode, accesses, nil
+}
+
+func startAndWait(c *exec.Cmd) (int, error) {
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+
+	err := c.Wait()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+
+	return 0, err
+}
+
+// runInit выполняется внутри новых namespace-ов, собирает корневую файловую систему
+// песочницы и заменяет себя командой пользователя.
+func runInit() {
+	errPipe := os.NewFile(3, "sandbox-errors")
+
+	fail := func(err error) {
+		_, _ = fmt.Fprint(errPipe, err.Error())
+		os.Exit(127)
+	}
+
+	var config initConfig
+	if err := json.Unmarshal([]byte(os.Getenv(initConfigEnv)), &config); err != nil {
+		fail(err)
+	}
+
+	if err := setupRoot(&config); err != nil {
+		fail(err)
+	}
+
+	if config.Dir != "" {
+		if err := os.Chdir(config.Dir); err != nil {
+			fail(err)
+		}
+	}
+
+	if config.Trace {
+		if _, _, errno := unix.RawSyscall(unix.SYS_PTRACE, unix.PTRACE_TRACEME, 0, 0); errno != 0 {
+			fail(fmt.Errorf("ptrace: %w", errno))
+		}
+	}
+
+	syscall.CloseOnExec(int(errPipe.Fd()))
+	fail(syscall.Exec(config.Path, config.Args, config.Env))
+}
+
+var devices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+func setupRoot(config *initConfig) error {
+	root := config.Root
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make / private: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, dir := range []string{"proc", "dev", "tmp"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Mount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+
+	if err := unix.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+
+	if err := setupDev(filepath.Join(root, "dev")); err != nil {
+		return err
+	}
+
+	for _, m := range config.Mounts {
+		if err := bindMount(m, filepath.Join(root, m.Path)); err != nil {
+			return fmt.Errorf("mount %s: %w", m.Path, err)
+		}
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount old root: %w", err)
+	}
+
+	if err := os.Remove("/.oldroot"); err != nil {
+		return err
+	}
+
+	if err := unix.Mount("", "/", "", unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount / read-only: %w", err)
+	}
+
+	return unix.Sethostname([]byte("distbuild"))
+}
+
+func setupDev(dev string) error {
+	if err := unix.Mount("tmpfs", dev, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=755"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+
+	for _, name := range devices {
+		if _, err := os.Stat(filepath.Join("/dev", name)); err != nil {
+			continue
+		}
+
+		if err := bindMount(Mount{Path: filepath.Join("/dev", name), Writable: true}, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("mount /dev/%s: %w", name, err)
+		}
+	}
+
+	links := map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	}
+
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func bindMount(m Mount, target string) error {
+	st, err := os.Stat(m.Path)
+	if err != nil {
+		return err
+	}
+
+	if st.IsDir() {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		_ = f.Close()
+	}
+
+	if err := unix.Mount(m.Path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return err
+	}
+
+	if m.Writable {
+		return nil
+	}
+
+	var fs unix.Statfs_t
+	if err := unix.Statfs(m.Path, &fs); err != nil {
+		return err
+	}
+
+	// Флаги nosuid, nodev и noexec нельзя снять при перемонтировании внутри user namespace.
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	flags |= uintptr(fs.Flags) & (unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC)
+
+	return unix.Mount("", target, "", flags, "")
+}
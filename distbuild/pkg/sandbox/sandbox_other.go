@@ -0,0 +1,25 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"io"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+)
+
+type Sandbox struct{}
+
+func New(config Config) (*Sandbox, error) {
+	return nil, ErrNotSupported
+}
+
+func (s *Sandbox) Run(ctx context.Context, cmd *build.Cmd, mounts []Mount, stdout, stderr io.Writer) (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (s *Sandbox) RunTraced(ctx context.Context, cmd *build.Cmd, mounts []Mount, stdout, stderr io.Writer) (int, []hermetic.Access, error) {
+	return 0, nil, ErrNotSupported
+}
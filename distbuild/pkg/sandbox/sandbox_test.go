@@ -0,0 +1,144 @@
+//go:build linux
+
+package sandbox_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+)
+
+type env struct {
+	s *sandbox.Sandbox
+
+	sourceDir string
+	outputDir string
+	secretDir string
+}
+
+func newEnv(t *testing.T, config sandbox.Config) *env {
+	s, err := sandbox.New(config)
+	if err != nil {
+		t.Skipf("sandbox is not available: %v", err)
+	}
+
+	_, err = s.Run(context.Background(), &build.Cmd{Exec: []string{"true"}}, nil, nil, nil)
+	if err != nil {
+		t.Skipf("sandbox is not available: %v", err)
+	}
+
+	root := t.TempDir()
+	e := &env{
+		s:         s,
+		sourceDir: filepath.Join(root, "src"),
+		outputDir: filepath.Join(root, "out"),
+		secretDir: filepath.Join(root, "secret"),
+	}
+
+	for _, dir := range []string{e.sourceDir, e.outputDir, e.secretDir} {
+		require.NoError(t, os.Mkdir(dir, 0777))
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(e.sourceDir, "a.txt"), []byte("foo"), 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(e.secretDir, "b.txt"), []byte("bar"), 0666))
+	return e
+}
+
+func (e *env) run(t *testing.T, script string) (int, string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := &build.Cmd{Exec: []string{"bash", "-c", script}}
+	mounts := []sandbox.Mount{
+		{Path: e.sourceDir},
+		{Path: e.outputDir, Writable: true},
+	}
+
+	code, err := e.s.Run(context.Background(), cmd, mounts, &stdout, &stderr)
+	return code, stdout.String(), stderr.String(), err
+}
+
+func TestSandbox_Filesystem(t *testing.T) {
+	e := newEnv(t, sandbox.Config{})
+
+	code, stdout, _, err := e.run(t, "cat "+e.sourceDir+"/a.txt > "+e.outputDir+"/a.txt && cat "+e.sourceDir+"/a.txt")
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	require.Equal(t, "foo", stdout)
+
+	content, err := os.ReadFile(filepath.Join(e.outputDir, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), content)
+
+	code, _, _, err = e.run(t, "cat "+e.secretDir+"/b.txt")
+	require.NoError(t, err)
+	require.NotEqual(t, 0, code)
+
+	code, _, _, err = e.run(t, "echo bar > "+e.sourceDir+"/a.txt")
+	require.NoError(t, err)
+	require.NotEqual(t, 0, code)
+}
+
+func TestSandbox_Network(t *testing.T) {
+	e := newEnv(t, sandbox.Config{})
+
+	code, stdout, _, err := e.run(t, "tail -n +3 /proc/net/dev")
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+
+	ifaces := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, ifaces, 1)
+	require.Contains(t, ifaces[0], "lo:")
+}
+
+func TestSandbox_Timeout(t *testing.T) {
+	e := newEnv(t, sandbox.Config{Limits: sandbox.Limits{Timeout: 100 * time.Millisecond}})
+
+	_, _, _, err := e.run(t, "sleep 10")
+
+	var limitErr *sandbox.LimitError
+	require.True(t, errors.As(err, &limitErr), "%v", err)
+	require.Equal(t, "time", limitErr.Resource)
+}
+
+func TestSandbox_MemoryLimit(t *testing.T) {
+	e := newEnv(t, sandbox.Config{Limits: sandbox.Limits{Memory: 32 << 20}})
+
+	_, _, _, err := e.run(t, "head -c 128M /dev/zero | tail > /dev/null")
+
+	var limitErr *sandbox.LimitError
+	require.True(t, errors.As(err, &limitErr), "%v", err)
+	require.Equal(t, "memory", limitErr.Resource)
+}
+
+func TestSandbox_RunTraced(t *testing.T) {
+	e := newEnv(t, sandbox.Config{})
+
+	var stdout bytes.Buffer
+	cmd := &build.Cmd{Exec: []string{"bash", "-c", "cat " + e.sourceDir + "/a.txt > " + e.outputDir + "/a.txt; cat " + e.sourceDir + "/a.txt"}}
+	mounts := []sandbox.Mount{
+		{Path: e.sourceDir},
+		{Path: e.outputDir, Writable: true},
+	}
+
+	code, accesses, err := e.s.RunTraced(context.Background(), cmd, mounts, &stdout, nil)
+	if errors.Is(err, hermetic.ErrNotSupported) {
+		t.Skip("tracing is not supported")
+	}
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	require.Equal(t, "foo", stdout.String())
+
+	require.Contains(t, accesses, hermetic.Access{Path: filepath.Join(e.sourceDir, "a.txt")})
+	require.Contains(t, accesses, hermetic.Access{Path: filepath.Join(e.outputDir, "a.txt"), Write: true})
+}
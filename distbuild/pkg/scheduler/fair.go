@@ -0,0 +1,93 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Usage описывает ресурсы, которые получила сборка.
+type Usage struct {
+	// Jobs - число джобов сборки, выданных воркерам.
+	Jobs int
+	// MilliCPU - сумма Requests().MilliCPU этих джобов.
+	MilliCPU int64
+}
+
+// buildState хранит состояние сборки для weighted fair queuing.
+//
+// Каждая сборка копит виртуальное время: ресурсы выданных ей джобов, делённые на её вес.
+// Воркер получает джоб сборки с наименьшим виртуальным временем, поэтому сборки
+// получают ресурсы пропорционально весам, и ни одна сборка не голодает.
+type buildState struct {
+	id     build.ID
+	weight int
+	vtime  float64
+	usage  Usage
+
+	// queued - число джобов сборки, которые ещё не забрал воркер.
+	queued int
+}
+
+func (c *Scheduler) buildState(buildID build.ID) *buildState {
+	b, ok := c.builds[buildID]
+	if !ok {
+		b = &buildState{id: buildID, weight: 1, vtime: c.vclock}
+		c.builds[buildID] = b
+	}
+	return b
+}
+
+// RegisterBuild регистрирует сборку с весом weight. Сборка с весом 2 получает вдвое больше
+// ресурсов, чем сборка с весом 1, когда обеим есть что выполнять.
+func (c *Scheduler) RegisterBuild(buildID build.ID, weight int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.buildState(buildID).weight = max(weight, 1)
+}
+
+// UnregisterBuild забывает сборку и возвращает ресурсы, которые она получила.
+//
+// Запланированные джобы сборки остаются в очередях, пока их не отменят через CancelJob.
+func (c *Scheduler) UnregisterBuild(buildID build.ID) Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.builds[buildID]
+	if !ok {
+		return Usage{}
+	}
+
+	delete(c.builds, buildID)
+	c.l.Debug("build unregistered",
+		zap.String("build_id", buildID.String()),
+		zap.Int("jobs", b.usage.Jobs),
+		zap.Int64("milli_cpu", b.usage.MilliCPU))
+
+	return b.usage
+}
+
+// BuildUsage возвращает ресурсы, которые сборка получила на текущий момент.
+func (c *Scheduler) BuildUsage(buildID build.ID) Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if b, ok := c.builds[buildID]; ok {
+		return b.usage
+	}
+	return Usage{}
+}
+
+// charge засчитывает выданный воркеру джоб его сборке.
+func (c *Scheduler) charge(pendingJob *PendingJob) {
+	b := pendingJob.build
+	cost := pendingJob.Job.Requests().MilliCPU
+
+	c.vclock = max(c.vclock, b.vtime)
+	b.vtime += float64(cost) / float64(b.weight)
+	b.usage.Jobs++
+	b.usage.MilliCPU += cost
+}
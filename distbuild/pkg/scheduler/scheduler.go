@@ -3,7 +3,12 @@
 package scheduler
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,36 +21,703 @@ type PendingJob struct {
 	Job      *api.JobSpec
 	Finished chan struct{}
 	Result   *api.JobResult
+
+	// PickedBy и PickedAt заполняются, когда джоб забирает воркер. Их можно читать после закрытия Finished.
+	//
+	// Если джоб выполняли два воркера (см. Config.SpeculativeFactor), после закрытия Finished
+	// PickedBy - воркер, чей результат записан в Result.
+	PickedBy api.WorkerID
+	PickedAt time.Time
+
+	// backupBy и backupAt заполняются, когда копию отстающего джоба забрал второй воркер.
+	backupBy api.WorkerID
+	backupAt time.Time
+	// speculated равен true, если копия джоба уже попала в очередь отстающих.
+	speculated bool
+
+	// pickedUp закрывается, когда джоб забрал воркер, джоб завершился или был отменён.
+	pickedUp chan struct{}
+	picked   bool
+
+	scheduled time.Time
+
+	// waiters считает вызовы ScheduleJob, которые ещё не отменены через CancelJob.
+	waiters int
+	// build - сборка, которая первой запланировала джоб. Ей засчитываются ресурсы джоба.
+	build *buildState
 }
 
 type Config struct {
 	CacheTimeout time.Duration
 	DepsTimeout  time.Duration
+
+	// WorkerTimeout задаёт, сколько воркер может не присылать heartbeat-ы, прежде чем
+	// шедулер сочтёт его потерянным. Если WorkerTimeout == 0, воркеры не теряются.
+	WorkerTimeout time.Duration
+
+	// SpeculativeFactor включает повторный запуск отстающих джобов. Если джоб выполняется
+	// в SpeculativeFactor раз дольше, чем обычно выполняются джобы с тем же именем, но не меньше
+	// SpeculativeMinDelay, шедулер отдаёт его копию другому воркеру. Если SpeculativeFactor == 0,
+	// копии не запускаются.
+	SpeculativeFactor   float64
+	SpeculativeMinDelay time.Duration
+
+	// OnWorkerLost, если задан, вызывается после того, как шедулер потерял воркера и забыл
+	// его артефакты. Шедулер вызывает его без своих блокировок.
+	OnWorkerLost func(workerID api.WorkerID)
+}
+
+// workerQueues хранит две локальные очереди воркера.
+type workerQueues struct {
+	// cache содержит джобы, результаты которых уже есть в кеше воркера.
+	cache []*PendingJob
+	// deps содержит джобы, часть зависимостей которых есть в кеше воркера.
+	deps []*PendingJob
 }
 
 type Scheduler struct {
+	l         *zap.Logger
+	config    Config
+	timeAfter func(d time.Duration) <-chan time.Time
+
+	mu          sync.Mutex
+	cachedJobs  map[build.ID]map[api.WorkerID]struct{}
+	pendingJobs map[build.ID]*PendingJob
+	globalQueue []*PendingJob
+	localQueues map[api.WorkerID]*workerQueues
+	cancelled   map[api.WorkerID][]build.ID
+
+	// stragglers - очередь копий отстающих джобов. Из неё берут только воркеры, которым
+	// больше нечего выполнять.
+	stragglers []*PendingJob
+	// durations хранит типичное время выполнения джобов по их имени.
+	durations map[string]time.Duration
+
+	// alive получает сигнал на каждый heartbeat воркера.
+	alive map[api.WorkerID]chan struct{}
+
+	builds map[build.ID]*buildState
+	// vclock - виртуальное время системы: виртуальное время сборки последнего выданного джоба.
+	vclock float64
+
+	// wakeup закрывается и пересоздаётся при каждом добавлении джоба в очередь.
+	wakeup chan struct{}
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 func NewScheduler(l *zap.Logger, config Config, timeAfter func(d time.Duration) <-chan time.Time) *Scheduler {
-	panic("implement me")
+	return &Scheduler{
+		l:         l,
+		config:    config,
+		timeAfter: timeAfter,
+
+		cachedJobs:  make(map[build.ID]map[api.WorkerID]struct{}),
+		pendingJobs: make(map[build.ID]*PendingJob),
+		localQueues: make(map[api.WorkerID]*workerQueues),
+		cancelled:   make(map[api.WorkerID][]build.ID),
+		durations:   make(map[string]time.Duration),
+		alive:       make(map[api.WorkerID]chan struct{}),
+		builds:      make(map[build.ID]*buildState),
+
+		wakeup: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+// RegisterWorker отмечает, что воркер жив. Его нужно вызывать на каждый heartbeat.
+func (c *Scheduler) RegisterWorker(workerID api.WorkerID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workerQueues(workerID)
+
+	if c.config.WorkerTimeout == 0 {
+		return
+	}
+
+	alive, ok := c.alive[workerID]
+	if !ok {
+		alive = make(chan struct{}, 1)
+		c.alive[workerID] = alive
+		go c.watchWorker(workerID, alive)
+		return
+	}
+
+	select {
+	case alive <- struct{}{}:
+	default:
+	}
+}
+
+// watchWorker теряет воркера, если от него нет heartbeat-ов дольше WorkerTimeout.
+func (c *Scheduler) watchWorker(workerID api.WorkerID, alive chan struct{}) {
+	for {
+		select {
+		case <-alive:
+		case <-c.timeAfter(c.config.WorkerTimeout):
+			if c.loseWorker(workerID, alive) && c.config.OnWorkerLost != nil {
+				c.config.OnWorkerLost(workerID)
+			}
+			return
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// loseWorker забывает артефакты и очереди воркера и завершает его джобы с WorkerLost.
+// loseWorker возвращает false, если воркер уже потерян.
+func (c *Scheduler) loseWorker(workerID api.WorkerID, alive chan struct{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.alive[workerID] != alive {
+		return false
+	}
+
+	c.l.Warn("worker lost", zap.String("worker_id", workerID.String()))
+
+	delete(c.alive, workerID)
+	delete(c.localQueues, workerID)
+	delete(c.cancelled, workerID)
+
+	for id, workers := range c.cachedJobs {
+		delete(workers, workerID)
+		if len(workers) == 0 {
+			delete(c.cachedJobs, id)
+		}
+	}
+
+	for _, pendingJob := range c.pendingJobs {
+		if !pendingJob.picked {
+			continue
+		}
+
+		switch workerID {
+		case pendingJob.backupBy:
+			pendingJob.backupBy = ""
+		case pendingJob.PickedBy:
+			c.loseRunner(pendingJob, fmt.Sprintf("worker %s lost", workerID))
+		}
+	}
+	return true
+}
+
+// SyncRunningJobs сверяет джобы, которые шедулер отдал воркеру, со списком из heartbeat-а.
+//
+// Джоб, который воркер не выполняет и не завершил, потерялся по дороге, например вместе
+// с ответом на heartbeat. Такой джоб завершается с WorkerLost. SyncRunningJobs нужно вызывать
+// после OnJobComplete для всех завершённых джобов из heartbeat-а.
+//
+// Ещё не забранный джоб, который воркер уже выполняет, например с момента до перезапуска
+// координатора, считается забранным этим воркером.
+func (c *Scheduler) SyncRunningJobs(workerID api.WorkerID, running []build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pendingJob := range c.pendingJobs {
+		if !pendingJob.picked && slices.Contains(running, pendingJob.Job.ID) {
+			c.charge(pendingJob)
+			pendingJob.PickedBy = workerID
+			pendingJob.PickedAt = time.Now()
+			c.markPicked(pendingJob)
+			c.speculate(pendingJob)
+			continue
+		}
+
+		if !pendingJob.picked || slices.Contains(running, pendingJob.Job.ID) {
+			continue
+		}
+
+		switch workerID {
+		case pendingJob.backupBy:
+			pendingJob.backupBy = ""
+		case pendingJob.PickedBy:
+			c.loseRunner(pendingJob, fmt.Sprintf("job is not running on worker %s", workerID))
+		}
+	}
+}
+
+// loseRunner обрабатывает джоб, который потерял забравший его воркер. Если джоб выполняет
+// копия на другом воркере, джоб продолжает ждать её результата, иначе завершается с WorkerLost.
+func (c *Scheduler) loseRunner(pendingJob *PendingJob, reason string) {
+	if pendingJob.backupBy == "" {
+		c.failLost(pendingJob, reason)
+		return
+	}
+
+	c.l.Warn("job lost, waiting for speculative copy",
+		zap.String("job_id", pendingJob.Job.ID.String()),
+		zap.String("worker_id", pendingJob.PickedBy.String()),
+		zap.String("backup_worker_id", pendingJob.backupBy.String()),
+		zap.String("reason", reason))
+
+	pendingJob.PickedBy, pendingJob.PickedAt = pendingJob.backupBy, pendingJob.backupAt
+	pendingJob.backupBy = ""
+}
+
+func (c *Scheduler) failLost(pendingJob *PendingJob, reason string) {
+	c.l.Warn("job lost",
+		zap.String("job_id", pendingJob.Job.ID.String()),
+		zap.String("worker_id", pendingJob.PickedBy.String()),
+		zap.String("reason", reason))
+
+	delete(c.pendingJobs, pendingJob.Job.ID)
+	pendingJob.Result = &api.JobResult{ID: pendingJob.Job.ID, Error: &reason, WorkerLost: true}
+	close(pendingJob.Finished)
+}
+
+func (c *Scheduler) workerQueues(workerID api.WorkerID) *workerQueues {
+	q, ok := c.localQueues[workerID]
+	if !ok {
+		q = &workerQueues{}
+		c.localQueues[workerID] = q
+	}
+	return q
+}
+
+func (c *Scheduler) notify() {
+	close(c.wakeup)
+	c.wakeup = make(chan struct{})
+}
+
+// GlobalQueueLen возвращает число джобов в глобальной очереди, которые ещё не забрал воркер.
+func (c *Scheduler) GlobalQueueLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, pendingJob := range c.globalQueue {
+		if !pendingJob.picked {
+			n++
+		}
+	}
+	return n
+}
+
+// QueuedJobsLen возвращает число запланированных джобов, которые ещё не забрал воркер.
+func (c *Scheduler) QueuedJobsLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, pendingJob := range c.pendingJobs {
+		if !pendingJob.picked {
+			n++
+		}
+	}
+	return n
+}
+
+// QueuedJob описывает запланированный джоб, который ещё не забрал воркер.
+type QueuedJob struct {
+	ID   build.ID
+	Name string
+
+	// BuildID - сборка, которой засчитываются ресурсы джоба.
+	BuildID build.ID
+
+	// Global равен true, если джоб уже попал в глобальную очередь и его может забрать любой воркер.
+	Global bool
+
+	Scheduled time.Time
+}
+
+// QueuedJobs возвращает запланированные джобы, которые ещё не забрал воркер, в порядке планирования.
+func (c *Scheduler) QueuedJobs() []QueuedJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	global := make(map[*PendingJob]struct{}, len(c.globalQueue))
+	for _, pendingJob := range c.globalQueue {
+		global[pendingJob] = struct{}{}
+	}
+
+	var queued []QueuedJob
+	for _, pendingJob := range c.pendingJobs {
+		if pendingJob.picked {
+			continue
+		}
+
+		_, inGlobal := global[pendingJob]
+		queued = append(queued, QueuedJob{
+			ID:        pendingJob.Job.ID,
+			Name:      pendingJob.Job.Name,
+			BuildID:   pendingJob.build.id,
+			Global:    inGlobal,
+			Scheduled: pendingJob.scheduled,
+		})
+	}
+
+	slices.SortFunc(queued, func(a, b QueuedJob) int {
+		if d := a.Scheduled.Compare(b.Scheduled); d != 0 {
+			return d
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+	return queued
+}
+
+// WorkersLen возвращает число зарегистрированных воркеров, которых шедулер не считает потерянными.
+func (c *Scheduler) WorkersLen() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.localQueues)
 }
 
 func (c *Scheduler) LocateArtifact(id build.ID) (api.WorkerID, bool) {
-	panic("implement me")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for workerID := range c.cachedJobs[id] {
+		return workerID, true
+	}
+
+	return "", false
 }
 
+// JobRunners возвращает воркеров, которые выполняют незавершённый джоб jobID: воркера, который
+// забрал джоб, и воркера, который выполняет копию отстающего джоба.
+func (c *Scheduler) JobRunners(jobID build.ID) []api.WorkerID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pendingJob, ok := c.pendingJobs[jobID]
+	if !ok || !pendingJob.picked || pendingJob.PickedBy == "" {
+		return nil
+	}
+
+	runners := []api.WorkerID{pendingJob.PickedBy}
+	if pendingJob.backupBy != "" {
+		runners = append(runners, pendingJob.backupBy)
+	}
+	return runners
+}
+
+// OnJobComplete завершает джоб jobID результатом res от воркера workerID.
+//
+// Расположение артефакта запоминается только для успешного джоба: неудачный джоб воркер
+// не сохраняет в кеш, и LocateArtifact не должен на него указывать.
 func (c *Scheduler) OnJobComplete(workerID api.WorkerID, jobID build.ID, res *api.JobResult) bool {
-	panic("implement me")
+	c.mu.Lock()
+
+	c.workerQueues(workerID)
+	if res.Error == nil && res.ExitCode == 0 {
+		if c.cachedJobs[jobID] == nil {
+			c.cachedJobs[jobID] = make(map[api.WorkerID]struct{})
+		}
+		c.cachedJobs[jobID][workerID] = struct{}{}
+	}
+	c.cancelled[workerID] = slices.DeleteFunc(c.cancelled[workerID], func(id build.ID) bool {
+		return id == jobID
+	})
+
+	pendingJob, ok := c.pendingJobs[jobID]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+
+	delete(c.pendingJobs, jobID)
+	c.markPicked(pendingJob)
+	c.stopRunners(pendingJob, workerID)
+	if res.Error == nil && res.ExitCode == 0 && pendingJob.PickedBy == workerID {
+		c.observe(pendingJob)
+	}
+	c.mu.Unlock()
+
+	c.l.Debug("job completed",
+		zap.String("job_id", jobID.String()),
+		zap.String("worker_id", workerID.String()))
+
+	pendingJob.Result = res
+	close(pendingJob.Finished)
+	return true
+}
+
+// AddArtifact сообщает, что артефакт id есть в кеше воркера, например после перезапуска координатора.
+func (c *Scheduler) AddArtifact(workerID api.WorkerID, id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cachedJobs[id] == nil {
+		c.cachedJobs[id] = make(map[api.WorkerID]struct{})
+	}
+	c.cachedJobs[id][workerID] = struct{}{}
+}
+
+// OnArtifactEvicted сообщает, что артефакта больше нет в кеше воркера.
+func (c *Scheduler) OnArtifactEvicted(workerID api.WorkerID, id build.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.cachedJobs[id], workerID)
+	if len(c.cachedJobs[id]) == 0 {
+		delete(c.cachedJobs, id)
+	}
 }
 
+func (c *Scheduler) markPicked(pendingJob *PendingJob) {
+	if !pendingJob.picked {
+		pendingJob.picked = true
+		pendingJob.build.queued--
+		close(pendingJob.pickedUp)
+	}
+}
+
+func (c *Scheduler) enqueueDepsLocal(pendingJob *PendingJob) {
+	workers := map[api.WorkerID]struct{}{}
+	for _, dep := range pendingJob.Job.Deps {
+		for workerID := range c.cachedJobs[dep] {
+			workers[workerID] = struct{}{}
+		}
+	}
+
+	for workerID := range workers {
+		q := c.workerQueues(workerID)
+		q.deps = append(q.deps, pendingJob)
+	}
+
+	if len(workers) != 0 {
+		c.notify()
+	}
+}
+
+// ScheduleJob планирует джоб вне какой-либо сборки. Такие джобы делят ресурсы со сборками
+// как одна сборка с весом 1.
 func (c *Scheduler) ScheduleJob(job *api.JobSpec) *PendingJob {
-	panic("implement me")
+	return c.ScheduleBuildJob(build.ID{}, job)
+}
+
+// ScheduleBuildJob планирует джоб сборки buildID.
+//
+// Если джоб с таким ID уже запланирован другой сборкой, возвращается тот же PendingJob,
+// а его ресурсы продолжают засчитываться первой сборке.
+func (c *Scheduler) ScheduleBuildJob(buildID build.ID, job *api.JobSpec) *PendingJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pendingJob, ok := c.pendingJobs[job.ID]; ok {
+		pendingJob.waiters++
+		return pendingJob
+	}
+
+	b := c.buildState(buildID)
+	if b.queued == 0 {
+		// Простаивавшая сборка не должна получить преимущество за время простоя.
+		b.vtime = max(b.vtime, c.vclock)
+	}
+	b.queued++
+
+	pendingJob := &PendingJob{
+		Job:      job,
+		Finished: make(chan struct{}),
+		pickedUp: make(chan struct{}),
+		waiters:  1,
+		build:    b,
+
+		scheduled: time.Now(),
+	}
+	c.pendingJobs[job.ID] = pendingJob
+
+	cached := len(c.cachedJobs[job.ID]) != 0
+	for workerID := range c.cachedJobs[job.ID] {
+		q := c.workerQueues(workerID)
+		q.cache = append(q.cache, pendingJob)
+	}
+
+	if cached {
+		c.notify()
+	} else {
+		c.enqueueDepsLocal(pendingJob)
+	}
+
+	c.l.Debug("job scheduled", zap.String("job_id", job.ID.String()), zap.Bool("cached", cached))
+
+	go c.waitTimeouts(pendingJob, cached)
+	return pendingJob
+}
+
+// waitTimeouts перекладывает джоб во вторые локальные очереди после CacheTimeout,
+// и в глобальную очередь после DepsTimeout.
+func (c *Scheduler) waitTimeouts(pendingJob *PendingJob, cached bool) {
+	if cached {
+		select {
+		case <-c.timeAfter(c.config.CacheTimeout):
+		case <-pendingJob.pickedUp:
+			return
+		case <-c.stop:
+			return
+		}
+
+		c.mu.Lock()
+		if !pendingJob.picked {
+			c.enqueueDepsLocal(pendingJob)
+		}
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-c.timeAfter(c.config.DepsTimeout):
+	case <-pendingJob.pickedUp:
+		return
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !pendingJob.picked {
+		c.globalQueue = append(c.globalQueue, pendingJob)
+		c.notify()
+	}
+}
+
+// popQueue удаляет из головы очереди уже взятые джобы.
+func popQueue(q *[]*PendingJob) {
+	for len(*q) != 0 && (*q)[0].picked {
+		*q = (*q)[1:]
+	}
+}
+
+// fairFit возвращает индекс джоба очереди, который помещается в ресурсы воркера и принадлежит
+// сборке с наименьшим виртуальным временем. При равенстве выбирается более ранний джоб.
+func fairFit(q []*PendingJob, resources api.WorkerResources) int {
+	best := -1
+	for i, pendingJob := range q {
+		if pendingJob.picked || !resources.Fits(pendingJob.Job.Requests()) {
+			continue
+		}
+
+		if best == -1 || pendingJob.build.vtime < q[best].build.vtime {
+			best = i
+		}
+	}
+	return best
+}
+
+func (c *Scheduler) tryPick(workerID api.WorkerID, resources api.WorkerResources) *PendingJob {
+	local := c.workerQueues(workerID)
+
+	type candidate struct {
+		q *[]*PendingJob
+		i int
+	}
+
+	// Из каждой очереди берётся джоб самой обделённой сборки. Если таких очередей
+	// несколько, очередь выбирается случайно.
+	var candidates []candidate
+	for _, q := range []*[]*PendingJob{&c.globalQueue, &local.cache, &local.deps} {
+		popQueue(q)
+
+		i := fairFit(*q, resources)
+		if i == -1 {
+			continue
+		}
+
+		vtime := (*q)[i].build.vtime
+		switch {
+		case len(candidates) == 0 || vtime < (*candidates[0].q)[candidates[0].i].build.vtime:
+			candidates = []candidate{{q: q, i: i}}
+		case vtime == (*candidates[0].q)[candidates[0].i].build.vtime:
+			candidates = append(candidates, candidate{q: q, i: i})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return c.pickStraggler(workerID, resources)
+	}
+
+	picked := candidates[rand.Intn(len(candidates))]
+	pendingJob := (*picked.q)[picked.i]
+	*picked.q = slices.Delete(*picked.q, picked.i, picked.i+1)
+
+	c.charge(pendingJob)
+
+	pendingJob.PickedBy = workerID
+	pendingJob.PickedAt = time.Now()
+	c.markPicked(pendingJob)
+	c.speculate(pendingJob)
+	return pendingJob
 }
 
+// CancelJob сообщает, что результат джоба, полученного из ScheduleJob, больше не нужен.
+//
+// Когда джоб отменили все, кто его ждал, он удаляется из очередей. Если джоб уже выполняется,
+// его ID вернёт CancelledJobs для воркера, который его забрал.
+func (c *Scheduler) CancelJob(pendingJob *PendingJob) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pendingJob.waiters--
+	if pendingJob.waiters > 0 || c.pendingJobs[pendingJob.Job.ID] != pendingJob {
+		return
+	}
+
+	delete(c.pendingJobs, pendingJob.Job.ID)
+	if pendingJob.picked {
+		c.cancelled[pendingJob.PickedBy] = append(c.cancelled[pendingJob.PickedBy], pendingJob.Job.ID)
+		if pendingJob.backupBy != "" {
+			c.cancelled[pendingJob.backupBy] = append(c.cancelled[pendingJob.backupBy], pendingJob.Job.ID)
+		}
+	} else {
+		c.markPicked(pendingJob)
+	}
+
+	c.l.Debug("job cancelled",
+		zap.String("job_id", pendingJob.Job.ID.String()),
+		zap.String("worker_id", pendingJob.PickedBy.String()))
+}
+
+// CancelledJobs возвращает джобы, которые воркер должен остановить, и забывает про них.
+func (c *Scheduler) CancelledJobs(workerID api.WorkerID) []build.ID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	jobs := c.cancelled[workerID]
+	delete(c.cancelled, workerID)
+	return jobs
+}
+
+// PickJob ждёт джоб для воркера, не учитывая ресурсы.
 func (c *Scheduler) PickJob(ctx context.Context, workerID api.WorkerID) *PendingJob {
-	panic("implement me")
+	return c.PickJobFor(ctx, workerID, api.WorkerResources{})
+}
+
+// PickJobFor ждёт джоб для воркера, который помещается в его ресурсы.
+//
+// Из каждой очереди воркеру достаётся первый подходящий по ресурсам джоб, поэтому
+// большой джоб в голове очереди не мешает запускать маленькие.
+func (c *Scheduler) PickJobFor(ctx context.Context, workerID api.WorkerID, resources api.WorkerResources) *PendingJob {
+	for {
+		c.mu.Lock()
+		pendingJob := c.tryPick(workerID, resources)
+		wakeup := c.wakeup
+		c.mu.Unlock()
+
+		if pendingJob != nil {
+			c.l.Debug("job picked",
+				zap.String("job_id", pendingJob.Job.ID.String()),
+				zap.String("worker_id", workerID.String()))
+			return pendingJob
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.stop:
+			return nil
+		case <-wakeup:
+		}
+	}
 }
 
 func (c *Scheduler) Stop() {
-	panic("implement me")
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
 }
@@ -0,0 +1,122 @@
+//go:build !solution
+
+package scheduler
+
+import (
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// speculate ждёт, пока забранный воркером джоб станет отстающим, и ставит его копию в очередь
+// отстающих. Если джобы с таким именем ещё не завершались, ожидать нечего и копия не запускается.
+//
+// Вызывается под c.mu.
+func (c *Scheduler) speculate(pendingJob *PendingJob) {
+	if c.config.SpeculativeFactor <= 0 || pendingJob.speculated {
+		return
+	}
+
+	expected, ok := c.durations[pendingJob.Job.Name]
+	if !ok {
+		return
+	}
+
+	delay := max(time.Duration(float64(expected)*c.config.SpeculativeFactor), c.config.SpeculativeMinDelay)
+	go c.waitStraggler(pendingJob, delay)
+}
+
+func (c *Scheduler) waitStraggler(pendingJob *PendingJob, delay time.Duration) {
+	select {
+	case <-c.timeAfter(delay):
+	case <-pendingJob.Finished:
+		return
+	case <-c.stop:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pendingJobs[pendingJob.Job.ID] != pendingJob || pendingJob.speculated {
+		return
+	}
+
+	c.l.Info("straggler job",
+		zap.String("job_id", pendingJob.Job.ID.String()),
+		zap.String("worker_id", pendingJob.PickedBy.String()),
+		zap.Duration("running", time.Since(pendingJob.PickedAt)))
+
+	pendingJob.speculated = true
+	c.stragglers = append(c.stragglers, pendingJob)
+	c.notify()
+}
+
+// pickStraggler отдаёт воркеру копию отстающего джоба, который выполняет другой воркер.
+func (c *Scheduler) pickStraggler(workerID api.WorkerID, resources api.WorkerResources) *PendingJob {
+	c.stragglers = slices.DeleteFunc(c.stragglers, func(pendingJob *PendingJob) bool {
+		return c.pendingJobs[pendingJob.Job.ID] != pendingJob
+	})
+
+	i := slices.IndexFunc(c.stragglers, func(pendingJob *PendingJob) bool {
+		return pendingJob.PickedBy != workerID && resources.Fits(pendingJob.Job.Requests())
+	})
+	if i == -1 {
+		return nil
+	}
+
+	pendingJob := c.stragglers[i]
+	c.stragglers = slices.Delete(c.stragglers, i, i+1)
+
+	c.charge(pendingJob)
+
+	pendingJob.backupBy = workerID
+	pendingJob.backupAt = time.Now()
+	return pendingJob
+}
+
+// stopRunners вызывается, когда workerID прислал первый результат джоба. Если джоб выполняют два
+// воркера, результат проигравшего не нужен: его джоб попадает в CancelledJobs, воркер убивает джоб
+// и не сохраняет его артефакт.
+func (c *Scheduler) stopRunners(pendingJob *PendingJob, workerID api.WorkerID) {
+	if pendingJob.backupBy == "" {
+		return
+	}
+
+	backupWon := workerID == pendingJob.backupBy
+	if backupWon {
+		pendingJob.PickedBy, pendingJob.backupBy = pendingJob.backupBy, pendingJob.PickedBy
+		pendingJob.PickedAt, pendingJob.backupAt = pendingJob.backupAt, pendingJob.PickedAt
+	}
+
+	for _, loser := range []api.WorkerID{pendingJob.PickedBy, pendingJob.backupBy} {
+		if loser != workerID {
+			c.cancelled[loser] = append(c.cancelled[loser], pendingJob.Job.ID)
+		}
+	}
+
+	c.l.Info("speculative job finished",
+		zap.String("job_id", pendingJob.Job.ID.String()),
+		zap.String("worker_id", workerID.String()),
+		zap.Bool("backup_won", backupWon))
+
+	pendingJob.backupBy = ""
+}
+
+// observe обновляет типичное время выполнения джобов с именем завершённого джоба. Типичное время -
+// экспоненциальное скользящее среднее, поэтому один медленный запуск не сдвигает его сильно.
+func (c *Scheduler) observe(pendingJob *PendingJob) {
+	name := pendingJob.Job.Name
+	if c.config.SpeculativeFactor <= 0 || name == "" || pendingJob.PickedAt.IsZero() {
+		return
+	}
+
+	d := time.Since(pendingJob.PickedAt)
+	if prev, ok := c.durations[name]; ok {
+		d = (3*prev + d) / 4
+	}
+	c.durations[name] = d
+}
@@ -0,0 +1,245 @@
+package tarstream
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Hash - sha256 содержимого файла.
+type Hash [sha256.Size]byte
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(b []byte) error {
+	raw, err := hex.DecodeString(string(b))
+	if err != nil {
+		return err
+	}
+	if len(raw) != len(h) {
+		return fmt.Errorf("invalid hash length %d", len(raw))
+	}
+	copy(h[:], raw)
+	return nil
+}
+
+// Entry описывает файл, директорию или символическую ссылку внутри передаваемой директории.
+type Entry struct {
+	// Path - путь относительно корня директории.
+	Path string
+	Mode os.FileMode
+
+	// Size и Hash заполнены только для обычных файлов.
+	Size int64 `json:",omitempty"`
+	Hash Hash
+
+	// Link - куда указывает символическая ссылка.
+	Link string `json:",omitempty"`
+}
+
+func (e *Entry) IsRegular() bool {
+	return e.Mode.IsRegular()
+}
+
+// Manifest обходит директорию и вычисляет хеши всех обычных файлов.
+func Manifest(dir string) ([]Entry, error) {
+	return walk(dir, true)
+}
+
+func walk(dir string, hash bool) ([]Entry, error) {
+	var entries []Entry
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if rel == "." {
+			return nil
+		}
+
+		e := Entry{Path: filepath.ToSlash(rel), Mode: info.Mode()}
+		switch {
+		case info.IsDir():
+		case isSymlink(info.Mode()):
+			if e.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			e.Size = info.Size()
+			if hash {
+				if e.Hash, err = hashFile(path); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("tarstream: unsupported file type %s: %s", info.Mode().Type(), path)
+		}
+
+		entries = append(entries, e)
+		return nil
+	})
+
+	return entries, err
+}
+
+func hashFile(path string) (Hash, error) {
+	var h Hash
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h, err
+	}
+	defer func() { _ = f.Close() }()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return h, err
+	}
+
+	copy(h[:], hasher.Sum(nil))
+	return h, nil
+}
+
+// paxDedup помечает файл, содержимое которого не передаётся: получатель сообщил, что у него
+// уже есть файл с таким хешом.
+const paxDedup = "DISTBUILD.dedup"
+
+// SendEntries сериализует entries из директории dir в поток w.
+//
+// Если have(e.Hash) возвращает true, содержимое файла не передаётся, а в потоке остаётся только
+// его заголовок с хешом. Такой поток можно прочитать только через ReceiveDedup.
+func SendEntries(dir string, entries []Entry, have func(Hash) bool, w io.Writer) error {
+	tw := tar.NewWriter(w)
+
+	for _, e := range entries {
+		h := &tar.Header{Name: e.Path, Mode: int64(e.Mode.Perm())}
+
+		switch {
+		case e.Mode.IsDir():
+			h.Typeflag = tar.TypeDir
+
+		case isSymlink(e.Mode):
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.Link
+
+		default:
+			h.Typeflag = tar.TypeReg
+			h.Size = e.Size
+
+			if have != nil && have(e.Hash) {
+				h.Size = 0
+				h.PAXRecords = map[string]string{paxDedup: e.Hash.String()}
+			}
+		}
+
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+
+		if h.Typeflag != tar.TypeReg || h.PAXRecords != nil {
+			continue
+		}
+
+		if err := copyFile(tw, filepath.Join(dir, filepath.FromSlash(e.Path)), e.Size); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func copyFile(w io.Writer, path string, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	n, err := io.Copy(w, io.LimitReader(f, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("tarstream: %s changed while sending", path)
+	}
+	return nil
+}
+
+// ReceiveDedup работает как Receive, но умеет читать файлы, содержимое которых не передавалось.
+// Такие файлы копируются из пути, который возвращает blob. Если blob == nil или файла с таким хешом
+// нет, ReceiveDedup возвращает ошибку.
+func ReceiveDedup(dir string, r io.Reader, blob func(Hash) (string, bool)) error {
+	tr := tar.NewReader(r)
+	recv := &receiver{dir: dir, symlinks: map[string]struct{}{}}
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = recv.mkdir(h)
+
+		case tar.TypeSymlink:
+			err = recv.symlink(h)
+
+		case tar.TypeReg:
+			if hash, ok := h.PAXRecords[paxDedup]; ok {
+				err = recv.copyBlob(h, hash, blob)
+			} else {
+				err = recv.writeFile(h, tr)
+			}
+
+		default:
+			err = fmt.Errorf("tarstream: unsupported entry type %q: %s", h.Typeflag, h.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *receiver) copyBlob(h *tar.Header, hash string, blob func(Hash) (string, bool)) error {
+	var id Hash
+	if err := id.UnmarshalText([]byte(hash)); err != nil {
+		return fmt.Errorf("tarstream: %s: %w", h.Name, err)
+	}
+
+	var src string
+	ok := false
+	if blob != nil {
+		src, ok = blob(id)
+	}
+	if !ok {
+		return fmt.Errorf("tarstream: %s: blob %s is missing", h.Name, id)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return r.writeFile(h, f)
+}
@@ -2,99 +2,100 @@ package tarstream
 
 import (
 	"archive/tar"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 )
 
 // Send рекурсивно обходит директорию и сериализует её содержимое в поток w.
+//
+// Символические ссылки передаются как ссылки, а не как файлы, на которые они указывают.
 func Send(dir string, w io.Writer) error {
-	tw := tar.NewWriter(w)
+	entries, err := walk(dir, false)
+	if err != nil {
+		return err
+	}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return SendEntries(dir, entries, nil, w)
+}
 
-		rel, err := filepath.Rel(dir, path)
-		if err != nil {
-			return err
-		}
+// Receive читает поток r и материализует содержимое потока внутри dir.
+func Receive(dir string, r io.Reader) error {
+	return ReceiveDedup(dir, r, nil)
+}
 
-		if rel == "." {
-			return nil
-		}
+// receiver проверяет, что записи потока не выходят за пределы dir.
+type receiver struct {
+	dir      string
+	symlinks map[string]struct{}
+}
+
+// path возвращает путь, по которому нужно создать запись name.
+//
+// Запись не может указывать за пределы dir или внутрь уже созданной символической ссылки,
+// иначе поток мог бы записать файл в произвольное место через ссылку.
+func (r *receiver) path(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("tarstream: invalid path %q", name)
+	}
 
-		switch {
-		case info.IsDir():
-			return tw.WriteHeader(&tar.Header{
-				Name:     rel,
-				Typeflag: tar.TypeDir,
-			})
-
-		default:
-			h := &tar.Header{
-				Typeflag: tar.TypeReg,
-				Name:     rel,
-				Size:     info.Size(),
-				Mode:     int64(info.Mode()),
-			}
-
-			if err := tw.WriteHeader(h); err != nil {
-				return err
-			}
-
-			f, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer func() { _ = f.Close() }()
-
-			_, err = io.Copy(tw, f)
-			return err
+	for prefix := filepath.Dir(name); prefix != "."; prefix = filepath.Dir(prefix) {
+		if _, ok := r.symlinks[prefix]; ok {
+			return "", fmt.Errorf("tarstream: path %q is inside symlink %q", name, prefix)
 		}
-	})
+	}
+
+	return filepath.Join(r.dir, name), nil
+}
 
+func (r *receiver) mkdir(h *tar.Header) error {
+	path, err := r.path(h.Name)
 	if err != nil {
 		return err
 	}
 
-	return tw.Close()
+	// Владелец сохраняет полный доступ к директории, иначе получатель не смог бы создать
+	// её содержимое, а потом перенести или удалить его. Старые отправители передавали директории без прав.
+	mode := os.FileMode(0777)
+	if h.Mode != 0 {
+		mode = os.FileMode(h.Mode).Perm() | 0700
+	}
+	return os.Mkdir(path, mode)
 }
 
-// Receive читает поток r и материализует содержимое потока внутри dir.
-func Receive(dir string, r io.Reader) error {
-	tr := tar.NewReader(r)
-
-	for {
-		h, err := tr.Next()
-		if err == io.EOF {
-			return nil
-		} else if err != nil {
-			return err
-		}
+func (r *receiver) symlink(h *tar.Header) error {
+	path, err := r.path(h.Name)
+	if err != nil {
+		return err
+	}
 
-		absPath := filepath.Join(dir, h.Name)
-
-		if h.Typeflag == tar.TypeDir {
-			if err := os.Mkdir(absPath, 0777); err != nil {
-				return err
-			}
-		} else {
-			writeFile := func() error {
-				f, err := os.OpenFile(absPath, os.O_CREATE|os.O_WRONLY, os.FileMode(h.Mode))
-				if err != nil {
-					return err
-				}
-				defer func() { _ = f.Close() }()
-
-				_, err = io.Copy(f, tr)
-				return err
-			}
-
-			if err := writeFile(); err != nil {
-				return err
-			}
-		}
+	if err := os.Symlink(h.Linkname, path); err != nil {
+		return err
+	}
+
+	r.symlinks[filepath.Clean(h.Name)] = struct{}{}
+	return nil
+}
+
+func (r *receiver) writeFile(h *tar.Header, content io.Reader) error {
+	path, err := r.path(h.Name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(h.Mode).Perm())
+	if err != nil {
+		return err
 	}
+
+	if _, err := io.Copy(f, content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func isSymlink(mode os.FileMode) bool {
+	return mode&os.ModeSymlink != 0
 }
@@ -1,7 +1,9 @@
 package tarstream_test
 
 import (
+	"archive/tar"
 	"bytes"
+	"crypto/sha256"
 	"os"
 	"path/filepath"
 	"testing"
@@ -30,19 +32,26 @@ func TestTarStream(t *testing.T) {
 	require.NoError(t, os.MkdirAll(filepath.Join(from, "b", "c", "d"), 0777))
 	require.NoError(t, os.WriteFile(filepath.Join(from, "a", "x.bin"), []byte("xxx"), 0777))
 	require.NoError(t, os.WriteFile(filepath.Join(from, "b", "c", "y.txt"), []byte("yyy"), 0666))
+	require.NoError(t, os.Chmod(filepath.Join(from, "b", "c"), 0711))
+	require.NoError(t, os.Chmod(filepath.Join(from, "b", "c", "d"), 0555))
 
 	require.NoError(t, tarstream.Send(from, &buf))
 
 	require.NoError(t, tarstream.Receive(to, &buf))
 
-	checkDir := func(path string) {
+	checkDir := func(path string, mode os.FileMode) {
+		t.Helper()
+
 		st, err := os.Stat(path)
 		require.NoError(t, err)
 		require.True(t, st.IsDir())
+		require.Equal(t, (os.ModeDir | mode).String(), st.Mode().String())
 	}
 
-	checkDir(filepath.Join(to, "a"))
-	checkDir(filepath.Join(to, "b", "c", "d"))
+	checkDir(filepath.Join(to, "a"), 0755)
+	checkDir(filepath.Join(to, "b", "c"), 0711)
+	// Владелец может писать в полученную директорию, даже если у отправителя не мог.
+	checkDir(filepath.Join(to, "b", "c", "d"), 0755)
 
 	checkFile := func(path string, content []byte, mode os.FileMode) {
 		t.Helper()
@@ -64,3 +73,88 @@ func TestTarStream(t *testing.T) {
 func init() {
 	unix.Umask(0022)
 }
+
+func TestTarStreamSymlink(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(from, "run.sh"), []byte("#!/bin/sh\n"), 0755))
+	require.NoError(t, os.Symlink("run.sh", filepath.Join(from, "link")))
+
+	var buf bytes.Buffer
+	require.NoError(t, tarstream.Send(from, &buf))
+	require.NoError(t, tarstream.Receive(to, &buf))
+
+	target, err := os.Readlink(filepath.Join(to, "link"))
+	require.NoError(t, err)
+	require.Equal(t, "run.sh", target)
+
+	st, err := os.Stat(filepath.Join(to, "link"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0755).String(), st.Mode().String())
+}
+
+func TestReceiveRejectsEscape(t *testing.T) {
+	for _, entries := range [][]*tar.Header{
+		{{Name: "../x.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		{{Name: "/x.txt", Typeflag: tar.TypeReg, Mode: 0644}},
+		{
+			{Name: "link", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "link/x.txt", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	} {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, h := range entries {
+			require.NoError(t, tw.WriteHeader(h))
+		}
+		require.NoError(t, tw.Close())
+
+		dir := filepath.Join(t.TempDir(), "to")
+		require.NoError(t, os.Mkdir(dir, 0777))
+
+		require.Error(t, tarstream.Receive(dir, &buf))
+
+		_, err := os.Stat(filepath.Join(dir, "..", "x.txt"))
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestDedup(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+	blobs := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(from, "old.bin"), []byte("old"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(from, "new.txt"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(blobs, "old"), []byte("old"), 0644))
+
+	entries, err := tarstream.Manifest(from)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	oldHash := sha256.Sum256([]byte("old"))
+	have := func(h tarstream.Hash) bool { return h == oldHash }
+
+	var buf bytes.Buffer
+	require.NoError(t, tarstream.SendEntries(from, entries, have, &buf))
+	require.NotContains(t, buf.String(), "old\x00")
+
+	var missing bytes.Buffer
+	missing.Write(buf.Bytes())
+	require.ErrorContains(t, tarstream.Receive(t.TempDir(), &missing), "missing")
+
+	require.NoError(t, tarstream.ReceiveDedup(to, &buf, func(h tarstream.Hash) (string, bool) {
+		return filepath.Join(blobs, "old"), h == oldHash
+	}))
+
+	for name, content := range map[string]string{"old.bin": "old", "new.txt": "new"} {
+		b, err := os.ReadFile(filepath.Join(to, name))
+		require.NoError(t, err)
+		require.Equal(t, content, string(b))
+	}
+
+	st, err := os.Stat(filepath.Join(to, "old.bin"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0755).String(), st.Mode().String())
+}
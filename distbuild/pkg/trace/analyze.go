@@ -0,0 +1,84 @@
+package trace
+
+import (
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Parallelism возвращает, сколько джобов в среднем выполнялось одновременно:
+// суммарное время работы джобов на воркерах, делённое на длительность сборки.
+func (t *Trace) Parallelism() float64 {
+	wall := t.End.Sub(t.Start)
+	if wall <= 0 {
+		return 0
+	}
+
+	var busy time.Duration
+	for i := range t.Jobs {
+		busy += t.Jobs[i].Duration()
+	}
+	return float64(busy) / float64(wall)
+}
+
+// CriticalPath возвращает самую длинную по Duration цепочку джобов, в которой каждый следующий
+// джоб зависит от предыдущего. Её длина - нижняя оценка времени сборки при бесконечном числе воркеров.
+//
+// Для каждого джоба учитывается последний запуск. Зависимости, которых нет в трейсе,
+// например взятые из удалённого кеша, пропускаются.
+func (t *Trace) CriticalPath() []Job {
+	last := make(map[build.ID]*Job, len(t.Jobs))
+	for i := range t.Jobs {
+		job := &t.Jobs[i]
+		if prev, ok := last[job.ID]; !ok || job.Attempt > prev.Attempt {
+			last[job.ID] = job
+		}
+	}
+
+	type node struct {
+		length time.Duration
+		prev   *Job
+	}
+
+	nodes := make(map[build.ID]node, len(last))
+	var visit func(job *Job) time.Duration
+	visit = func(job *Job) time.Duration {
+		if n, ok := nodes[job.ID]; ok {
+			return n.length
+		}
+
+		var n node
+		for _, dep := range job.Deps {
+			depJob, ok := last[dep]
+			if !ok {
+				continue
+			}
+
+			if length := visit(depJob); n.prev == nil || length > n.length {
+				n = node{length: length, prev: depJob}
+			}
+		}
+
+		n.length += job.Duration()
+		nodes[job.ID] = n
+		return n.length
+	}
+
+	var end *Job
+	for i := range t.Jobs {
+		job := last[t.Jobs[i].ID]
+		if end == nil || visit(job) > visit(end) {
+			end = job
+		}
+	}
+
+	var path []Job
+	for job := end; job != nil; job = nodes[job.ID].prev {
+		path = append(path, *job)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,183 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// Формат Trace Event, который понимают chrome://tracing и https://ui.perfetto.dev.
+//
+// Каждый воркер отображается отдельным процессом, а очередь шедулера - процессом "queue".
+// Джобы, которые выполнялись на воркере одновременно, раскладываются по разным потокам.
+// Событие джоба хранит Job целиком в args, поэтому ReadChrome восстанавливает Trace без потерь.
+
+const (
+	categoryJob   = "job"
+	categoryQueue = "queue"
+	categoryStage = "stage"
+
+	queuePID = 1
+)
+
+type chromeEvent struct {
+	Name     string          `json:"name"`
+	Category string          `json:"cat,omitempty"`
+	Phase    string          `json:"ph"`
+	TS       float64         `json:"ts"`
+	Dur      float64         `json:"dur,omitempty"`
+	PID      int             `json:"pid"`
+	TID      int             `json:"tid"`
+	Args     json.RawMessage `json:"args,omitempty"`
+}
+
+type chromeTrace struct {
+	TraceEvents     []chromeEvent `json:"traceEvents"`
+	DisplayTimeUnit string        `json:"displayTimeUnit"`
+	OtherData       *Trace        `json:"otherData"`
+}
+
+type jobArgs struct {
+	Job *Job `json:"job"`
+}
+
+// lanes раскладывает отрезки одного процесса по потокам так, чтобы они не пересекались.
+type lanes []time.Time
+
+func (l *lanes) place(s Span) int {
+	for i, end := range *l {
+		if !s.Start.Before(end) {
+			(*l)[i] = s.End
+			return i
+		}
+	}
+
+	*l = append(*l, s.End)
+	return len(*l) - 1
+}
+
+// WriteChrome записывает трейс в формате Chrome Trace Event.
+func WriteChrome(w io.Writer, t *Trace) error {
+	ts := func(at time.Time) float64 {
+		return float64(at.Sub(t.Start)) / float64(time.Microsecond)
+	}
+
+	var events []chromeEvent
+	span := func(name, category string, s Span, pid, tid int, args json.RawMessage) {
+		events = append(events, chromeEvent{
+			Name:     name,
+			Category: category,
+			Phase:    "X",
+			TS:       ts(s.Start),
+			Dur:      float64(s.Duration()) / float64(time.Microsecond),
+			PID:      pid,
+			TID:      tid,
+			Args:     args,
+		})
+	}
+
+	processName := func(pid int, name string) error {
+		args, err := json.Marshal(map[string]string{"name": name})
+		if err != nil {
+			return err
+		}
+		events = append(events, chromeEvent{Name: "process_name", Phase: "M", PID: pid, Args: args})
+		return nil
+	}
+
+	if err := processName(queuePID, "queue"); err != nil {
+		return err
+	}
+
+	jobs := slices.Clone(t.Jobs)
+	slices.SortStableFunc(jobs, func(a, b Job) int {
+		return a.Picked.Compare(b.Picked)
+	})
+
+	pids := make(map[string]int)
+	workerLanes := make(map[string]*lanes)
+	var queueLanes lanes
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		pid, ok := pids[job.Worker]
+		if !ok {
+			pid = queuePID + 1 + len(pids)
+			pids[job.Worker] = pid
+			workerLanes[job.Worker] = &lanes{}
+
+			if err := processName(pid, job.Worker); err != nil {
+				return err
+			}
+		}
+
+		wait := Span{Start: job.Queued, End: job.Picked}
+		span(job.Name, categoryQueue, wait, queuePID, queueLanes.place(wait), nil)
+
+		args, err := json.Marshal(jobArgs{Job: job})
+		if err != nil {
+			return err
+		}
+
+		run := Span{Start: job.Picked, End: job.Finished}
+		tid := workerLanes[job.Worker].place(run)
+		span(job.Name, categoryJob, run, pid, tid, args)
+
+		if job.Timings == nil || job.Timings.CacheHit {
+			continue
+		}
+
+		for _, stage := range []struct {
+			name string
+			span Span
+		}{
+			{"download", job.Timings.Download},
+			{"exec", job.Timings.Exec},
+			{"upload", job.Timings.Upload},
+		} {
+			if !stage.span.Start.IsZero() {
+				span(stage.name, categoryStage, stage.span, pid, tid, nil)
+			}
+		}
+	}
+
+	return json.NewEncoder(w).Encode(chromeTrace{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+		OtherData:       &Trace{BuildID: t.BuildID, Start: t.Start, End: t.End},
+	})
+}
+
+// ReadChrome читает трейс, записанный WriteChrome.
+func ReadChrome(r io.Reader) (*Trace, error) {
+	var ct chromeTrace
+	if err := json.NewDecoder(r).Decode(&ct); err != nil {
+		return nil, err
+	}
+
+	if ct.OtherData == nil {
+		return nil, fmt.Errorf("not a distbuild trace: otherData is missing")
+	}
+
+	t := ct.OtherData
+	for _, e := range ct.TraceEvents {
+		if e.Category != categoryJob {
+			continue
+		}
+
+		var args jobArgs
+		if err := json.Unmarshal(e.Args, &args); err != nil {
+			return nil, fmt.Errorf("job %q: %w", e.Name, err)
+		}
+
+		if args.Job == nil {
+			return nil, fmt.Errorf("job %q: args.job is missing", e.Name)
+		}
+		t.Jobs = append(t.Jobs, *args.Job)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,81 @@
+// Package trace описывает, на что ушло время сборки.
+//
+// Координатор записывает для каждого запуска джоба, когда джоб попал в очередь, когда его забрал воркер
+// и когда пришёл результат. Воркер дополняет эти данные временем скачивания входов, выполнения команд
+// и сохранения артефакта.
+package trace
+
+import (
+	"time"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// Span - отрезок времени.
+type Span struct {
+	Start, End time.Time
+}
+
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Timings описывает выполнение джоба на воркере. Время измеряется по часам воркера.
+type Timings struct {
+	// CacheHit означает, что результат джоба нашёлся в кеше артефактов воркера
+	// и команды джоба не запускались.
+	CacheHit bool `json:",omitempty"`
+
+	// Download - скачивание исходных файлов и артефактов зависимостей.
+	Download Span
+	// Exec - выполнение команд джоба.
+	Exec Span
+	// Upload - сохранение выходной директории и вывода джоба в кеш артефактов.
+	Upload Span
+}
+
+// Job описывает один запуск джоба. Если воркер пропал, джоб запускается повторно,
+// и в трейсе появляется несколько Job с одним ID.
+type Job struct {
+	ID   build.ID
+	Name string
+	Deps []build.ID `json:",omitempty"`
+
+	// Attempt - номер запуска, начиная с 1.
+	Attempt int
+
+	// Worker - воркер, который забрал джоб.
+	Worker string
+
+	// Queued - джоб попал в очередь шедулера.
+	Queued time.Time
+	// Picked - воркер забрал джоб.
+	Picked time.Time
+	// Finished - координатор получил результат джоба.
+	Finished time.Time
+
+	// Timings равен nil, если результат пришёл не от воркера, например воркер пропал.
+	Timings *Timings `json:",omitempty"`
+
+	Failed bool `json:",omitempty"`
+}
+
+// Wait возвращает время, которое джоб провёл в очереди.
+func (j *Job) Wait() time.Duration {
+	return j.Picked.Sub(j.Queued)
+}
+
+// Duration возвращает время от момента, когда воркер забрал джоб, до получения результата.
+func (j *Job) Duration() time.Duration {
+	return j.Finished.Sub(j.Picked)
+}
+
+// Trace описывает одну сборку.
+type Trace struct {
+	BuildID build.ID
+
+	// Start - координатор получил граф сборки. End - сборка завершилась.
+	Start, End time.Time
+
+	Jobs []Job
+}
@@ -0,0 +1,105 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(seconds int) time.Time {
+	return start.Add(time.Duration(seconds) * time.Second)
+}
+
+// diamond: a -> {b, c} -> d. Ветка через c длиннее.
+func diamond() *Trace {
+	a, b, c, d := build.ID{'a'}, build.ID{'b'}, build.ID{'c'}, build.ID{'d'}
+
+	return &Trace{
+		BuildID: build.ID{'t'},
+		Start:   at(0),
+		End:     at(10),
+		Jobs: []Job{
+			{ID: a, Name: "a", Attempt: 1, Worker: "w0", Queued: at(0), Picked: at(0), Finished: at(2)},
+			{ID: b, Name: "b", Deps: []build.ID{a}, Attempt: 1, Worker: "w0", Queued: at(2), Picked: at(2), Finished: at(3)},
+			{ID: c, Name: "c", Deps: []build.ID{a}, Attempt: 1, Worker: "w1", Queued: at(2), Picked: at(3), Finished: at(4)},
+			{ID: c, Name: "c", Deps: []build.ID{a}, Attempt: 2, Worker: "w0", Queued: at(4), Picked: at(4), Finished: at(8),
+				Timings: &Timings{
+					Download: Span{Start: at(4), End: at(5)},
+					Exec:     Span{Start: at(5), End: at(7)},
+					Upload:   Span{Start: at(7), End: at(8)},
+				}},
+			{ID: d, Name: "d", Deps: []build.ID{b, c}, Attempt: 1, Worker: "w1", Queued: at(8), Picked: at(8), Finished: at(10),
+				Timings: &Timings{CacheHit: true}},
+		},
+	}
+}
+
+func names(jobs []Job) []string {
+	var names []string
+	for _, job := range jobs {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+func TestCriticalPath(t *testing.T) {
+	tr := diamond()
+
+	path := tr.CriticalPath()
+	require.Equal(t, []string{"a", "c", "d"}, names(path))
+	require.Equal(t, 2, path[1].Attempt)
+
+	require.InDelta(t, 1.0, tr.Parallelism(), 1e-9)
+}
+
+func TestCriticalPathSkipsMissingDeps(t *testing.T) {
+	tr := &Trace{
+		Jobs: []Job{
+			{ID: build.ID{'b'}, Name: "b", Deps: []build.ID{{'a'}}, Picked: at(0), Finished: at(1)},
+		},
+	}
+
+	require.Equal(t, []string{"b"}, names(tr.CriticalPath()))
+	require.Empty(t, (&Trace{}).CriticalPath())
+}
+
+func TestChromeRoundTrip(t *testing.T) {
+	tr := diamond()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteChrome(&buf, tr))
+
+	var events struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &events))
+
+	// Джобы одного воркера не пересекаются по времени, поэтому каждому воркеру хватает одного потока.
+	for _, e := range events.TraceEvents {
+		if e.Category == categoryJob {
+			require.Equal(t, 0, e.TID, e.Name)
+		}
+	}
+
+	read, err := ReadChrome(&buf)
+	require.NoError(t, err)
+	require.Equal(t, tr.BuildID, read.BuildID)
+	require.True(t, tr.Start.Equal(read.Start))
+	require.Equal(t, names(tr.CriticalPath()), names(read.CriticalPath()))
+	require.Len(t, read.Jobs, len(tr.Jobs))
+}
+
+func TestLanes(t *testing.T) {
+	var l lanes
+	require.Equal(t, 0, l.place(Span{Start: at(0), End: at(2)}))
+	require.Equal(t, 1, l.place(Span{Start: at(1), End: at(3)}))
+	require.Equal(t, 0, l.place(Span{Start: at(2), End: at(4)}))
+	require.Equal(t, 2, l.place(Span{Start: at(2), End: at(4)}))
+}
@@ -0,0 +1,230 @@
+//go:build !solution
+
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+)
+
+// runBuiltin выполняет встроенные команды cat, copy, symlink, mkdir и write без запуска процесса.
+// Если cmd не встроенная команда, runBuiltin возвращает ok == false.
+//
+// Встроенная команда выполняется процессом воркера вне песочницы, поэтому runBuiltin сам проверяет,
+// что команда читает только внутри mounts, а пишет только внутри {{.OutputDir}}. В герметичном режиме
+// copy сообщает об обращении к каждому скопированному файлу, а не только к корню копии.
+func (w *Worker) runBuiltin(cmd *build.Cmd, jobCtx build.JobContext, mounts []sandbox.Mount) (ok bool, accesses []hermetic.Access, err error) {
+	var reads, writes []string
+	var run func() error
+
+	switch {
+	case cmd.CatOutput != "":
+		writes = []string{cmd.CatOutput}
+		run = func() error {
+			return os.WriteFile(cmd.CatOutput, []byte(cmd.CatTemplate), 0666)
+		}
+
+	case cmd.CopyTo != "":
+		reads, writes = []string{cmd.CopyFrom}, []string{cmd.CopyTo}
+		run = func() error {
+			return copyTree(cmd.CopyFrom, cmd.CopyTo, func(path, target string) {
+				if w.hermetic && path != cmd.CopyFrom {
+					accesses = append(accesses, hermetic.Access{Path: path}, hermetic.Access{Path: target, Write: true})
+				}
+			})
+		}
+
+	case cmd.SymlinkPath != "":
+		writes = []string{cmd.SymlinkPath}
+		run = func() error {
+			return os.Symlink(cmd.SymlinkTarget, cmd.SymlinkPath)
+		}
+
+	case cmd.MkdirPath != "":
+		writes = []string{cmd.MkdirPath}
+		run = func() error {
+			return os.MkdirAll(cmd.MkdirPath, 0777)
+		}
+
+	case cmd.WriteOutput != "":
+		reads, writes = []string{cmd.WriteFrom}, []string{cmd.WriteOutput}
+		run = func() error {
+			return writeTemplate(cmd.WriteFrom, cmd.WriteOutput, jobCtx)
+		}
+
+	default:
+		return false, nil, nil
+	}
+
+	// Писать можно только в выходную директорию, даже если команде доступны другие директории на запись.
+	builtinMounts := make([]sandbox.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		builtinMounts = append(builtinMounts, sandbox.Mount{Path: m.Path, Writable: m.Path == jobCtx.OutputDir})
+	}
+
+	if err := checkMounts(builtinMounts, reads, writes); err != nil {
+		return true, nil, err
+	}
+
+	if w.hermetic {
+		for _, path := range reads {
+			accesses = append(accesses, hermetic.Access{Path: path})
+		}
+		for _, path := range writes {
+			accesses = append(accesses, hermetic.Access{Path: path, Write: true})
+		}
+	}
+
+	err = run()
+	return true, accesses, err
+}
+
+// checkMounts проверяет, что пути reads доступны на чтение, а writes - на запись.
+//
+// Символические ссылки в путях раскрываются, иначе джоб мог бы выйти из песочницы через ссылку,
+// которую сам создал в выходной директории.
+func checkMounts(mounts []sandbox.Mount, reads, writes []string) error {
+	resolved := make([]sandbox.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		path, err := filepath.EvalSymlinks(m.Path)
+		if err != nil {
+			return err
+		}
+		resolved = append(resolved, sandbox.Mount{Path: path, Writable: m.Writable})
+	}
+
+	for _, path := range reads {
+		target, err := resolvePath(path)
+		if err != nil {
+			return err
+		}
+
+		if !sandbox.Readable(resolved, target) {
+			return fmt.Errorf("builtin: %s is outside of readable directories", path)
+		}
+	}
+
+	for _, path := range writes {
+		target, err := resolvePath(path)
+		if err != nil {
+			return err
+		}
+
+		if !sandbox.Writable(resolved, target) {
+			return fmt.Errorf("builtin: %s is outside of writable directories", path)
+		}
+	}
+	return nil
+}
+
+// resolvePath раскрывает символические ссылки в самом длинном существующем префиксе path.
+// Ссылка, которая никуда не указывает, - ошибка: запись через неё создала бы файл где угодно.
+func resolvePath(path string) (string, error) {
+	path = filepath.Clean(path)
+
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		if _, err := os.Lstat(path); err == nil {
+			return "", fmt.Errorf("builtin: dangling symlink %s", path)
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// copyTree копирует файл, символическую ссылку или директорию src по пути dst с сохранением прав файлов.
+// dst не должен существовать, поэтому копия не может записать файлы через уже существующие ссылки.
+//
+// copied вызывается для каждого скопированного пути, включая сам src.
+func copyTree(src, dst string, copied func(path, target string)) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		copied(path, target)
+
+		switch {
+		case d.IsDir():
+			return os.Mkdir(target, 0777)
+
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return copyFile(path, target, info.Mode().Perm())
+
+		default:
+			return fmt.Errorf("copy: unsupported file type %s: %s", d.Type(), path)
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// writeTemplate рендерит содержимое файла src с контекстом джоба и записывает результат в dst.
+func writeTemplate(src, dst string, jobCtx build.JobContext) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	rendered, err := jobCtx.RenderTemplate(string(content))
+	if err != nil {
+		return fmt.Errorf("write: %s: %w", src, err)
+	}
+	return os.WriteFile(dst, []byte(rendered), 0666)
+}
@@ -0,0 +1,154 @@
+//go:build !solution
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+)
+
+// pullFile скачивает файл с координатора, если его нет в локальном кеше.
+func (w *Worker) pullFile(ctx context.Context, id build.ID) error {
+	_, err, _ := w.downloads.Do("file/"+id.String(), func() (any, error) {
+		_, unlock, err := w.fileCache.Get(id)
+		if err == nil {
+			unlock()
+			w.metrics.cacheLookup("file", true)
+			return nil, nil
+		} else if !errors.Is(err, filecache.ErrNotFound) {
+			return nil, err
+		}
+
+		w.metrics.cacheLookup("file", false)
+
+		return nil, w.files.Download(ctx, w.fileCache, id)
+	})
+	return err
+}
+
+// pullArtifact скачивает артефакт с воркера from, если его нет в локальном кеше.
+func (w *Worker) pullArtifact(ctx context.Context, id build.ID, from api.WorkerID) error {
+	_, err, _ := w.downloads.Do("artifact/"+id.String(), func() (any, error) {
+		_, unlock, err := w.artifacts.Get(id)
+		if err == nil {
+			unlock()
+			w.metrics.cacheLookup("artifact", true)
+			return nil, nil
+		} else if !errors.Is(err, artifact.ErrNotFound) {
+			return nil, err
+		}
+
+		w.metrics.cacheLookup("artifact", false)
+
+		if from == "" {
+			return nil, fmt.Errorf("artifact %s location is unknown", id)
+		}
+
+		t, err := artifact.DownloadWith(ctx, from.String(), w.artifacts, id, artifact.DownloadOptions{
+			Blobs:    w.blobs,
+			Compress: w.compress,
+			Client:   w.http,
+		})
+		w.metrics.transferBytes.WithLabelValues("received").Add(float64(t.Received))
+		w.metrics.reusedBytes.Add(float64(t.Reused))
+		if err != nil {
+			return nil, err
+		}
+
+		w.indexArtifact(id)
+		return nil, nil
+	})
+	return err
+}
+
+// pullCachedArtifact восстанавливает артефакт джоба из удалённого кеша координатора, если его нет в локальном кеше.
+//
+// Координатор узнаёт о восстановленном артефакте из следующего heartbeat-а, как о результате джоба.
+func (w *Worker) pullCachedArtifact(ctx context.Context, id build.ID) error {
+	_, err, _ := w.downloads.Do("artifact/"+id.String(), func() (any, error) {
+		_, unlock, err := w.artifacts.Get(id)
+		if err == nil {
+			unlock()
+			w.metrics.cacheLookup("artifact", true)
+			return nil, nil
+		} else if !errors.Is(err, artifact.ErrNotFound) {
+			return nil, err
+		}
+
+		w.metrics.cacheLookup("artifact", false)
+
+		res, err := w.cache.GetActionResult(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("artifact %s: %w", id, err)
+		}
+
+		path, commit, abort, err := w.artifacts.Create(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := restoreArtifact(ctx, w.cache, res, path); err != nil {
+			_ = abort()
+			return nil, fmt.Errorf("artifact %s: %w", id, err)
+		}
+
+		if err := commit(); err != nil {
+			return nil, err
+		}
+
+		w.indexArtifact(id)
+
+		w.mu.Lock()
+		w.added = append(w.added, id)
+		w.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+// restoreArtifact раскладывает результат джоба из удалённого кеша в директорию артефакта path так же,
+// как её раскладывает runJob.
+func restoreArtifact(ctx context.Context, cache *remotecache.Client, res *remotecache.ActionResult, path string) error {
+	if err := os.Mkdir(outputDir(path), 0777); err != nil {
+		return err
+	}
+
+	if err := cache.DownloadOutputs(ctx, res, outputDir(path)); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(path, stdoutName), res.StdoutRaw, 0666); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, stderrName), res.StderrRaw, 0666)
+}
+
+// indexArtifact добавляет файлы артефакта в индекс, чтобы не скачивать их повторно в составе других артефактов.
+func (w *Worker) indexArtifact(id build.ID) {
+	if err := w.blobs.Add(w.artifacts, id); err != nil {
+		w.log.Warn("failed to index artifact", zap.String("artifact_id", id.String()), zap.Error(err))
+	}
+}
+
+// indexCache добавляет в индекс артефакты, оставшиеся в кеше с прошлого запуска воркера.
+func (w *Worker) indexCache(ctx context.Context) {
+	err := w.artifacts.Range(func(id build.ID) error {
+		// Артефакт могли удалить или начать перезаписывать, пока мы обходили кеш.
+		_ = w.blobs.Add(w.artifacts, id)
+		return ctx.Err()
+	})
+	if err != nil && ctx.Err() == nil {
+		w.log.Warn("failed to index artifact cache", zap.Error(err))
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !solution
+
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// runGC периодически чистит кеши воркера, пока не отменён ctx.
+func (w *Worker) runGC(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.gcInterval):
+		}
+
+		evicted, err := w.artifacts.GC(*w.gcConfig)
+		for _, id := range evicted {
+			w.blobs.Forget(id)
+		}
+		if len(evicted) != 0 {
+			w.log.Info("artifacts evicted", zap.Int("count", len(evicted)))
+
+			w.mu.Lock()
+			w.evicted = append(w.evicted, evicted...)
+			w.mu.Unlock()
+		}
+		if err != nil {
+			w.log.Warn("artifact cache gc failed", zap.Error(err))
+		}
+
+		evictedFiles, err := w.fileCache.GC(*w.gcConfig)
+		if len(evictedFiles) != 0 {
+			w.log.Info("files evicted", zap.Int("count", len(evictedFiles)))
+		}
+		if err != nil {
+			w.log.Warn("file cache gc failed", zap.Error(err))
+		}
+	}
+}
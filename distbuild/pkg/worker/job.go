@@ -0,0 +1,354 @@
+//go:build !solution
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/hermetic"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
+	"gitlab.com/slon/shad-go/distbuild/pkg/trace"
+)
+
+// Раскладка артефакта джоба в artifact.Cache.
+//
+//	output/ - выходная директория джоба, {{.OutputDir}}.
+//	stdout  - stdout всех команд джоба.
+//	stderr  - stderr всех команд джоба.
+const (
+	outputDirName = artifact.JobOutputDir
+	stdoutName    = "stdout"
+	stderrName    = "stderr"
+)
+
+func outputDir(artifactPath string) string {
+	return filepath.Join(artifactPath, outputDirName)
+}
+
+func errorResult(id build.ID, err error) *api.JobResult {
+	msg := err.Error()
+	return &api.JobResult{ID: id, Error: &msg}
+}
+
+// cachedResult читает результат джоба из кеша артефактов.
+func (w *Worker) cachedResult(id build.ID) (*api.JobResult, bool) {
+	path, unlock, err := w.artifacts.Get(id)
+	if err != nil {
+		return nil, false
+	}
+	defer unlock()
+
+	stdout, err := os.ReadFile(filepath.Join(path, stdoutName))
+	if err != nil {
+		return nil, false
+	}
+
+	stderr, err := os.ReadFile(filepath.Join(path, stderrName))
+	if err != nil {
+		return nil, false
+	}
+
+	return &api.JobResult{ID: id, Stdout: stdout, Stderr: stderr, Timings: &trace.Timings{CacheHit: true}}, true
+}
+
+// prepareSourceDir создаёт директорию с исходными файлами джоба.
+func (w *Worker) prepareSourceDir(ctx context.Context, spec *api.JobSpec) (dir string, err error) {
+	dir, err = os.MkdirTemp("", "distbuild-src-")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(dir)
+		}
+	}()
+
+	for id, path := range spec.SourceFiles {
+		if err = w.pullFile(ctx, id); err != nil {
+			return
+		}
+
+		if err = w.copyFile(id, filepath.Join(dir, path)); err != nil {
+			return
+		}
+	}
+
+	return dir, nil
+}
+
+func (w *Worker) copyFile(id build.ID, dst string) error {
+	src, unlock, err := w.fileCache.Get(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	st, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, st.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+func (w *Worker) runJob(ctx context.Context, spec *api.JobSpec) *api.JobResult {
+	l := w.log.With(zap.String("job_id", spec.ID.String()), zap.String("name", spec.Name))
+
+	res, ok := w.cachedResult(spec.ID)
+	w.metrics.cacheLookup("job", ok)
+	if ok {
+		l.Debug("job result found in cache")
+		return res
+	}
+
+	l.Debug("job started")
+
+	timings := &trace.Timings{}
+	timings.Download.Start = time.Now()
+
+	sourceDir, err := w.prepareSourceDir(ctx, spec)
+	if err != nil {
+		return errorResult(spec.ID, err)
+	}
+	defer func() { _ = os.RemoveAll(sourceDir) }()
+
+	jobCtx := build.JobContext{
+		SourceDir: sourceDir,
+		Deps:      make(map[build.ID]string, len(spec.Deps)),
+		Tools:     make(map[string]string, len(w.tools)),
+	}
+
+	for _, tool := range w.tools {
+		jobCtx.Tools[tool.Name] = tool.Path
+	}
+
+	for _, dep := range spec.Deps {
+		var err error
+		if slices.Contains(spec.CachedArtifacts, dep) {
+			err = w.pullCachedArtifact(ctx, dep)
+		} else {
+			err = w.pullArtifact(ctx, dep, spec.Artifacts[dep])
+		}
+		if err != nil {
+			return errorResult(spec.ID, err)
+		}
+
+		path, unlock, err := w.artifacts.Get(dep)
+		if err != nil {
+			return errorResult(spec.ID, err)
+		}
+		defer unlock()
+
+		jobCtx.Deps[dep] = outputDir(path)
+	}
+	timings.Download.End = time.Now()
+
+	path, commit, abort, err := w.artifacts.Create(spec.ID)
+	if errors.Is(err, artifact.ErrExists) {
+		if res, ok := w.cachedResult(spec.ID); ok {
+			return res
+		}
+		return errorResult(spec.ID, err)
+	} else if err != nil {
+		return errorResult(spec.ID, err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = abort()
+		}
+	}()
+
+	jobCtx.OutputDir = outputDir(path)
+	if err := os.Mkdir(jobCtx.OutputDir, 0777); err != nil {
+		return errorResult(spec.ID, err)
+	}
+
+	mounts := []sandbox.Mount{
+		{Path: jobCtx.SourceDir},
+		{Path: jobCtx.OutputDir, Writable: true},
+	}
+	for _, path := range jobCtx.Deps {
+		mounts = append(mounts, sandbox.Mount{Path: path})
+	}
+	for _, tool := range w.tools {
+		mounts = append(mounts, sandbox.Mount{Path: tool.Path, Writable: tool.Writable})
+	}
+
+	var accesses []hermetic.Access
+	res = &api.JobResult{ID: spec.ID, Timings: timings}
+
+	output := w.newOutputStream(ctx, spec.ID)
+	defer output.Close()
+
+	timings.Exec.Start = time.Now()
+
+	for _, cmd := range spec.Cmds {
+		rendered, err := cmd.Render(jobCtx)
+		if err != nil {
+			return errorResult(spec.ID, err)
+		}
+
+		var cmdAccesses []hermetic.Access
+		res.ExitCode, cmdAccesses, err = w.runCmd(ctx, rendered, jobCtx, mounts, output.Stdout(), output.Stderr())
+		accesses = append(accesses, cmdAccesses...)
+		if err != nil {
+			msg := err.Error()
+			res.Error = &msg
+			break
+		}
+
+		if res.ExitCode != 0 {
+			break
+		}
+	}
+
+	res.Stdout, res.Stderr = output.Close()
+
+	if w.hermetic && res.Error == nil {
+		res.Violations = w.checkHermetic(spec, jobCtx, accesses)
+		if len(res.Violations) != 0 {
+			msg := fmt.Sprintf("job is not hermetic: %d violations", len(res.Violations))
+			res.Error = &msg
+		}
+	}
+	timings.Exec.End = time.Now()
+
+	if res.Error != nil || res.ExitCode != 0 {
+		l.Info("job failed", zap.Int("exit_code", res.ExitCode))
+		return res
+	}
+
+	timings.Upload.Start = time.Now()
+	if err := os.WriteFile(filepath.Join(path, stdoutName), res.Stdout, 0666); err != nil {
+		return errorResult(spec.ID, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, stderrName), res.Stderr, 0666); err != nil {
+		return errorResult(spec.ID, err)
+	}
+
+	committed = true
+	if err := commit(); err != nil {
+		return errorResult(spec.ID, err)
+	}
+	timings.Upload.End = time.Now()
+
+	w.indexArtifact(spec.ID)
+
+	l.Debug("job finished")
+	return res
+}
+
+// checkHermetic проверяет обращения команд джоба к файловой системе.
+func (w *Worker) checkHermetic(spec *api.JobSpec, jobCtx build.JobContext, accesses []hermetic.Access) []api.Violation {
+	policy := hermetic.Policy{
+		SourceDir:  jobCtx.SourceDir,
+		OutputDir:  jobCtx.OutputDir,
+		ReadPaths:  append([]string{}, hermetic.DefaultSystemPaths...),
+		WritePaths: append([]string{}, hermetic.DefaultWritablePaths...),
+	}
+
+	for _, path := range spec.SourceFiles {
+		policy.Inputs = append(policy.Inputs, path)
+	}
+
+	for _, path := range jobCtx.Deps {
+		policy.ReadPaths = append(policy.ReadPaths, path)
+	}
+
+	for _, tool := range w.tools {
+		policy.ReadPaths = append(policy.ReadPaths, tool.Path)
+		if tool.Writable {
+			policy.WritePaths = append(policy.WritePaths, tool.Path)
+		}
+	}
+
+	// Внутри песочницы /tmp - приватный tmpfs, который исчезает вместе с джобом.
+	if w.sandbox != nil {
+		policy.WritePaths = append(policy.WritePaths, "/tmp")
+	}
+
+	return policy.Check(accesses)
+}
+
+// runCmd выполняет одну команду джоба и возвращает её код возврата.
+//
+// Если воркер запущен с песочницей, команде доступны только директории из mounts.
+// В герметичном режиме runCmd также возвращает все обращения команды к файлам.
+func (w *Worker) runCmd(
+	ctx context.Context,
+	cmd *build.Cmd,
+	jobCtx build.JobContext,
+	mounts []sandbox.Mount,
+	stdout, stderr io.Writer,
+) (int, []hermetic.Access, error) {
+	if ok, accesses, err := w.runBuiltin(cmd, jobCtx, mounts); ok {
+		return 0, accesses, err
+	}
+
+	if len(cmd.Exec) == 0 {
+		return 0, nil, nil
+	}
+
+	switch {
+	case w.hermetic && w.sandbox != nil:
+		return w.sandbox.RunTraced(ctx, cmd, mounts, stdout, stderr)
+	case w.hermetic:
+		return hermetic.Run(ctx, cmd, stdout, stderr)
+	case w.sandbox != nil:
+		code, err := w.sandbox.Run(ctx, cmd, mounts, stdout, stderr)
+		return code, nil, err
+	}
+
+	c := exec.CommandContext(ctx, cmd.Exec[0], cmd.Exec[1:]...)
+	c.Env = append([]string{}, cmd.Environ...)
+	c.Dir = cmd.WorkingDirectory
+	c.Stdout = stdout
+	c.Stderr = stderr
+	killProcessGroup(c)
+
+	err := c.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil, nil
+	}
+
+	return 0, nil, err
+}
@@ -0,0 +1,171 @@
+//go:build !solution
+
+package worker
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+)
+
+// metrics описывает метрики воркера. Метки принимают только перечисленные в комментариях значения,
+// чтобы число временных рядов не зависело от числа джобов.
+type metrics struct {
+	registry *prometheus.Registry
+
+	// jobDuration размечен result: success, failed, error, cached.
+	jobDuration *prometheus.HistogramVec
+	// cacheRequests размечен cache: job, artifact, file и result: hit, miss.
+	cacheRequests *prometheus.CounterVec
+	// transferBytes размечен direction: sent, received.
+	transferBytes *prometheus.CounterVec
+	reusedBytes   prometheus.Counter
+
+	heartbeatDuration prometheus.Histogram
+	heartbeatWait     prometheus.Histogram
+}
+
+func (w *Worker) newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "job_duration_seconds",
+			Help:      "Time spent running a job on the worker, by result.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"result"}),
+
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "cache_requests_total",
+			Help:      "Lookups of job results, artifacts and source files in local caches.",
+		}, []string{"cache", "result"}),
+
+		transferBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_transfer_bytes_total",
+			Help:      "Bytes of artifacts sent to and received from other workers via tarstream.",
+		}, []string{"direction"}),
+
+		reusedBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "artifact_reused_bytes_total",
+			Help:      "Bytes of downloaded artifacts copied from files already present in the local cache.",
+		}),
+
+		heartbeatDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "heartbeat_duration_seconds",
+			Help:      "Heartbeat round trip time, excluding the time coordinator waits for a new job.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+
+		heartbeatWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "distbuild",
+			Subsystem: "worker",
+			Name:      "heartbeat_wait_seconds",
+			Help:      "Time coordinator waited for a new job before answering a heartbeat.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+	}
+
+	runningJobs := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "distbuild",
+		Subsystem: "worker",
+		Name:      "running_jobs",
+		Help:      "Number of jobs running on the worker.",
+	}, func() float64 {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return float64(len(w.running))
+	})
+
+	cacheSize := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "distbuild",
+		Subsystem: "worker",
+		Name:      "artifact_cache_bytes",
+		Help:      "Total size of artifacts in the worker cache.",
+	}, func() float64 {
+		return float64(w.artifacts.Size())
+	})
+
+	m.registry.MustRegister(
+		m.jobDuration,
+		m.cacheRequests,
+		m.transferBytes,
+		m.reusedBytes,
+		m.heartbeatDuration,
+		m.heartbeatWait,
+		runningJobs,
+		cacheSize,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *metrics) cacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// observeHeartbeat разделяет время heartbeat-а elapsed на ожидание джоба на координаторе
+// и остальную задержку. Если heartbeat не удался, всё время считается задержкой.
+func (m *metrics) observeHeartbeat(elapsed time.Duration, rsp *api.HeartbeatResponse) {
+	if rsp != nil && rsp.Waited > 0 && rsp.Waited <= elapsed {
+		elapsed -= rsp.Waited
+		m.heartbeatWait.Observe(rsp.Waited.Seconds())
+	}
+	m.heartbeatDuration.Observe(elapsed.Seconds())
+}
+
+func jobResultLabel(res *api.JobResult) string {
+	switch {
+	case res.Timings != nil && res.Timings.CacheHit:
+		return "cached"
+	case res.Error != nil:
+		return "error"
+	case res.ExitCode != 0:
+		return "failed"
+	default:
+		return "success"
+	}
+}
+
+// countSent считает байты, которые handler отправил в ответах.
+func (m *metrics) countSent(handler http.Handler) http.Handler {
+	sent := m.transferBytes.WithLabelValues("sent")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(&countingResponseWriter{ResponseWriter: w, counter: sent}, r)
+	})
+}
+
+type countingResponseWriter struct {
+	http.ResponseWriter
+	counter prometheus.Counter
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.counter.Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,141 @@
+//go:build !solution
+
+package worker
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+)
+
+// outputLimit ограничивает объём вывода джоба, который ещё не доставлен на координатор.
+//
+// Если координатор или клиент не успевают читать вывод, запись в stdout и stderr джоба блокируется.
+const outputLimit = 1 << 20
+
+// outputStream копит полный вывод джоба и параллельно отправляет его кусками на координатор.
+type outputStream struct {
+	w      *Worker
+	id     build.ID
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu             sync.Mutex
+	cond           *sync.Cond
+	stdout, stderr bytes.Buffer
+	sent           [2]int
+	closed         bool
+}
+
+func (w *Worker) newOutputStream(ctx context.Context, id build.ID) *outputStream {
+	ctx, cancel := context.WithCancel(ctx)
+
+	s := &outputStream{
+		w:      w,
+		id:     id,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	go s.run(ctx)
+	return s
+}
+
+type streamWriter struct {
+	s      *outputStream
+	stderr bool
+}
+
+func (sw streamWriter) Write(p []byte) (int, error) {
+	s := sw.s
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.pending() >= outputLimit && !s.closed {
+		s.cond.Wait()
+	}
+
+	if sw.stderr {
+		s.stderr.Write(p)
+	} else {
+		s.stdout.Write(p)
+	}
+
+	s.cond.Broadcast()
+	return len(p), nil
+}
+
+func (s *outputStream) Stdout() io.Writer {
+	return streamWriter{s: s}
+}
+
+func (s *outputStream) Stderr() io.Writer {
+	return streamWriter{s: s, stderr: true}
+}
+
+func (s *outputStream) pending() int {
+	return s.stdout.Len() - s.sent[0] + s.stderr.Len() - s.sent[1]
+}
+
+// run отправляет на координатор всё, что накопилось с прошлой отправки. Одновременно
+// в полёте находится не больше одного куска, поэтому куски приходят на координатор по порядку.
+func (s *outputStream) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+		for s.pending() == 0 && !s.closed {
+			s.cond.Wait()
+		}
+
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+
+		chunk := &api.JobOutput{
+			ID:           s.id,
+			StdoutOffset: int64(s.sent[0]),
+			StderrOffset: int64(s.sent[1]),
+			Stdout:       bytes.Clone(s.stdout.Bytes()[s.sent[0]:]),
+			Stderr:       bytes.Clone(s.stderr.Bytes()[s.sent[1]:]),
+		}
+		s.mu.Unlock()
+
+		// Потерянный кусок не страшен: координатор возьмёт недостающий вывод из JobResult.
+		if err := s.w.outputs.JobOutput(ctx, chunk); err != nil && ctx.Err() == nil {
+			s.w.log.Debug("failed to send job output", zap.String("job_id", s.id.String()), zap.Error(err))
+		}
+
+		s.mu.Lock()
+		s.sent[0] += len(chunk.Stdout)
+		s.sent[1] += len(chunk.Stderr)
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// Close останавливает отправку и возвращает полный вывод джоба.
+//
+// Неотправленный остаток вывода клиент получит вместе с JobResult.
+func (s *outputStream) Close() (stdout, stderr []byte) {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stdout.Bytes(), s.stderr.Bytes()
+}
@@ -0,0 +1,12 @@
+//go:build !solution && !unix
+
+package worker
+
+import (
+	"os/exec"
+	"time"
+)
+
+func killProcessGroup(c *exec.Cmd) {
+	c.WaitDelay = time.Second
+}
@@ -0,0 +1,19 @@
+//go:build !solution && unix
+
+package worker
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killProcessGroup запускает команду в отдельной группе процессов и при отмене контекста
+// убивает всю группу, а не только первый процесс.
+func killProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+	c.WaitDelay = time.Second
+}
@@ -5,15 +5,164 @@ package worker
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
 
 	"gitlab.com/slon/shad-go/distbuild/pkg/api"
 	"gitlab.com/slon/shad-go/distbuild/pkg/artifact"
+	"gitlab.com/slon/shad-go/distbuild/pkg/auth"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
 	"gitlab.com/slon/shad-go/distbuild/pkg/filecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/remotecache"
+	"gitlab.com/slon/shad-go/distbuild/pkg/sandbox"
 )
 
+const (
+	// heartbeatInterval задаёт период heartbeat-ов, когда у воркера нет свободных слотов.
+	heartbeatInterval = time.Second
+
+	// retryInterval задаёт паузу после неудачного heartbeat-а.
+	retryInterval = 100 * time.Millisecond
+)
+
+// Option задаёт необязательный параметр воркера.
+type Option func(w *Worker)
+
+// WithHermeticMode включает проверку герметичности джобов.
+//
+// Воркер трассирует все обращения команд к файловой системе. Если джоб читает
+// необъявленные входы или пишет вне {{.OutputDir}}, он завершается с ошибкой,
+// а нарушения передаются клиенту в JobResult.Violations.
+func WithHermeticMode() Option {
+	return func(w *Worker) {
+		w.hermetic = true
+	}
+}
+
+// WithCacheGC включает периодическую очистку кеша артефактов и кеша файлов.
+//
+// Раз в interval воркер удаляет давно не использованные записи, пока каждый из кешей
+// не уложится в ограничения config, и сообщает координатору об удалённых артефактах.
+func WithCacheGC(config artifact.GCConfig, interval time.Duration) Option {
+	return func(w *Worker) {
+		w.gcConfig = &config
+		w.gcInterval = interval
+	}
+}
+
+// WithCapacity задаёт ресурсы воркера.
+//
+// По умолчанию у воркера DefaultMilliCPU процессорного времени, и он выполняет джобы по одному.
+// Если capacity.Memory == 0, воркер не учитывает память джобов.
+func WithCapacity(capacity build.Resources) Option {
+	return func(w *Worker) {
+		w.resources = api.WorkerResources{Capacity: capacity, Free: capacity}
+	}
+}
+
+// WithCompressedTransfer включает сжатие артефактов, которые воркер скачивает с других воркеров.
+//
+// Воркер просит отправителя сжать артефакт gzip. Это уменьшает трафик ценой процессорного времени
+// на обоих воркерах.
+func WithCompressedTransfer() Option {
+	return func(w *Worker) {
+		w.compress = true
+	}
+}
+
+// WithHTTPClient задаёт http.Client, через который воркер ходит на координатор и скачивает
+// артефакты с других воркеров, например с клиентским сертификатом из auth.NewHTTPClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(w *Worker) {
+		w.http = c
+	}
+}
+
+// WithGRPC переводит heartbeat-ы на gRPC соединение cc с координатором. Вывод джобов,
+// исходные файлы и артефакты по-прежнему передаются по HTTP.
+func WithGRPC(cc grpc.ClientConnInterface) Option {
+	return func(w *Worker) {
+		w.grpc = cc
+	}
+}
+
+// WithAuth включает аутентификацию запросов к воркеру. Артефакты и метрики отдаются только
+// запросам, которые пропустил a.
+func WithAuth(a *auth.Authenticator) Option {
+	return func(w *Worker) {
+		w.auth = a
+	}
+}
+
+// WithSandbox включает запуск команд джобов внутри песочницы s.
+func WithSandbox(s *sandbox.Sandbox) Option {
+	return func(w *Worker) {
+		w.sandbox = s
+	}
+}
+
+// Tool описывает директорию воркера, которую команды джобов получают через {{.Tool "<Name>"}}.
+type Tool struct {
+	Name string
+	Path string
+
+	// Writable разрешает джобам писать в Path, например в кеш компилятора.
+	Writable bool
+}
+
+// WithTools задаёт инструменты воркера, например GOROOT и GOCACHE для графов из gograph.
+//
+// Пути инструментов не входят в ID джобов, поэтому один и тот же джоб можно запускать на воркерах,
+// у которых инструменты лежат по разным путям. В песочнице и в герметичном режиме джобу доступны
+// директории всех инструментов: на чтение, а с Writable и на запись.
+func WithTools(tools ...Tool) Option {
+	return func(w *Worker) {
+		w.tools = append(w.tools, tools...)
+	}
+}
+
 type Worker struct {
+	id  api.WorkerID
+	log *zap.Logger
+
+	fileCache *filecache.Cache
+	artifacts *artifact.Cache
+	blobs     *artifact.BlobIndex
+	compress  bool
+
+	sandbox  *sandbox.Sandbox
+	hermetic bool
+	tools    []Tool
+
+	gcConfig   *artifact.GCConfig
+	gcInterval time.Duration
+
+	http      *http.Client
+	grpc      grpc.ClientConnInterface
+	auth      *auth.Authenticator
+	heartbeat api.HeartbeatService
+	outputs   *api.OutputClient
+	files     *filecache.Client
+	cache     *remotecache.Client
+	mux       *http.ServeMux
+	handler   http.Handler
+	metrics   *metrics
+
+	downloads singleflight.Group
+	jobs      sync.WaitGroup
+
+	mu        sync.Mutex
+	resources api.WorkerResources
+	running   map[build.ID]context.CancelFunc
+	cancelled map[build.ID]struct{}
+	finished  []api.JobResult
+	added     []build.ID
+	evicted   []build.ID
+	wakeup    chan struct{}
 }
 
 func New(
@@ -22,14 +171,222 @@ func New(
 	log *zap.Logger,
 	fileCache *filecache.Cache,
 	artifacts *artifact.Cache,
+	opts ...Option,
 ) *Worker {
-	panic("implement me")
+	w := &Worker{
+		id:  workerID,
+		log: log,
+
+		fileCache: fileCache,
+		artifacts: artifacts,
+		blobs:     artifact.NewBlobIndex(),
+
+		http: http.DefaultClient,
+		mux:  http.NewServeMux(),
+
+		resources: api.WorkerResources{
+			Capacity: build.Resources{MilliCPU: build.DefaultMilliCPU},
+			Free:     build.Resources{MilliCPU: build.DefaultMilliCPU},
+		},
+		running:   make(map[build.ID]context.CancelFunc),
+		cancelled: make(map[build.ID]struct{}),
+		wakeup:    make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if w.grpc != nil {
+		w.heartbeat = api.NewHeartbeatGRPCClient(log, w.grpc)
+	} else {
+		w.heartbeat = api.NewHeartbeatClient(log, coordinatorEndpoint, api.WithHTTPClient(w.http))
+	}
+	w.outputs = api.NewOutputClient(log, coordinatorEndpoint, api.WithHTTPClient(w.http))
+	w.files = filecache.NewClient(log, coordinatorEndpoint, filecache.WithHTTPClient(w.http))
+	w.cache = remotecache.NewClient(log, coordinatorEndpoint, remotecache.WithHTTPClient(w.http))
+
+	w.handler = w.mux
+	if w.auth != nil {
+		w.handler = w.auth.Handler(w.mux)
+	}
+
+	w.metrics = w.newMetrics()
+	w.mux.Handle("/metrics", w.metrics.handler())
+
+	artifactMux := http.NewServeMux()
+	artifact.NewHandler(log, artifacts).Register(artifactMux)
+	w.mux.Handle("/artifact", w.metrics.countSent(artifactMux))
+	w.mux.Handle("/artifact/", w.metrics.countSent(artifactMux))
+
+	return w
 }
 
 func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+	w.handler.ServeHTTP(rw, r)
+}
+
+func (w *Worker) heartbeatRequest() *api.HeartbeatRequest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	req := &api.HeartbeatRequest{
+		WorkerID:         w.id,
+		FreeSlots:        w.freeSlots(),
+		Resources:        w.resources,
+		FinishedJob:      w.finished,
+		AddedArtifacts:   w.added,
+		EvictedArtifacts: w.evicted,
+	}
+
+	for id := range w.running {
+		req.RunningJobs = append(req.RunningJobs, id)
+	}
+
+	w.finished = nil
+	w.added = nil
+	w.evicted = nil
+	return req
+}
+
+// restore возвращает результаты неудачного heartbeat-а в очередь на отправку.
+func (w *Worker) restore(req *api.HeartbeatRequest) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.finished = append(req.FinishedJob, w.finished...)
+	w.added = append(req.AddedArtifacts, w.added...)
+	w.evicted = append(req.EvictedArtifacts, w.evicted...)
+}
+
+// freeSlots возвращает 1, если воркер может взять ещё один джоб.
+func (w *Worker) freeSlots() int {
+	free := w.resources.Free
+	if free.MilliCPU <= 0 || (w.resources.Capacity.Memory != 0 && free.Memory <= 0) {
+		return 0
+	}
+	return 1
+}
+
+func (w *Worker) hasFreeSlots() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.freeSlots() > 0
 }
 
 func (w *Worker) Run(ctx context.Context) error {
-	panic("implement me")
+	defer w.jobs.Wait()
+
+	w.jobs.Add(1)
+	go func() {
+		defer w.jobs.Done()
+		w.indexCache(ctx)
+	}()
+
+	if w.gcConfig != nil {
+		w.jobs.Add(1)
+		go func() {
+			defer w.jobs.Done()
+			w.runGC(ctx)
+		}()
+	}
+
+	for {
+		req := w.heartbeatRequest()
+
+		start := time.Now()
+		rsp, err := w.heartbeat.Heartbeat(ctx, req)
+		w.metrics.observeHeartbeat(time.Since(start), rsp)
+		if err != nil {
+			w.restore(req)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			w.log.Warn("heartbeat failed", zap.Error(err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryInterval):
+			}
+			continue
+		}
+
+		for _, id := range rsp.JobsToCancel {
+			w.cancelJob(id)
+		}
+
+		for _, spec := range rsp.JobsToRun {
+			w.startJob(ctx, spec)
+		}
+
+		if w.hasFreeSlots() {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.wakeup:
+		case <-time.After(heartbeatInterval):
+		}
+	}
+}
+
+// cancelJob убивает процессы джоба. Результат отменённого джоба не отправляется на координатор.
+func (w *Worker) cancelJob(id build.ID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cancel, ok := w.running[id]
+	if !ok {
+		return
+	}
+
+	w.log.Info("cancelling job", zap.String("job_id", id.String()))
+	w.cancelled[id] = struct{}{}
+	cancel()
+}
+
+func (w *Worker) startJob(ctx context.Context, spec api.JobSpec) {
+	w.mu.Lock()
+	// Перезапущенный координатор может повторно отдать джоб, который уже выполняется.
+	if _, ok := w.running[spec.ID]; ok {
+		w.mu.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w.resources.Free = w.resources.Free.Sub(spec.Requests())
+	w.running[spec.ID] = cancel
+	w.mu.Unlock()
+
+	w.jobs.Add(1)
+	go func() {
+		defer w.jobs.Done()
+		defer cancel()
+
+		start := time.Now()
+		res := w.runJob(ctx, &spec)
+		w.metrics.jobDuration.WithLabelValues(jobResultLabel(res)).Observe(time.Since(start).Seconds())
+
+		w.mu.Lock()
+		w.resources.Free = w.resources.Free.Add(spec.Requests())
+		delete(w.running, spec.ID)
+		if _, ok := w.cancelled[spec.ID]; ok {
+			delete(w.cancelled, spec.ID)
+		} else {
+			w.finished = append(w.finished, *res)
+			if res.Error == nil && res.ExitCode == 0 {
+				w.added = append(w.added, spec.ID)
+			}
+		}
+		w.mu.Unlock()
+
+		select {
+		case w.wakeup <- struct{}{}:
+		default:
+		}
+	}()
 }
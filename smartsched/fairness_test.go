@@ -0,0 +1,125 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+// scheduleBuild планирует n джобов сборки и возвращает множество их ID.
+func (s *testScheduler) scheduleBuild(buildID build.ID, n int) map[build.ID]bool {
+	jobs := make(map[build.ID]bool, n)
+	for range n {
+		job := &api.JobSpec{Job: build.Job{ID: build.NewID()}}
+		s.ScheduleBuildJob(buildID, job)
+		jobs[job.ID] = true
+	}
+	return jobs
+}
+
+// advanceToGlobalQueue переводит часы на DepsTimeout, когда scheduled новых джобов ждут таймаута,
+// и ждёт, пока длина глобальной очереди станет равна queued.
+func (s *testScheduler) advanceToGlobalQueue(t *testing.T, scheduled, queued int) {
+	s.BlockUntil(scheduled)
+	s.Advance(config.DepsTimeout)
+
+	require.Eventually(t, func() bool {
+		return s.GlobalQueueLen() == queued
+	}, time.Second, time.Millisecond)
+}
+
+// pickJobs забирает n джобов одним воркером и считает, сколько из них досталось каждой сборке.
+func (s *testScheduler) pickJobs(t *testing.T, n int, builds map[string]map[build.ID]bool) map[string]int {
+	picked := make(map[string]int)
+	for range n {
+		pendingJob := s.tryPickJob(workerID0, api.WorkerResources{})
+		require.NotNil(t, pendingJob)
+
+		for name, jobs := range builds {
+			if jobs[pendingJob.Job.ID] {
+				picked[name]++
+			}
+		}
+
+		s.OnJobComplete(workerID0, pendingJob.Job.ID, &api.JobResult{ID: pendingJob.Job.ID})
+	}
+	return picked
+}
+
+func TestScheduler_FairShare(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	huge, small := build.NewID(), build.NewID()
+	s.RegisterBuild(huge, 1)
+	s.RegisterBuild(small, 1)
+
+	builds := map[string]map[build.ID]bool{
+		"huge":  s.scheduleBuild(huge, 20),
+		"small": s.scheduleBuild(small, 3),
+	}
+	s.advanceToGlobalQueue(t, 23, 23)
+
+	s.RegisterWorker(workerID0)
+
+	// Маленькая сборка запланировала джобы позже, но не ждёт, пока выполнится большая.
+	for range 3 {
+		require.Equal(t, map[string]int{"huge": 1, "small": 1}, s.pickJobs(t, 2, builds))
+	}
+
+	require.Equal(t, scheduler.Usage{Jobs: 3, MilliCPU: 3 * build.DefaultMilliCPU}, s.UnregisterBuild(small))
+	require.Equal(t, scheduler.Usage{Jobs: 3, MilliCPU: 3 * build.DefaultMilliCPU}, s.BuildUsage(huge))
+}
+
+func TestScheduler_PriorityWeights(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	ci, interactive := build.NewID(), build.NewID()
+	s.RegisterBuild(ci, api.PriorityBatch.Weight())
+	s.RegisterBuild(interactive, api.PriorityInteractive.Weight())
+
+	builds := map[string]map[build.ID]bool{
+		"ci":          s.scheduleBuild(ci, 10),
+		"interactive": s.scheduleBuild(interactive, 10),
+	}
+	s.advanceToGlobalQueue(t, 20, 20)
+
+	s.RegisterWorker(workerID0)
+
+	// Интерактивная сборка получает вчетверо больше джобов, но CI сборка не голодает.
+	for range 2 {
+		require.Equal(t, map[string]int{"ci": 1, "interactive": 4}, s.pickJobs(t, 5, builds))
+	}
+}
+
+func TestScheduler_IdleBuildDoesNotAccumulateCredit(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	busy, idle := build.NewID(), build.NewID()
+	s.RegisterBuild(busy, 1)
+	s.RegisterBuild(idle, 1)
+
+	builds := map[string]map[build.ID]bool{
+		"busy": s.scheduleBuild(busy, 10),
+	}
+	s.advanceToGlobalQueue(t, 10, 10)
+
+	s.RegisterWorker(workerID0)
+	require.Equal(t, map[string]int{"busy": 5}, s.pickJobs(t, 5, builds))
+
+	// Сборка, которая простаивала, пока работала другая, не забирает все слоты разом.
+	builds["idle"] = s.scheduleBuild(idle, 5)
+	s.advanceToGlobalQueue(t, 5, 10)
+
+	require.Equal(t, map[string]int{"idle": 1}, s.pickJobs(t, 1, builds))
+	for range 2 {
+		require.Equal(t, map[string]int{"busy": 1, "idle": 1}, s.pickJobs(t, 2, builds))
+	}
+}
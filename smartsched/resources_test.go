@@ -0,0 +1,96 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+const workerID1 api.WorkerID = "w1"
+
+func cpuResources(capacity, free int64) api.WorkerResources {
+	return api.WorkerResources{
+		Capacity: build.Resources{MilliCPU: capacity},
+		Free:     build.Resources{MilliCPU: free},
+	}
+}
+
+// tryPickJob возвращает nil, если подходящего джоба нет.
+func (s *testScheduler) tryPickJob(workerID api.WorkerID, resources api.WorkerResources) *scheduler.PendingJob {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	return s.PickJobFor(ctx, workerID, resources)
+}
+
+func TestScheduler_BinPacking(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	bigJob := &api.JobSpec{Job: build.Job{ID: build.NewID(), Resources: build.Resources{MilliCPU: 4000}}}
+	smallJob := &api.JobSpec{Job: build.Job{ID: build.NewID(), Resources: build.Resources{MilliCPU: 500}}}
+
+	pendingBigJob := s.ScheduleJob(bigJob)
+	pendingSmallJob := s.ScheduleJob(smallJob)
+
+	s.BlockUntil(2)
+	s.Advance(config.DepsTimeout) // At this point both jobs are in global queue.
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+
+	// Большой джоб не помещается в занятый воркер, но не мешает взять маленький.
+	require.Equal(t, pendingSmallJob, s.tryPickJob(workerID0, cpuResources(2000, 1000)))
+	require.Nil(t, s.tryPickJob(workerID0, cpuResources(2000, 1500)))
+
+	// Простаивающий воркер берёт джоб, даже если тот больше всех его ресурсов.
+	require.Equal(t, pendingBigJob, s.tryPickJob(workerID1, cpuResources(2000, 2000)))
+}
+
+func TestScheduler_MemoryRequests(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Resources: build.Resources{MilliCPU: 100, Memory: 1 << 30}}}
+	pendingJob := s.ScheduleJob(job)
+
+	s.BlockUntil(1)
+	s.Advance(config.DepsTimeout)
+
+	s.RegisterWorker(workerID0)
+
+	busy := api.WorkerResources{
+		Capacity: build.Resources{MilliCPU: 2000, Memory: 2 << 30},
+		Free:     build.Resources{MilliCPU: 1000, Memory: 512 << 20},
+	}
+	require.Nil(t, s.tryPickJob(workerID0, busy))
+
+	busy.Free.Memory = 1 << 30
+	require.Equal(t, pendingJob, s.tryPickJob(workerID0, busy))
+}
+
+func TestScheduler_CacheLocalSchedulingWithResources(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	cachedJob := &api.JobSpec{Job: build.Job{ID: build.NewID(), Resources: build.Resources{MilliCPU: 1000}}}
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+	s.OnJobComplete(workerID0, cachedJob.ID, &api.JobResult{})
+
+	pendingCachedJob := s.ScheduleJob(cachedJob)
+	s.BlockUntil(1)
+
+	// До CacheTimeout джоб достаётся только воркеру, у которого он есть в кеше,
+	// и только если помещается в его ресурсы.
+	require.Nil(t, s.tryPickJob(workerID1, cpuResources(2000, 2000)))
+	require.Nil(t, s.tryPickJob(workerID0, cpuResources(2000, 500)))
+	require.Equal(t, pendingCachedJob, s.tryPickJob(workerID0, cpuResources(2000, 1000)))
+}
@@ -34,6 +34,10 @@ type testScheduler struct {
 }
 
 func newTestScheduler(t *testing.T) *testScheduler {
+	return newTestSchedulerConfig(t, config)
+}
+
+func newTestSchedulerConfig(t *testing.T, config scheduler.Config) *testScheduler {
 	log := zaptest.NewLogger(t)
 
 	fakeClock := clockwork.NewFakeClock()
@@ -124,6 +128,50 @@ func TestScheduler_CacheLocalScheduling(t *testing.T) {
 	assert.Equal(t, pendingUncachedJob, secondPickedJob)
 }
 
+func TestScheduler_FailedJobIsNotCached(t *testing.T) {
+	s := newTestScheduler(t)
+	defer s.stop(t)
+
+	s.RegisterWorker(workerID0)
+
+	failed := build.NewID()
+	s.OnJobComplete(workerID0, failed, &api.JobResult{ID: failed, ExitCode: 1})
+
+	errored := build.NewID()
+	errorMsg := "worker lost"
+	s.OnJobComplete(workerID0, errored, &api.JobResult{ID: errored, Error: &errorMsg})
+
+	_, ok := s.LocateArtifact(failed)
+	assert.False(t, ok)
+
+	_, ok = s.LocateArtifact(errored)
+	assert.False(t, ok)
+}
+
+func TestScheduler_WorkerLost(t *testing.T) {
+	lost := make(chan api.WorkerID, 1)
+
+	cfg := config
+	cfg.WorkerTimeout = time.Second
+	cfg.OnWorkerLost = func(workerID api.WorkerID) { lost <- workerID }
+
+	s := newTestSchedulerConfig(t, cfg)
+	defer s.stop(t)
+
+	s.RegisterWorker(workerID0)
+
+	job := build.NewID()
+	s.OnJobComplete(workerID0, job, &api.JobResult{ID: job})
+
+	s.BlockUntil(1)
+	s.Advance(cfg.WorkerTimeout)
+
+	require.Equal(t, workerID0, <-lost)
+
+	_, ok := s.LocateArtifact(job)
+	assert.False(t, ok)
+}
+
 func TestScheduler_DependencyLocalScheduling(t *testing.T) {
 	s := newTestScheduler(t)
 	defer s.stop(t)
@@ -0,0 +1,134 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/slon/shad-go/distbuild/pkg/api"
+	"gitlab.com/slon/shad-go/distbuild/pkg/build"
+	"gitlab.com/slon/shad-go/distbuild/pkg/scheduler"
+)
+
+var speculativeConfig = scheduler.Config{
+	CacheTimeout:        config.CacheTimeout,
+	DepsTimeout:         config.DepsTimeout,
+	SpeculativeFactor:   2,
+	SpeculativeMinDelay: time.Minute,
+}
+
+// runJob планирует джоб с именем name и отдаёт его воркеру workerID.
+func (s *testScheduler) runJob(t *testing.T, name string, workerID api.WorkerID) *scheduler.PendingJob {
+	job := &api.JobSpec{Job: build.Job{ID: build.NewID(), Name: name}}
+	pendingJob := s.ScheduleJob(job)
+
+	s.BlockUntil(1)
+	s.Advance(config.DepsTimeout)
+
+	require.Equal(t, pendingJob, s.PickJob(context.Background(), workerID))
+	return pendingJob
+}
+
+// startStraggler запускает джоб на workerID0 после джоба с тем же именем, дожидается,
+// пока он станет отстающим, и отдаёт его копию workerID1.
+func (s *testScheduler) startStraggler(t *testing.T) *scheduler.PendingJob {
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+
+	warmup := s.runJob(t, "compile", workerID0)
+	s.OnJobComplete(workerID0, warmup.Job.ID, &api.JobResult{ID: warmup.Job.ID})
+
+	pendingJob := s.runJob(t, "compile", workerID0)
+
+	s.BlockUntil(1)
+	s.Advance(speculativeConfig.SpeculativeMinDelay)
+
+	require.Equal(t, pendingJob, s.PickJob(context.Background(), workerID1))
+	require.Nil(t, s.tryPickJob(workerID0, api.WorkerResources{}))
+	return pendingJob
+}
+
+func requireFinished(t *testing.T, pendingJob *scheduler.PendingJob) {
+	t.Helper()
+
+	select {
+	case <-pendingJob.Finished:
+	default:
+		t.Fatalf("job is not finished")
+	}
+}
+
+func TestScheduler_SpeculativeBackupWins(t *testing.T) {
+	s := newTestSchedulerConfig(t, speculativeConfig)
+	defer s.stop(t)
+
+	pendingJob := s.startStraggler(t)
+	id := pendingJob.Job.ID
+
+	result := &api.JobResult{ID: id}
+	require.True(t, s.OnJobComplete(workerID1, id, result))
+
+	requireFinished(t, pendingJob)
+	require.Equal(t, result, pendingJob.Result)
+	require.Equal(t, workerID1, pendingJob.PickedBy)
+
+	require.Equal(t, []build.ID{id}, s.CancelledJobs(workerID0))
+	require.Empty(t, s.CancelledJobs(workerID1))
+
+	// Результат проигравшего воркера, который не успел убить джоб, игнорируется.
+	require.False(t, s.OnJobComplete(workerID0, id, &api.JobResult{ID: id}))
+}
+
+func TestScheduler_SpeculativeOriginalWins(t *testing.T) {
+	s := newTestSchedulerConfig(t, speculativeConfig)
+	defer s.stop(t)
+
+	pendingJob := s.startStraggler(t)
+	id := pendingJob.Job.ID
+
+	require.True(t, s.OnJobComplete(workerID0, id, &api.JobResult{ID: id}))
+
+	requireFinished(t, pendingJob)
+	require.Equal(t, workerID0, pendingJob.PickedBy)
+
+	require.Empty(t, s.CancelledJobs(workerID0))
+	require.Equal(t, []build.ID{id}, s.CancelledJobs(workerID1))
+}
+
+func TestScheduler_SpeculativeOriginalLost(t *testing.T) {
+	s := newTestSchedulerConfig(t, speculativeConfig)
+	defer s.stop(t)
+
+	pendingJob := s.startStraggler(t)
+	id := pendingJob.Job.ID
+
+	// Воркер потерял джоб, но копия продолжает выполняться.
+	s.SyncRunningJobs(workerID0, nil)
+	s.SyncRunningJobs(workerID1, []build.ID{id})
+
+	select {
+	case <-pendingJob.Finished:
+		t.Fatalf("job is finished")
+	default:
+	}
+
+	require.True(t, s.OnJobComplete(workerID1, id, &api.JobResult{ID: id}))
+	requireFinished(t, pendingJob)
+	require.False(t, pendingJob.Result.WorkerLost)
+	require.Equal(t, workerID1, pendingJob.PickedBy)
+}
+
+func TestScheduler_SpeculativeNeedsHistory(t *testing.T) {
+	s := newTestSchedulerConfig(t, speculativeConfig)
+	defer s.stop(t)
+
+	s.RegisterWorker(workerID0)
+	s.RegisterWorker(workerID1)
+
+	s.runJob(t, "link", workerID0)
+	s.Advance(time.Hour)
+
+	require.Nil(t, s.tryPickJob(workerID1, api.WorkerResources{}))
+}